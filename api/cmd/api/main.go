@@ -1,15 +1,32 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	// Required for unicode.IsSpace and unicode.ToUpper
 	// Make sure this path is correct for your module
 
 	"gonuxt-context-assistant/internal/api"
 	"gonuxt-context-assistant/internal/app/assistant"
+	"gonuxt-context-assistant/internal/app/conversation"
+	"gonuxt-context-assistant/internal/app/gateway"
+	"gonuxt-context-assistant/internal/app/mcpclient"
+	"gonuxt-context-assistant/internal/app/mcpserver"
+	"gonuxt-context-assistant/internal/auth"
+	"gonuxt-context-assistant/internal/config"
+	"gonuxt-context-assistant/internal/llm"
+	"gonuxt-context-assistant/internal/mcp"
+	"gonuxt-context-assistant/internal/prompts"
+	"gonuxt-context-assistant/internal/rag"
+	"gonuxt-context-assistant/internal/tools"
+	"gonuxt-context-assistant/internal/usage"
 
 	"github.com/rs/cors"
 )
@@ -17,11 +34,77 @@ import (
 // main function is the entry point of our server application.
 func main() {
 
+	// Load the optional configuration file (see internal/config)
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+
+	// Load the prompt templates: the built-in ones plus those of prompts.dir (see internal/prompts)
+	promptRegistry, err := prompts.Load(cfg.Prompts.Dir, cfg.Prompts.Versions)
+	if err != nil {
+		log.Fatalf("Error loading prompts: %v", err)
+	}
+	tools.SetWeatherSentence(promptRegistry.WeatherSentence)
+
 	// Initialize the core assistant service
 	assistantSvc := assistant.NewService()
+	assistantSvc.Sessions = conversation.NewMemoryStore() // history for follow-up questions
+	assistantSvc.Prompts = promptRegistry
+
+	// Let a language model pick the tools when one is configured (see internal/llm)
+	provider, err := llm.New(cfg.LLM)
+	if err != nil {
+		log.Fatalf("Error creating LLM provider: %v", err)
+	}
+	var usageLedger *usage.Ledger
+	if provider != nil {
+		// Count the tokens and cost of every model call (see internal/usage)
+		usageLedger = usage.NewLedger()
+		provider = usage.NewMeter(provider, cfg.LLM.Model, cfg.LLM.Prices, usageLedger)
+
+		assistantSvc.LLM = provider
+		assistantSvc.MaxSteps = cfg.LLM.MaxSteps
+		assistantSvc.AgentTimeout = time.Duration(cfg.LLM.TimeoutSeconds) * time.Second
+		assistantSvc.History = llm.NewHistoryManager(cfg.LLM.ContextTokens, provider) // summarizes long chats
+		log.Printf("Using %s model %s to route queries", cfg.LLM.Provider, cfg.LLM.Model)
+	} else {
+		log.Println("No llm.provider configured; routing queries by keyword")
+	}
+
+	// Search documents to ground answers in them: those of rag.dir and those uploaded to
+	// /documents (see internal/rag)
+	documents := rag.NewIndex()
+	documents.ChunkTokens = cfg.RAG.ChunkTokens
+	documents.TopK = cfg.RAG.TopK
+	if e := cfg.RAG.Embeddings; e != nil {
+		documents.Embedder = llm.NewOpenAIEmbeddings(e.BaseURL, e.APIKey(), e.Model)
+	}
+	if cfg.RAG.Dir != "" {
+		added, err := documents.AddDir(context.Background(), cfg.RAG.Dir)
+		if err != nil {
+			log.Printf("Warning: some documents of %s could not be indexed: %v", cfg.RAG.Dir, err)
+		}
+		log.Printf("Indexed %d document(s) from %s", added, cfg.RAG.Dir)
+	}
+	assistantSvc.Documents = documents
+	ingester := rag.NewIngester(documents, 2) // indexes uploads in the background
+	defer ingester.Close()
+
+	// Connect to external MCP servers so the assistant can use their tools too
+	if len(cfg.MCPServers) > 0 {
+		remote := mcpclient.NewManager(cfg.MCPServers)
+		remote.Start(context.Background())
+		defer remote.Close()
+		assistantSvc.Remote = remote
+	}
 
 	// Initialize the API handlers, injecting the assistant service
 	apiHandlers := api.NewHandler(assistantSvc)
+	apiHandlers.Usage = usageLedger
+	apiHandlers.AdminToken = config.AdminToken()
+	apiHandlers.Prompts = promptRegistry
+	apiHandlers.Ingester = ingester
 
 	// 1. Create a new HTTP multiplexer (router). This is best practice for custom routing.
 	// We could use http.DefaultServeMux, but creating our own gives more control.
@@ -30,23 +113,91 @@ func main() {
 	// 2. Register our askHandler with the multiplexer.
 	// http.HandlerFunc(askHandler) converts the function into an http.Handler.
 	mux.Handle("/ask", http.HandlerFunc(apiHandlers.AskHandler))
+	mux.Handle("/ask/stream", http.HandlerFunc(apiHandlers.AskStreamHandler))   // Server-Sent Events
+	mux.Handle("/sessions", http.HandlerFunc(apiHandlers.SessionsHandler))      // needs ASSISTANT_ADMIN_TOKEN
+	mux.Handle("/sessions/", http.HandlerFunc(apiHandlers.SessionHandler))      // the session ID is enough
+	mux.Handle("/documents", http.HandlerFunc(apiHandlers.DocumentsHandler))    // list and upload
+	mux.Handle("/documents/", http.HandlerFunc(apiHandlers.DocumentHandler))    // one document, or a job; DELETE needs the admin token
+	mux.Handle("/admin/usage", http.HandlerFunc(apiHandlers.AdminUsageHandler)) // needs ASSISTANT_ADMIN_TOKEN
+	mux.Handle("/admin/prompts", http.HandlerFunc(apiHandlers.AdminPromptsHandler))
+	mux.Handle("/admin/weather", http.HandlerFunc(apiHandlers.AdminWeatherHandler)) // updates notify MCP subscribers
+	mux.Handle("/admin/capitals", http.HandlerFunc(apiHandlers.AdminCapitalsHandler))
 	mux.Handle("/ask-multiple-city-weather", http.HandlerFunc(apiHandlers.AskMultiCityWeatherFromQueryHandler))
 	mux.Handle("/ask-multi-city-weather-async", http.HandlerFunc(apiHandlers.AskMultipleCityWeatherAsyncHandler))
 
+	// The MCP endpoint (Streamable HTTP transport) shares the same assistant service,
+	// so remote agents get the exact tools the Nuxt front-end uses.
+	mcpServer := mcpserver.New(assistantSvc)
+	defer mcpServer.Close()
+	assistantSvc.LogSink = mcpserver.LogSink(mcpServer) // logging/setLevel + notifications/message
+
+	// As a gateway, /mcp also re-exposes the downstream servers of the config file
+	if len(cfg.Gateway.MCPServers) > 0 {
+		gw := gateway.New(mcpServer, cfg.Gateway.MCPServers)
+		gw.Start(context.Background())
+		defer gw.Close()
+		apiHandlers.Gateway = gw
+		mux.Handle("/gateway/health", http.HandlerFunc(apiHandlers.GatewayHealthHandler))
+	}
+
+	mcpHandler := mcp.NewHTTPHandler(mcpServer)
+	mcpHandler.AllowedOrigins = []string{"http://localhost:3000"}
+
+	// With an authorization server configured, /mcp needs an OAuth 2.1 bearer token and
+	// tools are limited to the token's scopes (see internal/auth)
+	if cfg.Auth.Issuer != "" {
+		resourceServer := auth.New(cfg.Auth)
+		mcpServer.AuthorizeTool = resourceServer.AuthorizeTool
+		mux.Handle(resourceServer.MetadataPath(), http.HandlerFunc(resourceServer.ServeMetadata))
+		if resourceServer.MetadataPath() != "/.well-known/oauth-protected-resource" {
+			mux.Handle("/.well-known/oauth-protected-resource", http.HandlerFunc(resourceServer.ServeMetadata))
+		}
+		mux.Handle("/mcp", resourceServer.Protect(mcpHandler))
+	} else {
+		log.Println("Warning: /mcp is not protected; set auth.issuer in the config file to require tokens")
+		mux.Handle("/mcp", mcpHandler)
+	}
+
 	// 3. Create the CORS middleware instance.
 	// The `cors` package expects an `http.Handler` to wrap.
 	// We wrap our `mux` (which is an http.Handler).
 	handler := cors.New(cors.Options{
 		AllowedOrigins: []string{"http://localhost:3000"}, // Allow Nuxt.js dev server
-		AllowedMethods: []string{"GET", "POST", "OPTIONS"},
-		AllowedHeaders: []string{"Content-Type"},
+		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedHeaders: []string{"Content-Type", "Accept", "Authorization", api.HeaderAPIKey, mcp.HeaderSessionID, mcp.HeaderProtocolVersion},
+		ExposedHeaders: []string{mcp.HeaderSessionID, "WWW-Authenticate"},
 		Debug:          true, // Enable CORS logging for debugging
 	}).Handler(mux) // <--- Correct usage: wrap the mux (router)
 
 	// 4. Start the HTTP server with the CORS-wrapped handler.
+	// We pass our `handler` (which is the mux wrapped by CORS) to the server.
+	server := &http.Server{Addr: ":8080", Handler: handler}
+
+	// On Ctrl+C or SIGTERM, stop accepting requests and give the running ones some time
+	// to finish, then return so the deferred Closes above run.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+		log.Println("Shutting down...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Error shutting down the server: %v", err)
+		}
+	}()
+
 	fmt.Println("Server starting on port 8080...")
-	// We pass our `handler` (which is the mux wrapped by CORS) to ListenAndServe.
-	log.Fatal(http.ListenAndServe(":8080", handler))
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		log.Printf("Server error: %v", err)
+		return
+	}
+	<-shutdownDone
 }
 
+// shutdownTimeout bounds how long a shutdown waits for open requests, such as SSE streams.
+const shutdownTimeout = 10 * time.Second
+
 // Helper functions (kept outside main for clarity and reusability within this package)
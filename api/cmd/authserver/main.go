@@ -0,0 +1,255 @@
+// Command authserver is a stand-in OAuth 2.1 authorization server for trying out (and
+// testing) the protected /mcp endpoint locally. It is NOT meant for production: every
+// client is accepted and every authorization request is approved without a login.
+//
+// It supports what MCP clients use: authorization server metadata (RFC 8414), dynamic
+// client registration (RFC 7591), the authorization code flow with PKCE, the client
+// credentials grant for scripts, resource indicators (RFC 8707) and a JWKS.
+//
+//	go run ./cmd/authserver -addr :9090
+//	curl -d grant_type=client_credentials -d scope=weather:read \
+//	     -d resource=http://localhost:8080/mcp http://localhost:9090/token
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"gonuxt-context-assistant/internal/auth"
+)
+
+const (
+	tokenLifetime = time.Hour
+	codeLifetime  = time.Minute
+)
+
+// authServer holds the signing key and the short-lived authorization codes.
+type authServer struct {
+	issuer          string
+	defaultAudience string
+	key             *rsa.PrivateKey
+	kid             string
+
+	mu    sync.Mutex
+	codes map[string]authorizationCode
+}
+
+type authorizationCode struct {
+	clientID    string
+	redirectURI string
+	challenge   string
+	scope       string
+	resource    string
+	expiresAt   time.Time
+}
+
+func main() {
+	addr := flag.String("addr", ":9090", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9090", "issuer URL, as configured in auth.issuer")
+	audience := flag.String("audience", "http://localhost:8080/mcp", "audience of tokens requested without a resource parameter")
+	flag.Parse()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatalf("Error generating signing key: %v", err)
+	}
+	as := &authServer{
+		issuer:          strings.TrimSuffix(*issuer, "/"),
+		defaultAudience: *audience,
+		key:             key,
+		kid:             randomString(8),
+		codes:           make(map[string]authorizationCode),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/oauth-authorization-server", as.metadata)
+	mux.HandleFunc("/jwks.json", as.jwks)
+	mux.HandleFunc("/register", as.register)
+	mux.HandleFunc("/authorize", as.authorize)
+	mux.HandleFunc("/token", as.token)
+
+	fmt.Printf("Stand-in authorization server %s listening on %s...\n", as.issuer, *addr)
+	log.Fatal(http.ListenAndServe(*addr, allowBrowsers(mux)))
+}
+
+func (as *authServer) metadata(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                as.issuer,
+		"authorization_endpoint":                as.issuer + "/authorize",
+		"token_endpoint":                        as.issuer + "/token",
+		"registration_endpoint":                 as.issuer + "/register",
+		"jwks_uri":                              as.issuer + "/jwks.json",
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code", "client_credentials"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"token_endpoint_auth_methods_supported": []string{"none", "client_secret_post"},
+	})
+}
+
+func (as *authServer) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, auth.JWKS{Keys: []auth.JWK{auth.NewRSAJWK(as.kid, &as.key.PublicKey)}})
+}
+
+// register accepts any client and hands out a fresh client_id (RFC 7591).
+func (as *authServer) register(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var client map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&client); err != nil {
+		oauthError(w, http.StatusBadRequest, "invalid_client_metadata", err.Error())
+		return
+	}
+	client["client_id"] = "client-" + randomString(8)
+	client["client_id_issued_at"] = time.Now().Unix()
+	client["token_endpoint_auth_method"] = "none"
+	writeJSON(w, http.StatusCreated, client)
+}
+
+// authorize approves every request right away and redirects back with a code.
+func (as *authServer) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	redirectURI, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || !redirectURI.IsAbs() {
+		http.Error(w, "redirect_uri must be an absolute URL", http.StatusBadRequest)
+		return
+	}
+	if q.Get("response_type") != "code" || q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "expected response_type=code with an S256 code_challenge (PKCE)", http.StatusBadRequest)
+		return
+	}
+
+	code := randomString(16)
+	as.mu.Lock()
+	as.codes[code] = authorizationCode{
+		clientID:    q.Get("client_id"),
+		redirectURI: q.Get("redirect_uri"),
+		challenge:   q.Get("code_challenge"),
+		scope:       q.Get("scope"),
+		resource:    q.Get("resource"),
+		expiresAt:   time.Now().Add(codeLifetime),
+	}
+	as.mu.Unlock()
+
+	back := redirectURI.Query()
+	back.Set("code", code)
+	if state := q.Get("state"); state != "" {
+		back.Set("state", state)
+	}
+	redirectURI.RawQuery = back.Encode()
+	log.Printf("Approved client %s for scope %q", q.Get("client_id"), q.Get("scope"))
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (as *authServer) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		oauthError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	var clientID, scope, resource string
+	switch r.PostForm.Get("grant_type") {
+	case "client_credentials":
+		clientID, scope, resource = r.PostForm.Get("client_id"), r.PostForm.Get("scope"), r.PostForm.Get("resource")
+		if clientID == "" {
+			clientID = "cli"
+		}
+	case "authorization_code":
+		as.mu.Lock()
+		code, ok := as.codes[r.PostForm.Get("code")]
+		delete(as.codes, r.PostForm.Get("code")) // codes are single use
+		as.mu.Unlock()
+		if !ok || time.Now().After(code.expiresAt) || code.redirectURI != r.PostForm.Get("redirect_uri") {
+			oauthError(w, http.StatusBadRequest, "invalid_grant", "unknown or expired code")
+			return
+		}
+		verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(verifier[:]) != code.challenge {
+			oauthError(w, http.StatusBadRequest, "invalid_grant", "code_verifier does not match the code_challenge")
+			return
+		}
+		clientID, scope, resource = code.clientID, code.scope, code.resource
+		if r.PostForm.Get("resource") != "" {
+			resource = r.PostForm.Get("resource")
+		}
+	default:
+		oauthError(w, http.StatusBadRequest, "unsupported_grant_type", "use authorization_code or client_credentials")
+		return
+	}
+	if resource == "" {
+		resource = as.defaultAudience
+	}
+
+	now := time.Now()
+	accessToken, err := auth.SignRS256(auth.Claims{
+		Issuer:   as.issuer,
+		Subject:  clientID,
+		Audience: auth.Audience{resource},
+		Expiry:   now.Add(tokenLifetime).Unix(),
+		IssuedAt: now.Unix(),
+		Scope:    scope,
+		ClientID: clientID,
+	}, as.key, as.kid)
+	if err != nil {
+		oauthError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	log.Printf("Issued token to %s for %s with scope %q", clientID, resource, scope)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(tokenLifetime.Seconds()),
+		"scope":        scope,
+	})
+}
+
+// allowBrowsers lets browser-based MCP clients (e.g. the MCP Inspector) call every endpoint.
+func allowBrowsers(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func oauthError(w http.ResponseWriter, status int, code, description string) {
+	writeJSON(w, status, map[string]string{"error": code, "error_description": description})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+func randomString(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		log.Fatalf("Error reading random bytes: %v", err)
+	}
+	return hex.EncodeToString(b)
+}
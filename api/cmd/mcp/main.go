@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"gonuxt-context-assistant/internal/app/assistant"
+	"gonuxt-context-assistant/internal/app/mcpserver"
+)
+
+// main starts the MCP server on stdin/stdout so desktop MCP clients can launch it as a subprocess.
+func main() {
+	// stdout carries the protocol, so make sure nothing else ends up there.
+	log.SetOutput(os.Stderr)
+
+	// Initialize the core assistant service, exactly like the HTTP API does
+	assistantSvc := assistant.NewService()
+
+	// Wrap the assistant's tools in an MCP server
+	server := mcpserver.New(assistantSvc)
+	defer server.Close()
+
+	// Forward the assistant's logs to clients that ask for them with logging/setLevel
+	assistantSvc.LogSink = mcpserver.LogSink(server)
+
+	// Stop cleanly on Ctrl+C or when the client terminates us
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Println("MCP server running on stdio...")
+	if err := server.ServeStdio(ctx, os.Stdin, os.Stdout); err != nil && ctx.Err() == nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,483 @@
+// Command mcpctl is a command-line inspector for MCP servers. It connects to a server
+// launched over stdio or reached over Streamable HTTP, and lists, calls and tails what the
+// server offers. We use it to smoke-test our own tools (cmd/mcp, /mcp) and to try out
+// third-party servers before adding them to the config file.
+//
+//	mcpctl -stdio ./bin/mcp tools
+//	mcpctl -stdio ./bin/mcp call get_weather '{"city": "Lisbon"}'
+//	mcpctl -url http://localhost:8080/mcp -H "Authorization: Bearer $TOKEN" resources
+//	mcpctl -url http://localhost:8080/mcp tail -level debug weather://cities
+//	mcpctl -stdio ./bin/mcp -record smoke.jsonl call get_capital '{"country": "Japan"}'
+//	mcpctl -stdio ./bin/mcp replay smoke.jsonl
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"gonuxt-context-assistant/internal/mcp"
+)
+
+// errToolFailed makes mcpctl exit with status 1 after printing a tool's error result.
+var errToolFailed = errors.New("tool reported an error")
+
+// command is one mcpctl sub-command.
+type command struct {
+	usage string
+	help  string
+	run   func(ctx context.Context, ctl *ctl, args []string) error
+}
+
+var commands = map[string]command{
+	"info":      {"info", "show the server's name, version, capabilities and instructions", runInfo},
+	"tools":     {"tools", "list tools with their arguments (* = required)", runTools},
+	"resources": {"resources", "list resources and resource templates", runResources},
+	"prompts":   {"prompts", "list prompts with their arguments", runPrompts},
+	"call":      {"call <tool> [json|-]", "call a tool; arguments are a JSON object, - reads them from stdin", runCall},
+	"read":      {"read <uri>", "read a resource", runRead},
+	"prompt":    {"prompt <name> [json]", "render a prompt; arguments are a JSON object of strings", runPrompt},
+	"tail":      {"tail [-level level] [uri...]", "print notifications (logs, progress, resource updates) until Ctrl+C", runTail},
+	"replay":    {"replay <file>", "re-send the requests of a recorded session and report different answers", runReplay},
+}
+
+// ctl is what every command gets: the connected client and the output settings.
+type ctl struct {
+	client  *mcp.Client
+	out     io.Writer
+	rawJSON bool
+	// notes receives notifications; stderr so they do not mix with a command's output.
+	notes io.Writer
+}
+
+// headerFlags collects repeated -H "Name: value" flags.
+type headerFlags map[string]string
+
+func (h headerFlags) String() string { return fmt.Sprint(map[string]string(h)) }
+
+func (h headerFlags) Set(value string) error {
+	name, v, ok := strings.Cut(value, ":")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("header %q must look like \"Name: value\"", value)
+	}
+	h[strings.TrimSpace(name)] = strings.TrimSpace(v)
+	return nil
+}
+
+// options are the parsed command line.
+type options struct {
+	stdio   string
+	url     string
+	headers headerFlags
+	record  string
+	timeout time.Duration
+	rawJSON bool
+	command string   // one of commands
+	args    []string // the command's own arguments
+}
+
+// newFlagSet declares mcpctl's flags, storing their values in opts.
+func newFlagSet(opts *options) *flag.FlagSet {
+	fs := flag.NewFlagSet("mcpctl", flag.ContinueOnError)
+	fs.StringVar(&opts.stdio, "stdio", "", "command line of a server to launch over stdio, e.g. \"./bin/mcp\"")
+	fs.StringVar(&opts.url, "url", "", "Streamable HTTP endpoint of a server, e.g. http://localhost:8080/mcp")
+	fs.Var(opts.headers, "H", "extra HTTP header \"Name: value\" (repeatable)")
+	fs.StringVar(&opts.record, "record", "", "append every message of the session to this file (JSON Lines)")
+	fs.DurationVar(&opts.timeout, "timeout", 30*time.Second, "time limit for a command (tail runs until interrupted)")
+	fs.BoolVar(&opts.rawJSON, "json", false, "print results as JSON instead of text")
+	return fs
+}
+
+// parseArgs reads the command line (without the program name). It returns flag.ErrHelp
+// for -h.
+func parseArgs(args []string) (*options, error) {
+	opts := &options{headers: headerFlags{}}
+	fs := newFlagSet(opts)
+	fs.SetOutput(io.Discard) // main prints the error and the usage
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if fs.NArg() == 0 {
+		return nil, errors.New("name a command")
+	}
+	opts.command, opts.args = fs.Arg(0), fs.Args()[1:]
+	if _, ok := commands[opts.command]; !ok {
+		return nil, fmt.Errorf("unknown command %q", opts.command)
+	}
+	switch {
+	case opts.stdio != "" && opts.url != "":
+		return nil, errors.New("use either -stdio or -url, not both")
+	case strings.TrimSpace(opts.stdio) == "" && opts.url == "":
+		return nil, errors.New("name a server with -stdio or -url")
+	}
+	return opts, nil
+}
+
+// transport returns the connection to the server named by -stdio or -url.
+func (opts *options) transport() mcp.ClientTransport {
+	if opts.url != "" {
+		return &mcp.HTTPClientTransport{URL: opts.url, Headers: opts.headers}
+	}
+	fields := strings.Fields(opts.stdio)
+	return &mcp.CommandTransport{Name: fields[0], Command: fields[0], Args: fields[1:]}
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("mcpctl: ")
+
+	opts, err := parseArgs(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		usage()
+		return
+	}
+	if err != nil {
+		log.Print(err)
+		usage()
+		os.Exit(2)
+	}
+	cmd := commands[opts.command]
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	c := &ctl{
+		client:  mcp.NewClient("mcpctl", "0.1.0", opts.transport()),
+		out:     os.Stdout,
+		rawJSON: opts.rawJSON,
+		notes:   os.Stderr,
+	}
+	if opts.command == "tail" {
+		c.notes = os.Stdout // the notifications are the output
+	}
+	c.client.OnNotification = c.printNotification
+	if opts.record != "" {
+		rec, err := newRecorder(opts.record)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer rec.Close()
+		c.client.OnMessage = rec.record
+	}
+
+	if err := c.client.Connect(ctx); err != nil {
+		log.Fatal(err)
+	}
+	defer c.client.Close()
+
+	if opts.command != "tail" {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
+	if err := cmd.run(ctx, c, opts.args); err != nil {
+		if !errors.Is(err, errToolFailed) {
+			log.Print(err)
+		}
+		c.client.Close()
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: mcpctl (-stdio \"command args\" | -url URL) [flags] <command> [arguments]\n\nCommands:\n")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s\t%s\n", commands[name].usage, commands[name].help)
+	}
+	w.Flush()
+	fmt.Fprintf(os.Stderr, "\nFlags:\n")
+	fs := newFlagSet(&options{headers: headerFlags{}})
+	fs.SetOutput(os.Stderr)
+	fs.PrintDefaults()
+}
+
+func runInfo(ctx context.Context, c *ctl, args []string) error {
+	info := map[string]any{
+		"serverInfo":   c.client.ServerInfo(),
+		"capabilities": c.client.ServerCapabilities(),
+		"instructions": c.client.Instructions(),
+	}
+	if c.rawJSON {
+		return c.printJSON(info)
+	}
+	server := c.client.ServerInfo()
+	fmt.Fprintf(c.out, "Server:       %s %s\n", server.Name, server.Version)
+	caps, _ := json.Marshal(c.client.ServerCapabilities())
+	fmt.Fprintf(c.out, "Capabilities: %s\n", caps)
+	if instructions := c.client.Instructions(); instructions != "" {
+		fmt.Fprintf(c.out, "Instructions: %s\n", instructions)
+	}
+	return nil
+}
+
+func runTools(ctx context.Context, c *ctl, args []string) error {
+	tools, err := c.client.ListTools(ctx)
+	if err != nil {
+		return err
+	}
+	if c.rawJSON {
+		return c.printJSON(tools)
+	}
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TOOL\tARGUMENTS\tDESCRIPTION")
+	for _, tool := range tools {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", tool.Name, schemaArguments(tool.InputSchema), firstLine(tool.Description))
+	}
+	return w.Flush()
+}
+
+func runResources(ctx context.Context, c *ctl, args []string) error {
+	caps := c.client.ServerCapabilities()
+	if caps.Resources == nil {
+		fmt.Fprintln(c.notes, "The server does not offer resources.")
+		return nil
+	}
+	resources, err := c.client.ListResources(ctx)
+	if err != nil {
+		return err
+	}
+	templates, err := c.client.ListResourceTemplates(ctx)
+	if err != nil {
+		return err
+	}
+	if c.rawJSON {
+		return c.printJSON(map[string]any{"resources": resources, "resourceTemplates": templates})
+	}
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "URI\tMIME TYPE\tDESCRIPTION")
+	for _, r := range resources {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", r.URI, r.MimeType, firstLine(r.Description))
+	}
+	for _, t := range templates {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", t.URITemplate, t.MimeType, firstLine(t.Description))
+	}
+	return w.Flush()
+}
+
+func runPrompts(ctx context.Context, c *ctl, args []string) error {
+	if c.client.ServerCapabilities().Prompts == nil {
+		fmt.Fprintln(c.notes, "The server does not offer prompts.")
+		return nil
+	}
+	prompts, err := c.client.ListPrompts(ctx)
+	if err != nil {
+		return err
+	}
+	if c.rawJSON {
+		return c.printJSON(prompts)
+	}
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROMPT\tARGUMENTS\tDESCRIPTION")
+	for _, p := range prompts {
+		names := make([]string, 0, len(p.Arguments))
+		for _, arg := range p.Arguments {
+			names = append(names, argumentName(arg.Name, arg.Required))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", p.Name, strings.Join(names, ", "), firstLine(p.Description))
+	}
+	return w.Flush()
+}
+
+func runCall(ctx context.Context, c *ctl, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New("usage: call <tool> [json|-]")
+	}
+	var arguments any // nil sends no arguments at all
+	if len(args) == 2 {
+		raw := []byte(args[1])
+		if args[1] == "-" {
+			var err error
+			if raw, err = io.ReadAll(os.Stdin); err != nil {
+				return err
+			}
+		}
+		if !json.Valid(raw) {
+			return fmt.Errorf("arguments are not valid JSON: %s", raw)
+		}
+		arguments = json.RawMessage(raw)
+	}
+
+	result, err := c.client.CallTool(ctx, args[0], arguments)
+	if err != nil {
+		return err
+	}
+	if c.rawJSON {
+		err = c.printJSON(result)
+	} else {
+		for _, content := range result.Content {
+			if content.Type == "text" {
+				fmt.Fprintln(c.out, content.Text)
+			} else {
+				fmt.Fprintf(c.out, "[%s content]\n", content.Type)
+			}
+		}
+		if result.StructuredContent != nil {
+			fmt.Fprintln(c.out, "\nstructuredContent:")
+			err = c.printJSON(result.StructuredContent)
+		}
+	}
+	if err == nil && result.IsError {
+		return errToolFailed
+	}
+	return err
+}
+
+func runRead(ctx context.Context, c *ctl, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: read <uri>")
+	}
+	result, err := c.client.ReadResource(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if c.rawJSON {
+		return c.printJSON(result)
+	}
+	for _, contents := range result.Contents {
+		if len(result.Contents) > 1 {
+			fmt.Fprintf(c.out, "--- %s\n", contents.URI)
+		}
+		if contents.Blob != "" {
+			fmt.Fprintf(c.out, "[%d bytes of base64 %s]\n", len(contents.Blob), contents.MimeType)
+			continue
+		}
+		fmt.Fprintln(c.out, contents.Text)
+	}
+	return nil
+}
+
+func runPrompt(ctx context.Context, c *ctl, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New("usage: prompt <name> [json]")
+	}
+	var arguments map[string]string
+	if len(args) == 2 {
+		if err := json.Unmarshal([]byte(args[1]), &arguments); err != nil {
+			return fmt.Errorf("prompt arguments must be a JSON object of strings: %w", err)
+		}
+	}
+	result, err := c.client.GetPrompt(ctx, args[0], arguments)
+	if err != nil {
+		return err
+	}
+	if c.rawJSON {
+		return c.printJSON(result)
+	}
+	for _, msg := range result.Messages {
+		fmt.Fprintf(c.out, "%s: %s\n", msg.Role, msg.Content.Text)
+	}
+	return nil
+}
+
+// runTail subscribes to logs (and to the given resource URIs) and prints notifications
+// until interrupted or the server goes away.
+func runTail(ctx context.Context, c *ctl, args []string) error {
+	fs := flag.NewFlagSet("tail", flag.ContinueOnError)
+	level := fs.String("level", "info", "lowest log level to receive")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	caps := c.client.ServerCapabilities()
+	if caps.Logging != nil {
+		if err := c.client.SetLogLevel(ctx, mcp.LoggingLevel(*level)); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintln(os.Stderr, "The server does not offer logging; only other notifications are shown.")
+	}
+	for _, uri := range fs.Args() {
+		if _, err := c.client.Call(ctx, "resources/subscribe", map[string]string{"uri": uri}); err != nil {
+			return fmt.Errorf("subscribe to %s: %w", uri, err)
+		}
+	}
+
+	fmt.Fprintln(os.Stderr, "Waiting for notifications, press Ctrl+C to stop...")
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-c.client.Done():
+		return errors.New("the server closed the connection")
+	}
+}
+
+// printNotification prints log records as "[level] logger: message" and everything
+// else as the method followed by its params.
+func (c *ctl) printNotification(msg *mcp.Message) {
+	stamp := time.Now().Format("15:04:05")
+	if msg.Method == "notifications/message" {
+		var p struct {
+			Level  string `json:"level"`
+			Logger string `json:"logger"`
+			Data   any    `json:"data"`
+		}
+		if err := json.Unmarshal(msg.Params, &p); err == nil {
+			text, ok := p.Data.(string)
+			if !ok {
+				data, _ := json.Marshal(p.Data)
+				text = string(data)
+			}
+			fmt.Fprintf(c.notes, "%s [%s] %s: %s\n", stamp, p.Level, p.Logger, text)
+			return
+		}
+	}
+	fmt.Fprintf(c.notes, "%s %s %s\n", stamp, msg.Method, msg.Params)
+}
+
+func (c *ctl) printJSON(v any) error {
+	enc := json.NewEncoder(c.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// schemaArguments lists the properties of a JSON schema, marking required ones.
+func schemaArguments(schema any) string {
+	var s struct {
+		Properties map[string]any `json:"properties"`
+		Required   []string       `json:"required"`
+	}
+	raw, err := json.Marshal(schema)
+	if err != nil || json.Unmarshal(raw, &s) != nil {
+		return ""
+	}
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		names[i] = argumentName(name, slices.Contains(s.Required, name))
+	}
+	return strings.Join(names, ", ")
+}
+
+func argumentName(name string, required bool) string {
+	if required {
+		return name + "*"
+	}
+	return name
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gonuxt-context-assistant/internal/mcp"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want *options // nil when err is set
+		err  string
+	}{
+		{"stdio server", []string{"-stdio", "./bin/mcp -v", "tools"},
+			&options{stdio: "./bin/mcp -v", headers: headerFlags{}, timeout: 30 * time.Second, command: "tools", args: []string{}}, ""},
+		{"http server with flags", []string{"-url", "http://localhost:8080/mcp", "-H", "Authorization: Bearer t", "-H", "X-Trace:1", "-json", "-timeout", "5s", "call", "get_weather", `{"city":"Lisbon"}`},
+			&options{url: "http://localhost:8080/mcp", headers: headerFlags{"Authorization": "Bearer t", "X-Trace": "1"}, timeout: 5 * time.Second, rawJSON: true,
+				command: "call", args: []string{"get_weather", `{"city":"Lisbon"}`}}, ""},
+		{"command flags are left to the command", []string{"-url", "u", "tail", "-level", "debug", "weather://cities"},
+			&options{url: "u", headers: headerFlags{}, timeout: 30 * time.Second, command: "tail", args: []string{"-level", "debug", "weather://cities"}}, ""},
+		{"no command", []string{"-url", "u"}, nil, "name a command"},
+		{"unknown command", []string{"-url", "u", "list"}, nil, `unknown command "list"`},
+		{"both servers", []string{"-url", "u", "-stdio", "s", "tools"}, nil, "either -stdio or -url"},
+		{"no server", []string{"tools"}, nil, "name a server"},
+		{"blank stdio", []string{"-stdio", "  ", "tools"}, nil, "name a server"},
+		{"bad header", []string{"-url", "u", "-H", "Authorization", "tools"}, nil, `must look like "Name: value"`},
+		{"unknown flag", []string{"-verbose", "tools"}, nil, "flag provided but not defined"},
+		{"help", []string{"-h"}, nil, flag.ErrHelp.Error()},
+	}
+	for _, tt := range tests {
+		got, err := parseArgs(tt.args)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got.stdio != tt.want.stdio || got.url != tt.want.url || got.timeout != tt.want.timeout || got.rawJSON != tt.want.rawJSON ||
+			got.command != tt.want.command || !slices.Equal(got.args, tt.want.args) || got.headers.String() != tt.want.headers.String() {
+			t.Errorf("%s: options = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTransport(t *testing.T) {
+	opts, err := parseArgs([]string{"-stdio", "./bin/mcp -config dev.json", "tools"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdio, ok := opts.transport().(*mcp.CommandTransport)
+	if !ok || stdio.Command != "./bin/mcp" || !slices.Equal(stdio.Args, []string{"-config", "dev.json"}) {
+		t.Errorf("stdio transport = %#v", opts.transport())
+	}
+
+	opts, err = parseArgs([]string{"-url", "http://localhost:8080/mcp", "-H", "Authorization: Bearer t", "tools"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, ok := opts.transport().(*mcp.HTTPClientTransport)
+	if !ok || remote.URL != "http://localhost:8080/mcp" || remote.Headers["Authorization"] != "Bearer t" {
+		t.Errorf("http transport = %#v", opts.transport())
+	}
+}
+
+func TestCallAgainstAServer(t *testing.T) {
+	server := mcp.NewServer("test", "0.0.0")
+	server.AddTool(mcp.Tool{Name: "echo"}, func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args struct {
+			Text string `json:"text"`
+		}
+		if err := req.Bind(&args); err != nil {
+			return nil, err
+		}
+		return mcp.StructuredResult(args.Text, map[string]int{"length": len(args.Text)}), nil
+	})
+	server.AddTool(mcp.Tool{Name: "fail"}, func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.ErrorResult("it broke"), nil
+	})
+	endpoint := httptest.NewServer(mcp.NewHTTPHandler(server))
+	defer endpoint.Close()
+
+	opts, err := parseArgs([]string{"-url", endpoint.URL, "call", "echo", `{"text": "hello"}`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	c := &ctl{client: mcp.NewClient("mcpctl", "0.1.0", opts.transport()), out: &out, notes: &out}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.client.Connect(ctx); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer c.client.Close()
+
+	tests := []struct {
+		args []string
+		out  string
+		err  error  // wrapped by the returned error
+		text string // part of the returned error's message
+	}{
+		{opts.args, "hello\n\nstructuredContent:\n{\n  \"length\": 5\n}\n", nil, ""},
+		{[]string{"fail"}, "it broke\n", errToolFailed, ""},
+		{[]string{"echo", "{not json"}, "", nil, "not valid JSON"},
+		{[]string{"missing"}, "", nil, "missing"},
+		{[]string{}, "", nil, "usage: call"},
+	}
+	for _, tt := range tests {
+		out.Reset()
+		err := runCall(ctx, c, tt.args)
+		switch {
+		case tt.err != nil && !errors.Is(err, tt.err),
+			tt.text != "" && (err == nil || !strings.Contains(err.Error(), tt.text)),
+			tt.err == nil && tt.text == "" && err != nil:
+			t.Errorf("call %q: err = %v", tt.args, err)
+		}
+		if out.String() != tt.out {
+			t.Errorf("call %q printed %q, want %q", tt.args, out.String(), tt.out)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"gonuxt-context-assistant/internal/mcp"
+)
+
+// recordedMessage is one line of a recording.
+type recordedMessage struct {
+	Time      time.Time    `json:"time"`
+	Direction string       `json:"direction"` // "send" (to the server) or "recv"
+	Message   *mcp.Message `json:"message"`
+}
+
+// recorder appends every message of a session to a JSON Lines file. Several sessions
+// can be recorded into the same file; each starts with its initialize request.
+type recorder struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+func newRecorder(path string) (*recorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open recording: %w", err)
+	}
+	return &recorder{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+// record is installed as mcp.Client.OnMessage.
+func (r *recorder) record(outgoing bool, msg *mcp.Message) {
+	direction := "recv"
+	if outgoing {
+		direction = "send"
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.enc.Encode(recordedMessage{Time: time.Now(), Direction: direction, Message: msg}); err != nil {
+		log.Printf("recording: %v", err)
+	}
+}
+
+func (r *recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+// exchange is a recorded request and the response it got (nil if none was recorded).
+type exchange struct {
+	request  *mcp.Message
+	response *mcp.Message
+}
+
+// readRecording pairs the requests we sent with their responses. Request ids restart
+// with every session, so pairing starts over at each initialize.
+func readRecording(path string) ([]*exchange, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var (
+		exchanges []*exchange
+		pending   = make(map[string]*exchange)
+	)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var entry recordedMessage
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.Message == nil {
+			return nil, fmt.Errorf("%s:%d: not a recorded message", path, line)
+		}
+		msg := entry.Message
+		switch {
+		case entry.Direction == "send" && msg.IsRequest():
+			if msg.Method == "initialize" {
+				pending = make(map[string]*exchange) // the client does its own handshake on replay
+				continue
+			}
+			ex := &exchange{request: msg}
+			exchanges = append(exchanges, ex)
+			pending[string(msg.ID)] = ex
+		case entry.Direction == "recv" && msg.IsResponse():
+			if ex, ok := pending[string(msg.ID)]; ok {
+				ex.response = msg
+				delete(pending, string(msg.ID))
+			}
+		}
+	}
+	return exchanges, scanner.Err()
+}
+
+// runReplay sends the recorded requests again, in order, and compares the answers with
+// the recorded ones. Answers that change by design (e.g. the current time) show up too.
+func runReplay(ctx context.Context, c *ctl, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: replay <file>")
+	}
+	exchanges, err := readRecording(args[0])
+	if err != nil {
+		return err
+	}
+	if len(exchanges) == 0 {
+		return fmt.Errorf("%s has no recorded requests", args[0])
+	}
+
+	differ := 0
+	for _, ex := range exchanges {
+		label := describe(ex.request)
+		var params any
+		if len(ex.request.Params) > 0 {
+			params = ex.request.Params
+		}
+		result, err := c.client.Call(ctx, ex.request.Method, params)
+		if ex.response == nil {
+			fmt.Fprintf(c.out, "skip  %s (no recorded answer)\n", label)
+			continue
+		}
+		if sameAnswer(ex.response, result, err) {
+			fmt.Fprintf(c.out, "ok    %s\n", label)
+			continue
+		}
+		differ++
+		fmt.Fprintf(c.out, "DIFF  %s\n", label)
+		var recordedErr error
+		if ex.response.Error != nil {
+			recordedErr = ex.response.Error
+		}
+		fmt.Fprintf(c.out, "  recorded: %s\n", answerText(ex.response.Result, recordedErr))
+		fmt.Fprintf(c.out, "  now:      %s\n", answerText(result, err))
+	}
+	if differ > 0 {
+		return fmt.Errorf("%d of %d answers differ from the recording", differ, len(exchanges))
+	}
+	return nil
+}
+
+// sameAnswer compares results as JSON values (so key order and spacing do not matter)
+// and errors by code.
+func sameAnswer(recorded *mcp.Message, result json.RawMessage, err error) bool {
+	if recorded.Error != nil || err != nil {
+		var rpcErr *mcp.Error
+		return recorded.Error != nil && errors.As(err, &rpcErr) && rpcErr.Code == recorded.Error.Code
+	}
+	var want, got any
+	if json.Unmarshal(recorded.Result, &want) != nil || json.Unmarshal(result, &got) != nil {
+		return false
+	}
+	return reflect.DeepEqual(want, got)
+}
+
+func answerText(result json.RawMessage, err error) string {
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return string(result)
+}
+
+// describe names a request for the replay report, e.g. "tools/call get_weather".
+func describe(msg *mcp.Message) string {
+	var p struct {
+		Name string `json:"name"`
+		URI  string `json:"uri"`
+	}
+	_ = json.Unmarshal(msg.Params, &p)
+	switch {
+	case p.Name != "":
+		return msg.Method + " " + p.Name
+	case p.URI != "":
+		return msg.Method + " " + p.URI
+	}
+	return msg.Method
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"gonuxt-context-assistant/internal/tools"
+)
+
+// HeaderAPIKey identifies the client an /ask call is billed to. Keys are not checked;
+// they only group usage (see internal/usage).
+const HeaderAPIKey = "X-API-Key"
+
+// AdminUsageHandler answers GET /admin/usage with the model usage since the API started:
+// in total, per API key and per session. It needs the admin token as a bearer token.
+func (h *Handler) AdminUsageHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.isAdmin(w, r) {
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.Usage == nil {
+		http.Error(w, "No language model is configured, so no usage is counted", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.Usage.Report())
+}
+
+// AdminPromptsHandler lists the prompt templates and their versions on GET, and reads the
+// template files again on POST, so edited prompts are used without a restart.
+func (h *Handler) AdminPromptsHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.isAdmin(w, r) {
+		return
+	}
+	if h.Prompts == nil {
+		http.Error(w, "No prompt registry is configured", http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if err := h.Prompts.Reload(); err != nil {
+			log.Printf("Error reloading prompts: %v", err)
+			http.Error(w, "Could not reload the prompts: "+err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		log.Println("Prompts reloaded")
+	default:
+		http.Error(w, "Only GET and POST methods are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, PromptsResponseBody{Prompts: h.Prompts.List()})
+}
+
+// AdminWeatherHandler lists the simulated weather on GET and adds or replaces the weather
+// of one city on PUT, e.g. {"city": "Lisbon", "condition": "rainy", "temperature": 17}.
+// MCP clients subscribed to the city's resource are told about the change.
+func (h *Handler) AdminWeatherHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.isAdmin(w, r) {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		var reports []tools.Weather
+		for _, city := range tools.WeatherCities() {
+			if weather, found := tools.WeatherFor(city); found {
+				reports = append(reports, weather)
+			}
+		}
+		writeJSON(w, http.StatusOK, reports)
+	case http.MethodPut:
+		var weather tools.Weather
+		if err := json.NewDecoder(r.Body).Decode(&weather); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if strings.TrimSpace(weather.City) == "" || (weather.Condition == "" && weather.Report == "") {
+			http.Error(w, "city and condition (or report) are required", http.StatusBadRequest)
+			return
+		}
+		tools.SetWeather(weather)
+		log.Printf("Weather of %s updated", weather.City)
+		weather, _ = tools.WeatherFor(weather.City)
+		writeJSON(w, http.StatusOK, weather)
+	default:
+		http.Error(w, "Only GET and PUT methods are allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// AdminCapitalsHandler lists the country capitals on GET and adds or replaces the capital
+// of one country on PUT, e.g. {"country": "Spain", "capital": "Madrid"}.
+func (h *Handler) AdminCapitalsHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.isAdmin(w, r) {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, tools.Capitals())
+	case http.MethodPut:
+		var entry tools.Capital
+		if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		entry.Country, entry.Capital = strings.TrimSpace(entry.Country), strings.TrimSpace(entry.Capital)
+		if entry.Country == "" || entry.Capital == "" {
+			http.Error(w, "country and capital are required", http.StatusBadRequest)
+			return
+		}
+		tools.SetCapital(entry.Country, entry.Capital)
+		log.Printf("Capital of %s updated", entry.Country)
+		writeJSON(w, http.StatusOK, entry)
+	default:
+		http.Error(w, "Only GET and PUT methods are allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// isAdmin checks the admin token, answering the request itself when it is missing or wrong.
+func (h *Handler) isAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if h.AdminToken == "" {
+		http.Error(w, "Admin endpoints are disabled", http.StatusNotFound)
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.AdminToken)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
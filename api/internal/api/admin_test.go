@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gonuxt-context-assistant/internal/tools"
+)
+
+func TestAdminWeatherHandlerUpdatesTheData(t *testing.T) {
+	h := &Handler{AdminToken: "secret"}
+	original, _ := tools.WeatherFor("Paris")
+	t.Cleanup(func() { tools.SetWeather(original) })
+
+	var changes []tools.DataChange
+	t.Cleanup(tools.Watch(func(change tools.DataChange) { changes = append(changes, change) }))
+
+	tests := []struct {
+		name   string
+		token  string
+		body   string
+		status int
+	}{
+		{"no token", "", `{"city": "Paris", "condition": "windy", "temperature": 12}`, http.StatusUnauthorized},
+		{"no condition", "secret", `{"city": "Paris"}`, http.StatusBadRequest},
+		{"invalid body", "secret", `{`, http.StatusBadRequest},
+		{"update", "secret", `{"city": "Paris", "condition": "windy", "temperature": 12}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPut, "/admin/weather", strings.NewReader(tt.body))
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		rec := httptest.NewRecorder()
+		h.AdminWeatherHandler(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d (%s)", tt.name, rec.Code, tt.status, rec.Body)
+		}
+	}
+
+	if weather, _ := tools.WeatherFor("Paris"); weather.Report != "windy with 12°C." {
+		t.Errorf("report = %q, want the update", weather.Report)
+	}
+	if len(changes) != 1 || changes[0].Key != "Paris" || changes[0].Added {
+		t.Errorf("changes = %+v, want one update of Paris", changes)
+	}
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"gonuxt-context-assistant/internal/rag"
+)
+
+// maxUploadBytes limits the body of POST /documents, all files together.
+const maxUploadBytes = 10 << 20
+
+// DocumentsHandler answers GET /documents with the indexed documents and the recent
+// ingestion jobs, and POST /documents by queueing documents for indexing. POST takes
+// either a multipart form with one or more "file" fields (and optional "title" and
+// "format" fields) or a JSON DocumentRequestBody, and answers 202 with the jobs; poll
+// GET /documents/jobs/{id} until they are finished. If the queue fills up partway through
+// an upload, the answer is 503 with the jobs of the documents queued before.
+func (h *Handler) DocumentsHandler(w http.ResponseWriter, r *http.Request) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Recovered from panic in DocumentsHandler: %v", r)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+	}()
+
+	if h.Ingester == nil {
+		http.Error(w, "Documents are not enabled", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, DocumentsResponseBody{Documents: h.Ingester.Index.Documents(), Jobs: h.Ingester.Jobs()})
+	case http.MethodPost:
+		h.uploadDocuments(w, r)
+	default:
+		http.Error(w, "Only GET and POST methods are allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) uploadDocuments(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes)
+	inputs, err := readDocuments(r)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, "Documents are too large (10 MB at most)", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Check every document before queueing any, so a bad file does not leave the ones
+	// before it queued.
+	for _, in := range inputs {
+		if _, err := in.ResolveFormat(); err != nil {
+			http.Error(w, "Only Markdown, HTML and text documents are supported", http.StatusUnsupportedMediaType)
+			return
+		}
+		if len(bytes.TrimSpace(in.Data)) == 0 {
+			http.Error(w, "Document "+in.Name+" has no text", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var resp DocumentJobsResponseBody
+	for _, in := range inputs {
+		job, err := h.Ingester.Submit(in)
+		if err != nil {
+			status, message := http.StatusInternalServerError, "Could not queue the document "+in.Name
+			if errors.Is(err, rag.ErrQueueFull) {
+				w.Header().Set("Retry-After", "10")
+				status, message = http.StatusServiceUnavailable, "Too many documents are waiting to be indexed; try again later"
+			} else {
+				log.Printf("Error queueing document %s: %v", in.Name, err)
+			}
+			if len(resp.Jobs) == 0 {
+				http.Error(w, message, status)
+				return
+			}
+			// The documents before this one are queued already: tell which.
+			resp.Error = message
+			writeJSON(w, status, resp)
+			return
+		}
+		log.Printf("Queued document %q for indexing (%s, %s)", in.Name, job.ID, job.Status)
+		resp.Jobs = append(resp.Jobs, job)
+	}
+	if len(resp.Jobs) == 1 {
+		w.Header().Set("Location", "/documents/jobs/"+resp.Jobs[0].ID)
+	}
+	writeJSON(w, http.StatusAccepted, resp)
+}
+
+// readDocuments reads the documents of an upload, multipart or JSON.
+func readDocuments(r *http.Request) ([]rag.Input, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		var reqBody DocumentRequestBody
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(reqBody.Text) == "" {
+			return nil, errors.New("text is required")
+		}
+		return []rag.Input{{
+			Name:   reqBody.Name,
+			Title:  reqBody.Title,
+			Format: rag.Format(reqBody.Format),
+			Data:   []byte(reqBody.Text),
+		}}, nil
+	}
+
+	if err := r.ParseMultipartForm(maxUploadBytes); err != nil {
+		return nil, err
+	}
+	files := r.MultipartForm.File["file"]
+	if len(files) == 0 {
+		return nil, errors.New(`no "file" field`)
+	}
+	var inputs []rag.Input
+	for _, header := range files {
+		file, err := header.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		in := rag.Input{Name: header.Filename, Format: rag.Format(r.FormValue("format")), Data: data}
+		if len(files) == 1 {
+			in.Title = r.FormValue("title") // a title only makes sense for a single file
+		}
+		inputs = append(inputs, in)
+	}
+	return inputs, nil
+}
+
+// DocumentHandler answers GET /documents/{id} with a document, DELETE /documents/{id} by
+// removing it from the index, and GET /documents/jobs/{id} with an ingestion job.
+// Removing a document takes it away from every user, so DELETE needs the admin token.
+func (h *Handler) DocumentHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Ingester == nil {
+		http.Error(w, "Documents are not enabled", http.StatusNotFound)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/documents/")
+
+	if jobID, ok := strings.CutPrefix(id, "jobs/"); ok {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		job, found := h.Ingester.Job(jobID)
+		if !found {
+			http.Error(w, "Job not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, job)
+		return
+	}
+
+	if id == "" || strings.Contains(id, "/") {
+		http.Error(w, "Expected /documents/{id}", http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		doc, found := h.Ingester.Index.Get(id)
+		if !found {
+			http.Error(w, "Document not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, doc)
+	case http.MethodDelete:
+		if !h.isAdmin(w, r) {
+			return
+		}
+		if !h.Ingester.Index.Remove(id) {
+			http.Error(w, "Document not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Removed document %s", id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Only GET and DELETE methods are allowed", http.StatusMethodNotAllowed)
+	}
+}
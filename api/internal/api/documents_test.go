@@ -0,0 +1,192 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gonuxt-context-assistant/internal/rag"
+)
+
+// gate is an Embedder that lets one call through per value sent on it.
+type gate chan struct{}
+
+func (g gate) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	select {
+	case <-g:
+		return make([][]float32, len(texts)), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// upload makes a multipart POST /documents of files, by name.
+func upload(t *testing.T, files ...string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for i := 0; i < len(files); i += 2 {
+		part, err := form.CreateFormFile("file", files[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write([]byte(files[i+1]))
+	}
+	form.Close()
+	req := httptest.NewRequest(http.MethodPost, "/documents", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return req
+}
+
+func TestUploadDocuments(t *testing.T) {
+	index := rag.NewIndex()
+	index.Embedder = make(gate) // nothing gets indexed: the jobs stay pending
+	ingester := rag.NewIngester(index, 1)
+	defer ingester.Close()
+	h := &Handler{Ingester: ingester}
+
+	tests := []struct {
+		name   string
+		req    *http.Request
+		status int
+		jobs   int // queued by the request
+	}{
+		{"json", httptest.NewRequest(http.MethodPost, "/documents", strings.NewReader(`{"name": "faq.txt", "text": "Trams run all day."}`)), http.StatusAccepted, 1},
+		{"json without text", httptest.NewRequest(http.MethodPost, "/documents", strings.NewReader(`{"name": "faq.txt", "text": " "}`)), http.StatusBadRequest, 0},
+		{"files", upload(t, "lisbon.md", "# Lisbon\n\nTrams.", "porto.html", "<h1>Porto</h1>"), http.StatusAccepted, 2},
+		{"an empty file", upload(t, "castle.md", "# Castle", "empty.md", " \n", "river.txt", "Douro"), http.StatusBadRequest, 0},
+		{"an unsupported file", upload(t, "castle.md", "# Castle", "slides.pdf", "%PDF"), http.StatusUnsupportedMediaType, 0},
+		{"no file", upload(t), http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		before := len(ingester.Jobs())
+		rec := httptest.NewRecorder()
+		h.DocumentsHandler(rec, tt.req)
+		if rec.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d (%s)", tt.name, rec.Code, tt.status, rec.Body)
+		}
+		if queued := len(ingester.Jobs()) - before; queued != tt.jobs {
+			t.Errorf("%s: %d jobs queued, want %d", tt.name, queued, tt.jobs)
+		}
+		if rec.Code != http.StatusAccepted {
+			continue
+		}
+		var resp DocumentJobsResponseBody
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Jobs) != tt.jobs || resp.Error != "" {
+			t.Errorf("%s: answer %+v, want %d jobs", tt.name, resp, tt.jobs)
+		}
+		if location := rec.Header().Get("Location"); (tt.jobs == 1) != (location == "/documents/jobs/"+resp.Jobs[0].ID) {
+			t.Errorf("%s: Location %q", tt.name, location)
+		}
+	}
+}
+
+func TestUploadAnswersWithTheJobsQueuedBeforeTheQueueFilledUp(t *testing.T) {
+	index := rag.NewIndex()
+	embed := make(gate)
+	index.Embedder = embed
+	ingester := rag.NewIngester(index, 1)
+	defer ingester.Close()
+	h := &Handler{Ingester: ingester}
+
+	waitRunning := func(id string) {
+		for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+			if job, _ := ingester.Job(id); job.Status == rag.JobRunning {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("job %s did not start", id)
+			}
+		}
+	}
+	// The worker holds one document; fill the queue behind it.
+	var jobs []rag.Job
+	for i := 0; ; i++ {
+		job, err := ingester.Submit(rag.Input{Name: "filler.txt", Data: []byte(strings.Repeat("x", i+1))})
+		if err != nil {
+			break
+		}
+		jobs = append(jobs, job)
+		if i == 0 {
+			waitRunning(job.ID)
+		}
+	}
+	// Let the first one through: the worker takes the next, and one place is free.
+	embed <- struct{}{}
+	waitRunning(jobs[1].ID)
+
+	rec := httptest.NewRecorder()
+	h.DocumentsHandler(rec, upload(t, "lisbon.md", "# Lisbon", "porto.md", "# Porto"))
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("status = %d, Retry-After %q; want 503 and a delay", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	var resp DocumentJobsResponseBody
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Jobs) != 1 || resp.Jobs[0].Name != "lisbon.md" || resp.Jobs[0].Status != rag.JobQueued || resp.Error == "" {
+		t.Errorf("answer %+v, want the queued lisbon.md job and an error", resp)
+	}
+	if _, ok := ingester.Job(resp.Jobs[0].ID); !ok {
+		t.Errorf("job %s of the answer is unknown", resp.Jobs[0].ID)
+	}
+
+	rec = httptest.NewRecorder()
+	h.DocumentsHandler(rec, upload(t, "castle.md", "# Castle"))
+	if rec.Code != http.StatusServiceUnavailable || strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+		t.Errorf("nothing queued: status = %d, %s; want a plain 503", rec.Code, rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestOnlyAdminsDeleteDocuments(t *testing.T) {
+	index := rag.NewIndex()
+	lisbon, err := index.Add(context.Background(), rag.Input{Name: "lisbon.md", Data: []byte("# Lisbon\n\nTrams.")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ingester := rag.NewIngester(index, 1)
+	defer ingester.Close()
+	h := &Handler{Ingester: ingester, AdminToken: "secret"}
+
+	tests := []struct {
+		name    string
+		method  string
+		id      string
+		token   string
+		handler *Handler
+		status  int
+	}{
+		{"get without token", http.MethodGet, lisbon.ID, "", h, http.StatusOK},
+		{"delete without token", http.MethodDelete, lisbon.ID, "", h, http.StatusUnauthorized},
+		{"delete with a wrong token", http.MethodDelete, lisbon.ID, "wrong", h, http.StatusUnauthorized},
+		{"delete without admin token set", http.MethodDelete, lisbon.ID, "secret", &Handler{Ingester: ingester}, http.StatusNotFound},
+		{"delete", http.MethodDelete, lisbon.ID, "secret", h, http.StatusNoContent},
+		{"get deleted", http.MethodGet, lisbon.ID, "", h, http.StatusNotFound},
+		{"delete unknown", http.MethodDelete, lisbon.ID, "secret", h, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/documents/"+tt.id, nil)
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		rec := httptest.NewRecorder()
+		tt.handler.DocumentHandler(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d (%s)", tt.name, rec.Code, tt.status, rec.Body)
+		}
+		if tt.status == http.StatusUnauthorized {
+			if _, ok := index.Get(lisbon.ID); !ok {
+				t.Fatalf("%s: the document was removed", tt.name)
+			}
+		}
+	}
+}
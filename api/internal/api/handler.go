@@ -4,6 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"gonuxt-context-assistant/internal/app/assistant"
+	"gonuxt-context-assistant/internal/app/gateway"
+	"gonuxt-context-assistant/internal/prompts"
+	"gonuxt-context-assistant/internal/rag"
+	"gonuxt-context-assistant/internal/usage"
 	"log"
 	"net/http"
 	"time"
@@ -12,7 +16,12 @@ import (
 // Handler struct to hold dependencies like the assistant service (if needed later)
 // This is a common pattern for injecting dependencies into handlers.
 type Handler struct {
-	Assistant *assistant.Service // The core assistant service
+	Assistant  *assistant.Service // The core assistant service
+	Gateway    *gateway.Gateway   // Optional: downstream MCP servers re-exposed on /mcp
+	Usage      *usage.Ledger      // Optional: model usage, reported on /admin/usage
+	AdminToken string             // Bearer token of the /admin endpoints; empty disables them
+	Prompts    *prompts.Registry  // Optional: prompt templates, listed and reloaded on /admin/prompts
+	Ingester   *rag.Ingester      // Optional: indexes documents uploaded to /documents
 }
 
 // NewHandler creates a new Handler instance.
@@ -37,6 +46,12 @@ func (h *Handler) AskHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Clients asking for an event stream get the answer as it is written
+	if wantsEventStream(r) {
+		h.AskStreamHandler(w, r)
+		return
+	}
+
 	var reqBody RequestBody
 	err := json.NewDecoder(r.Body).Decode(&reqBody)
 	if err != nil {
@@ -45,14 +60,32 @@ func (h *Handler) AskHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close() // Ensure the request body is closed
 
-	log.Printf("Received query: \"%s\"", reqBody.Query)
+	ctx, sessionID, err := h.withSession(r.Context(), reqBody.SessionID)
+	if err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, query, err := withMessages(ctx, reqBody)
+	if err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("Received query: \"%s\"", query)
+
+	// Count the model calls of this query, for the response and per client
+	ctx, tally := usage.Start(ctx, usage.Account{Key: r.Header.Get(HeaderAPIKey), Session: sessionID})
+	ctx, usedPrompts := prompts.Start(ctx) // which prompt versions produced the answer
+	ctx, citations := rag.Start(ctx)       // which documents it is based on
 
 	var answer string
-	query := reqBody.Query
 
-	answer, httpStatus := h.Assistant.ProcessQuery(r.Context(), query)
+	answer, httpStatus := h.Assistant.ProcessQuery(ctx, query)
 
-	respBody := ResponseBody{Answer: answer}
+	respBody := ResponseBody{Answer: answer, SessionID: sessionID, Prompts: usedPrompts.Versions(), Sources: citations.Sources()}
+	if totals := tally.Totals(); totals.Calls > 0 {
+		respBody.Usage = &totals
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(httpStatus) // Set status code before writing body
@@ -167,3 +200,29 @@ func (h *Handler) AskMultiCityWeatherFromQueryHandler(w http.ResponseWriter, r *
 		log.Printf("Error encoding response: %v", err)
 	}
 }
+
+// GatewayHealthHandler reports the state of every downstream MCP server of the gateway.
+// It answers 200 when all of them are connected and 503 otherwise, so it can back a
+// health check; the body says which server is down and why.
+func (h *Handler) GatewayHealthHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.Gateway == nil {
+		http.Error(w, "No gateway servers are configured", http.StatusNotFound)
+		return
+	}
+
+	health := h.Gateway.Health()
+	httpStatus := http.StatusOK
+	if health.Status != gateway.StatusOK {
+		httpStatus = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	if err := json.NewEncoder(w).Encode(health); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
@@ -1,11 +1,49 @@
 package api
 
+import (
+	"gonuxt-context-assistant/internal/app/conversation"
+	"gonuxt-context-assistant/internal/prompts"
+	"gonuxt-context-assistant/internal/rag"
+	"gonuxt-context-assistant/internal/usage"
+)
+
+// RequestBody is the body of /ask. SessionID continues an earlier conversation; without
+// one a new session is started. Clients that keep the conversation themselves send it as
+// Messages instead, oldest first; without a Query the last user message is the question.
 type RequestBody struct {
-	Query string `json:"query"`
+	Query     string        `json:"query"`
+	SessionID string        `json:"sessionId,omitempty"`
+	Messages  []ChatMessage `json:"messages,omitempty"`
+}
+
+// ChatMessage is one earlier message of a conversation: Role is "user" or "assistant".
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
 }
 
+// ResponseBody is the answer of /ask. SessionID is set when the assistant keeps history;
+// send it with the next query to ask a follow-up. Usage counts the tokens of the language
+// model calls made for the answer, if any, and Prompts the versions of the prompt
+// templates that produced it, by prompt name. Sources are the passages of documents the
+// answer is based on.
 type ResponseBody struct {
-	Answer string `json:"answer"`
+	Answer    string            `json:"answer"`
+	SessionID string            `json:"sessionId,omitempty"`
+	Usage     *usage.Totals     `json:"usage,omitempty"`
+	Prompts   map[string]string `json:"prompts,omitempty"`
+	Sources   []rag.Source      `json:"sources,omitempty"`
+}
+
+// StreamAnswer is the last event of a streamed answer. Status is the HTTP status /ask
+// would have answered with, since the stream itself always starts with 200.
+type StreamAnswer struct {
+	Answer    string            `json:"answer"`
+	Status    int               `json:"status"`
+	SessionID string            `json:"sessionId,omitempty"`
+	Usage     *usage.Totals     `json:"usage,omitempty"`
+	Prompts   map[string]string `json:"prompts,omitempty"`
+	Sources   []rag.Source      `json:"sources,omitempty"`
 }
 
 type MultipleCityRequestBody struct {
@@ -23,3 +61,34 @@ type MultipleAsyncRequestBody struct {
 type MultipleAsyncResponseBody struct {
 	Reports map[string]string `json:"reports"`
 }
+
+type SessionsResponseBody struct {
+	Sessions []conversation.Summary `json:"sessions"`
+}
+
+// DocumentRequestBody is a JSON document for POST /documents. Format is "markdown",
+// "html" or "text"; without it, it is told from the extension of Name, else text.
+type DocumentRequestBody struct {
+	Name   string `json:"name,omitempty"`
+	Title  string `json:"title,omitempty"`
+	Format string `json:"format,omitempty"`
+	Text   string `json:"text"`
+}
+
+// DocumentJobsResponseBody is the answer of POST /documents: one job per document. Error
+// is set when not all documents could be queued; Jobs then has those that were.
+type DocumentJobsResponseBody struct {
+	Jobs  []rag.Job `json:"jobs"`
+	Error string    `json:"error,omitempty"`
+}
+
+// DocumentsResponseBody is the answer of GET /documents.
+type DocumentsResponseBody struct {
+	Documents []rag.Document `json:"documents"`
+	Jobs      []rag.Job      `json:"jobs"` // recent ingestion jobs, most recent first
+}
+
+// PromptsResponseBody is the answer of /admin/prompts.
+type PromptsResponseBody struct {
+	Prompts []prompts.Info `json:"prompts"`
+}
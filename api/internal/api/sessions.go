@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"gonuxt-context-assistant/internal/app/assistant"
+	"gonuxt-context-assistant/internal/app/conversation"
+	"gonuxt-context-assistant/internal/llm"
+)
+
+// withSession puts the request's session on the context, starting a new session when the
+// client sent no ID. It returns the session ID, or "" when the assistant keeps no history.
+// IDs are always issued by the server (see conversation.ValidID).
+func (h *Handler) withSession(ctx context.Context, sessionID string) (context.Context, string, error) {
+	if h.Assistant.Sessions == nil {
+		return ctx, "", nil
+	}
+	switch {
+	case sessionID == "":
+		sessionID = conversation.NewID()
+	case !conversation.ValidID(sessionID):
+		return ctx, "", errors.New("sessionId must be one returned by an earlier answer")
+	}
+	return assistant.WithSession(ctx, sessionID), sessionID, nil
+}
+
+// withMessages puts the conversation the client sent on the context and returns the
+// question: the Query, or else the last message, which must then be the user's.
+func withMessages(ctx context.Context, reqBody RequestBody) (context.Context, string, error) {
+	if len(reqBody.Messages) == 0 {
+		return ctx, reqBody.Query, nil
+	}
+	messages := make([]llm.Message, 0, len(reqBody.Messages))
+	for i, msg := range reqBody.Messages {
+		role := llm.Role(msg.Role)
+		if role != llm.RoleUser && role != llm.RoleAssistant {
+			return ctx, "", fmt.Errorf("messages[%d]: role must be \"user\" or \"assistant\"", i)
+		}
+		messages = append(messages, llm.Message{Role: role, Content: msg.Content})
+	}
+
+	query := reqBody.Query
+	if query == "" {
+		last := messages[len(messages)-1]
+		if last.Role != llm.RoleUser {
+			return ctx, "", errors.New("the last message must be the user's question when there is no query")
+		}
+		query, messages = last.Content, messages[:len(messages)-1]
+	}
+	return assistant.WithMessages(ctx, messages), query, nil
+}
+
+// SessionsHandler answers GET /sessions with every session, most recent first. Sessions
+// belong to all clients, so like the /admin endpoints it needs the admin token.
+func (h *Handler) SessionsHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.isAdmin(w, r) {
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.Assistant.Sessions == nil {
+		http.Error(w, "Sessions are not enabled", http.StatusNotFound)
+		return
+	}
+
+	summaries, err := h.Assistant.Sessions.List(r.Context())
+	if err != nil {
+		log.Printf("Error listing sessions: %v", err)
+		http.Error(w, "Could not list sessions", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, SessionsResponseBody{Sessions: summaries})
+}
+
+// SessionHandler answers GET /sessions/{id} with the session's history and
+// DELETE /sessions/{id} by forgetting it. The random session ID is the credential: the
+// client that started the session, or anyone it shares the ID with, needs no admin token.
+func (h *Handler) SessionHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Assistant.Sessions == nil {
+		http.Error(w, "Sessions are not enabled", http.StatusNotFound)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/sessions/")
+	if id == "" || strings.Contains(id, "/") {
+		http.Error(w, "Expected /sessions/{id}", http.StatusNotFound)
+		return
+	}
+	if !conversation.ValidID(id) {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		conv, err := h.Assistant.Sessions.Get(r.Context(), id)
+		if errors.Is(err, conversation.ErrNotFound) {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Error loading session %s: %v", id, err)
+			http.Error(w, "Could not load session", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, conv)
+	case http.MethodDelete:
+		err := h.Assistant.Sessions.Delete(r.Context(), id)
+		if errors.Is(err, conversation.ErrNotFound) {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Error deleting session %s: %v", id, err)
+			http.Error(w, "Could not delete session", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Only GET and DELETE methods are allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gonuxt-context-assistant/internal/app/assistant"
+	"gonuxt-context-assistant/internal/app/conversation"
+)
+
+func TestOnlyTheSessionListNeedsTheAdminToken(t *testing.T) {
+	svc := assistant.NewService()
+	svc.Sessions = conversation.NewMemoryStore()
+	h := &Handler{Assistant: svc, AdminToken: "secret"}
+	alice, bob := conversation.NewID(), conversation.NewID()
+	for _, id := range []string{alice, bob, "guessable"} {
+		if err := svc.Sessions.Append(context.Background(), id, conversation.Turn{Query: "Weather in Lisbon?"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		token   string
+		handler http.HandlerFunc
+		status  int
+	}{
+		{"list without token", http.MethodGet, "/sessions", "", h.SessionsHandler, http.StatusUnauthorized},
+		{"list with a wrong token", http.MethodGet, "/sessions", alice, h.SessionsHandler, http.StatusUnauthorized},
+		{"list", http.MethodGet, "/sessions", "secret", h.SessionsHandler, http.StatusOK},
+		{"get by ID", http.MethodGet, "/sessions/" + alice, "", h.SessionHandler, http.StatusOK},
+		{"get unknown", http.MethodGet, "/sessions/" + conversation.NewID(), "", h.SessionHandler, http.StatusNotFound},
+		{"get by a non-server ID", http.MethodGet, "/sessions/guessable", "", h.SessionHandler, http.StatusNotFound},
+		{"delete non-server ID", http.MethodDelete, "/sessions/guessable", "secret", h.SessionHandler, http.StatusNotFound},
+		{"delete by ID", http.MethodDelete, "/sessions/" + alice, "", h.SessionHandler, http.StatusNoContent},
+		{"get deleted", http.MethodGet, "/sessions/" + alice, "", h.SessionHandler, http.StatusNotFound},
+		{"post", http.MethodPost, "/sessions/" + bob, "", h.SessionHandler, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		rec := httptest.NewRecorder()
+		tt.handler(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d (%s)", tt.name, rec.Code, tt.status, rec.Body)
+		}
+	}
+
+	if _, err := svc.Sessions.Get(context.Background(), bob); err != nil {
+		t.Errorf("bob's session: %v", err)
+	}
+}
+
+func TestAskOnlyAcceptsSessionIDsOfTheServer(t *testing.T) {
+	svc := assistant.NewService()
+	svc.Sessions = conversation.NewMemoryStore()
+	h := &Handler{Assistant: svc}
+
+	ask := func(body string) (int, ResponseBody) {
+		rec := httptest.NewRecorder()
+		h.AskHandler(rec, httptest.NewRequest(http.MethodPost, "/ask", strings.NewReader(body)))
+		var resp ResponseBody
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rec.Code, resp
+	}
+
+	status, first := ask(`{"query": "What is the weather in Lisbon?"}`)
+	if status != http.StatusOK || !conversation.ValidID(first.SessionID) {
+		t.Fatalf("first answer: %d, sessionId %q", status, first.SessionID)
+	}
+
+	tests := []struct {
+		name      string
+		sessionID string
+		status    int
+	}{
+		{"issued by the server", first.SessionID, http.StatusOK},
+		{"new one of the server's format", conversation.NewID(), http.StatusOK},
+		{"chosen by the client", "alice", http.StatusBadRequest},
+		{"upper case", strings.ToUpper(first.SessionID), http.StatusBadRequest},
+		{"too long", first.SessionID + "0", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		status, resp := ask(`{"query": "And in Paris?", "sessionId": "` + tt.sessionID + `"}`)
+		if status != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, status, tt.status)
+		}
+		if status == http.StatusOK && resp.SessionID != tt.sessionID {
+			t.Errorf("%s: sessionId = %q, want %q", tt.name, resp.SessionID, tt.sessionID)
+		}
+	}
+}
+
+func TestSessionsAreDisabledWithoutAdminToken(t *testing.T) {
+	svc := assistant.NewService()
+	svc.Sessions = conversation.NewMemoryStore()
+	h := &Handler{Assistant: svc}
+
+	rec := httptest.NewRecorder()
+	h.SessionsHandler(rec, httptest.NewRequest(http.MethodGet, "/sessions", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", rec.Code)
+	}
+}
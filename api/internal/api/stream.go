@@ -0,0 +1,164 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"gonuxt-context-assistant/internal/app/assistant"
+	"gonuxt-context-assistant/internal/prompts"
+	"gonuxt-context-assistant/internal/rag"
+	"gonuxt-context-assistant/internal/usage"
+)
+
+// Streaming answers for /ask, as Server-Sent Events. The stream sends:
+//
+//	event: token        data: {"type":"token","text":"It is "}
+//	event: tool_start   data: {"type":"tool_start","id":"call_1","tool":"get_weather","arguments":{"city":"Lisbon"}}
+//	event: tool_end     data: {"type":"tool_end","id":"call_1","tool":"get_weather","result":"..."}
+//	event: answer       data: {"answer":"It is sunny in Lisbon.","status":200,"sessionId":"..."}
+//
+// "answer" is always the last event and holds the whole answer, which is the one to show:
+// tokens only come from a language model, and a failed model attempt may have sent some
+// before the assistant fell back to keyword routing. Comments (": ping") are sent while
+// nothing else happens, so proxies keep the connection open.
+
+const streamHeartbeatInterval = 15 * time.Second
+
+// AskStreamHandler answers POST /ask/stream (or /ask with Accept: text/event-stream)
+// with an event stream. The query stops when the client disconnects.
+func (h *Handler) AskStreamHandler(w http.ResponseWriter, r *http.Request) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Recovered from panic in askStreamHandler: %v", r)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+	}()
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := w.(http.Flusher); !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	var reqBody RequestBody
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	ctx, sessionID, err := h.withSession(r.Context(), reqBody.SessionID)
+	if err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, query, err := withMessages(ctx, reqBody)
+	if err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("Received streaming query: \"%s\"", query)
+
+	ctx, tally := usage.Start(ctx, usage.Account{Key: r.Header.Get(HeaderAPIKey), Session: sessionID})
+	ctx, usedPrompts := prompts.Start(ctx)
+	ctx, citations := rag.Start(ctx)
+
+	// The query runs in its own goroutine and hands its events over a channel, so only
+	// this goroutine writes to w. Once the client is gone the events are dropped.
+	events := make(chan assistant.Event, 64)
+	done := make(chan StreamAnswer, 1)
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				log.Printf("Recovered from panic in streaming query: %v", rec)
+				done <- StreamAnswer{Answer: "Internal server error", Status: http.StatusInternalServerError}
+			}
+		}()
+		answer, httpStatus := h.Assistant.ProcessQueryWithEvents(ctx, query, func(e assistant.Event) {
+			select {
+			case events <- e:
+			case <-ctx.Done():
+			}
+		})
+		result := StreamAnswer{
+			Answer:    answer,
+			Status:    httpStatus,
+			SessionID: sessionID,
+			Prompts:   usedPrompts.Versions(),
+			Sources:   citations.Sources(),
+		}
+		if totals := tally.Totals(); totals.Calls > 0 {
+			result.Usage = &totals
+		}
+		done <- result
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flush(w)
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("Client went away; stopped streaming query: \"%s\"", query)
+			return
+		case e := <-events:
+			if err := writeSSE(w, e.Type, e); err != nil {
+				return
+			}
+		case answer := <-done:
+			// Events sent just before the answer may still be waiting in the channel.
+			for len(events) > 0 {
+				e := <-events
+				if err := writeSSE(w, e.Type, e); err != nil {
+					return
+				}
+			}
+			if err := writeSSE(w, "answer", answer); err != nil {
+				log.Printf("Error writing answer event: %v", err)
+			}
+			return
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flush(w)
+		}
+	}
+}
+
+// wantsEventStream reports whether the client asked for an event stream.
+func wantsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+func writeSSE(w http.ResponseWriter, event string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+		return err
+	}
+	flush(w)
+	return nil
+}
+
+func flush(w http.ResponseWriter) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
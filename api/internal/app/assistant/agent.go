@@ -0,0 +1,165 @@
+package assistant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"gonuxt-context-assistant/internal/llm"
+	"gonuxt-context-assistant/internal/prompts"
+	"gonuxt-context-assistant/internal/rag"
+	"gonuxt-context-assistant/internal/tools"
+)
+
+// The agent loop: the model sees the conversation and the tools, asks for tool calls, gets
+// their results back and repeats until it writes a final answer.
+
+const (
+	defaultMaxSteps     = 5
+	defaultAgentTimeout = 30 * time.Second
+)
+
+// ErrMaxSteps is returned when the model still wants tools after Service.MaxSteps turns.
+var ErrMaxSteps = errors.New("assistant: the model did not finish within the step limit")
+
+// runAgent answers the query with the model, running the tools it asks for. The loop
+// stops at a final answer, after MaxSteps model calls, or when AgentTimeout (or ctx)
+// runs out. On the last step the model may not call tools, so it has to answer with what
+// it has.
+// With onEvent set the model's answer is streamed to it, along with the tool calls.
+// history holds the earlier messages of the conversation. Before every model call the chat
+// is fitted into the token budget (see llm.HistoryManager). hits are passages of the
+// user's documents found for the query, given to the model after the system prompt.
+func (s *Service) runAgent(ctx context.Context, query string, history []llm.Message, hits []rag.Hit, onEvent QueryEvents) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.agentTimeout())
+	defer cancel()
+
+	messages := []llm.Message{{Role: llm.RoleSystem, Content: s.render(ctx, prompts.NameSystem, prompts.Data{Query: query})}}
+	if len(hits) > 0 {
+		passages := s.render(ctx, prompts.NameContext, prompts.Data{Query: query, Excerpts: excerpts(hits)})
+		messages = append(messages, llm.Message{Role: llm.RoleSystem, Content: passages})
+	}
+	messages = append(messages, history...)
+	messages = append(messages, llm.Message{Role: llm.RoleUser, Content: query})
+	defs := s.toolDefinitions()
+	maxSteps := s.maxSteps()
+
+	for step := 1; step <= maxSteps; step++ {
+		req := llm.Request{Messages: messages, Tools: defs, NoToolCalls: step == maxSteps}
+		fitted, err := s.history().Fit(ctx, messages, req.Tools)
+		if err != nil {
+			return "", fmt.Errorf("step %d: %w", step, err)
+		}
+		if len(fitted) != len(messages) {
+			s.Logf(ctx, LevelDebug, loggerLLM, "Step %d: shortened the chat from %d to %d messages", step, len(messages), len(fitted))
+		}
+		messages = fitted
+		req.Messages = messages
+		if onEvent != nil {
+			req.OnText = func(text string) { onEvent(Event{Type: EventToken, Text: text}) }
+		}
+		resp, err := s.LLM.Chat(ctx, req)
+		if err != nil {
+			return "", fmt.Errorf("step %d: %w", step, err)
+		}
+		messages = append(messages, resp.Message)
+
+		if len(resp.Message.ToolCalls) == 0 {
+			if strings.TrimSpace(resp.Message.Content) == "" {
+				return "", errors.New("the model returned an empty answer")
+			}
+			s.Logf(ctx, LevelDebug, loggerLLM, "Model answered after %d step(s)", step)
+			return resp.Message.Content, nil
+		}
+		s.Logf(ctx, LevelDebug, loggerLLM, "Step %d: model asked for %d tool call(s)", step, len(resp.Message.ToolCalls))
+		messages = append(messages, s.runToolCalls(ctx, resp.Message.ToolCalls, onEvent)...)
+	}
+	return "", ErrMaxSteps
+}
+
+// runToolCalls runs the tool calls of one model turn concurrently, like
+// GetMultiCityWeatherWithProgress fans out over cities, and returns one tool message per
+// call, in the order of calls. Calls still running when ctx ends are reported as cancelled.
+func (s *Service) runToolCalls(ctx context.Context, calls []llm.ToolCall, onEvent QueryEvents) []llm.Message {
+	results := make([]llm.Message, len(calls))
+	for i, call := range calls {
+		results[i] = llm.Message{
+			Role:       llm.RoleTool,
+			ToolCallID: call.ID,
+			Content:    fmt.Sprintf("The %s tool was cancelled.", call.Name),
+		}
+	}
+
+	var wg sync.WaitGroup
+	type toolResult struct {
+		Index   int
+		Content string
+	}
+	resultsChan := make(chan toolResult, len(calls))
+
+	for i, call := range calls {
+		wg.Add(1)
+		go func(index int, currentCall llm.ToolCall) {
+			defer wg.Done()
+			s.Logf(ctx, LevelInfo, loggerLLM, "Model called tool %s with %s", currentCall.Name, currentCall.Arguments)
+			onEvent.emit(Event{Type: EventToolStart, ToolCallID: currentCall.ID, Tool: currentCall.Name, Arguments: currentCall.Arguments})
+			// GetData stops waiting as soon as ctx ends, even if the tool is still busy.
+			content, err := tools.GetData(ctx, currentCall, func(c llm.ToolCall) (string, bool) {
+				return s.runTool(ctx, c), true
+			})
+			if err != nil {
+				s.Logf(ctx, LevelWarning, loggerLLM, "Tool %s stopped: %v", currentCall.Name, err)
+				content = fmt.Sprintf("The %s tool did not finish: %v", currentCall.Name, err)
+			}
+			onEvent.emit(Event{Type: EventToolEnd, ToolCallID: currentCall.ID, Tool: currentCall.Name, Result: content})
+			resultsChan <- toolResult{Index: index, Content: content}
+		}(i, call)
+	}
+
+	go func() {
+		wg.Wait()
+		close(resultsChan)
+	}()
+
+	for {
+		select {
+		case res, ok := <-resultsChan:
+			if !ok {
+				return results
+			}
+			results[res.Index].Content = res.Content
+		case <-ctx.Done():
+			return results // resultsChan is buffered, so the goroutines can still finish
+		}
+	}
+}
+
+func (s *Service) maxSteps() int {
+	if s.MaxSteps > 0 {
+		return s.MaxSteps
+	}
+	return defaultMaxSteps
+}
+
+func (s *Service) history() *llm.HistoryManager {
+	if s.History != nil {
+		return s.History
+	}
+	return &llm.HistoryManager{}
+}
+
+// maxQueryTokens limits a single question to half the budget, leaving the rest for the
+// system prompt, the tools and the conversation.
+func (s *Service) maxQueryTokens() int {
+	return s.history().MaxTokens() / 2
+}
+
+func (s *Service) agentTimeout() time.Duration {
+	if s.AgentTimeout > 0 {
+		return s.AgentTimeout
+	}
+	return defaultAgentTimeout
+}
@@ -0,0 +1,101 @@
+package assistant
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gonuxt-context-assistant/internal/llm/llmtest"
+)
+
+func TestLastStepForbidsToolCallsButKeepsTheTools(t *testing.T) {
+	script, err := llmtest.ParseScript([]byte(`{
+		"name": "step limit",
+		"turns": [
+			{
+				"expect": {"tools": ["get_current_datetime", "get_weather", "get_multi_city_weather", "get_capital"]},
+				"reply": {"toolCalls": [{"name": "get_weather", "arguments": {"city": "Lisbon"}}]}
+			},
+			{
+				"expect": {
+					"messages": [{"role": "tool", "contains": "Lisbon"}],
+					"tools": ["get_current_datetime", "get_weather", "get_multi_city_weather", "get_capital"],
+					"noToolCalls": true
+				},
+				"reply": {"content": "It is sunny in Lisbon."}
+			}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock := llmtest.NewMock(script)
+	svc := NewService()
+	svc.LLM = mock
+	svc.MaxSteps = 2
+
+	answer, err := svc.runAgent(context.Background(), "Weather in Lisbon?", nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer != "It is sunny in Lisbon." {
+		t.Errorf("answer = %q", answer)
+	}
+	if err := mock.Err(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAgentStreamsTheAnswer(t *testing.T) {
+	mock := llmtest.NewMock(llmtest.RecordedScript("weather_lisbon.json"))
+	svc := NewService()
+	svc.LLM = mock
+
+	var tokens strings.Builder
+	var results []string
+	answer, err := svc.runAgent(context.Background(), "Weather in Lisbon?", nil, nil, func(e Event) {
+		switch e.Type {
+		case EventToken:
+			tokens.WriteString(e.Text)
+		case EventToolEnd:
+			results = append(results, e.Result)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if tokens.String() != answer || answer != "It is sunny in Lisbon, 28°C." {
+		t.Errorf("streamed %q, answer %q", tokens.String(), answer)
+	}
+	if len(results) != 1 || !strings.Contains(results[0], "Lisbon") {
+		t.Errorf("tool results = %q, want the weather in Lisbon", results)
+	}
+}
+
+func TestAgentStopsAtTheStepLimit(t *testing.T) {
+	// A model that keeps calling tools, even when told not to.
+	script, err := llmtest.ParseScript([]byte(`{
+		"turns": [
+			{"reply": {"toolCalls": [{"name": "get_capital", "arguments": {"country": "Portugal"}}]}},
+			{"expect": {"noToolCalls": true}, "reply": {"toolCalls": [{"name": "get_capital", "arguments": {"country": "Spain"}}]}}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock := llmtest.NewMock(script)
+	svc := NewService()
+	svc.LLM = mock
+	svc.MaxSteps = 2
+
+	if _, err := svc.runAgent(context.Background(), "Capitals?", nil, nil, nil); !errors.Is(err, ErrMaxSteps) {
+		t.Errorf("err = %v, want ErrMaxSteps", err)
+	}
+	if err := mock.Err(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,27 @@
+package assistant
+
+import (
+	"context"
+	"errors"
+)
+
+// AskUser asks the person behind the current request for a missing value, offering the
+// accepted choices. It returns ErrNoAnswer when they decline.
+// Transports that can talk back to the user mid-request (MCP elicitation) attach one to
+// the request context with WithAskUser; plain HTTP requests have none.
+type AskUser func(ctx context.Context, question string, choices []string) (string, error)
+
+// ErrNoAnswer is returned by an AskUser when the user declines or dismisses the question.
+var ErrNoAnswer = errors.New("assistant: user did not answer")
+
+type askUserKey struct{}
+
+// WithAskUser returns a context carrying ask for the assistant to use.
+func WithAskUser(ctx context.Context, ask AskUser) context.Context {
+	return context.WithValue(ctx, askUserKey{}, ask)
+}
+
+func askUserFrom(ctx context.Context) AskUser {
+	ask, _ := ctx.Value(askUserKey{}).(AskUser)
+	return ask
+}
@@ -2,6 +2,7 @@ package assistant
 
 import (
 	"context" // Important for context propagation
+	"errors"
 	"fmt"
 	"log"
 	"net/http" // For HTTP status codes
@@ -9,6 +10,11 @@ import (
 	"sync" // For sync.WaitGroup
 	"time"
 
+	"gonuxt-context-assistant/internal/app/conversation"
+	"gonuxt-context-assistant/internal/app/mcpclient"
+	"gonuxt-context-assistant/internal/llm"
+	"gonuxt-context-assistant/internal/prompts"
+	"gonuxt-context-assistant/internal/rag"
 	"gonuxt-context-assistant/internal/tools" // Import our tools
 )
 
@@ -17,6 +23,35 @@ import (
 type Service struct {
 	// Add any dependencies here, e.g., Logger *log.Logger
 	Logger *log.Logger // Optional: if you want to log within the service
+
+	// LogSink also receives every record logged through Logf. Optional.
+	LogSink LogSink
+
+	// Remote gives access to tools of external MCP servers. Optional: nil means local tools only.
+	Remote *mcpclient.Manager
+
+	// LLM picks the tools for a query. Optional: nil means queries are routed by keyword.
+	LLM llm.LLMProvider
+	// MaxSteps limits the model calls of one query (default 5).
+	MaxSteps int
+	// AgentTimeout limits the time the model and its tools may take for one query (default 30s).
+	AgentTimeout time.Duration
+
+	// Sessions remembers the turns of each session (see WithSession). Optional: nil means
+	// every query stands alone.
+	Sessions conversation.Store
+
+	// History keeps the chat sent to the model within its token budget, summarizing old
+	// turns. Optional: nil means the default budget, without summaries.
+	History *llm.HistoryManager
+
+	// Prompts holds the texts of the assistant and the system prompt of the model.
+	// Optional: nil means the built-in prompts.
+	Prompts *prompts.Registry
+
+	// Documents are searched for every query, to ground the answer in the user's files.
+	// Optional: nil means no document search.
+	Documents *rag.Index
 }
 
 // NewService creates a new instance of the Assistant Service.
@@ -25,31 +60,116 @@ func NewService() *Service {
 }
 
 // ProcessQuery takes a context and a query string, returning the answer and an HTTP status code.
+// With an LLM configured the model picks and chains the tools (see runAgent); if it fails,
+// or without one, the query is routed by keyword. Within a session (see WithSession) the
+// earlier turns are taken into account and the answer is remembered, with the versions of
+// the prompts that produced it. With Documents set the answer is grounded in the passages
+// found for the query, cited in the context (see rag.Start).
 func (s *Service) ProcessQuery(ctx context.Context, query string) (string, int) {
-	var answer string
-	if contains(query, "time") || contains(query, "date") {
-		answer = tools.GetCurrentDateTime()
-	} else if contains(query, "weather") {
-		city := extractCity(query)
-		if city != "" {
-			ctx, cancel := context.WithTimeout(ctx, 3*time.Second) // Set a timeout for the request context
-			defer cancel()
+	return s.processQuery(ctx, query, nil)
+}
+
+func (s *Service) processQuery(ctx context.Context, query string, onEvent QueryEvents) (string, int) {
+	ctx, _ = prompts.Start(ctx) // callers may have started it to read the versions too
+
+	if llm.EstimateTokens(query) > s.maxQueryTokens() {
+		s.Logf(ctx, LevelNotice, loggerAssistant, "Query of about %d tokens rejected", llm.EstimateTokens(query))
+		return "Sorry, your question is too long. Please shorten it.", http.StatusRequestEntityTooLarge
+	}
+
+	conv := s.loadConversation(ctx)
+	prev := conv.LastTurn()
+	if prev.Query == "" {
+		prev = previousTurn(messagesFrom(ctx))
+	}
+
+	hits := s.searchDocuments(ctx, query)
 
-			log.Printf("Invoking GetWeather tool for city: %s", city)
-			weatherReport, _ := tools.GetData(ctx, city, tools.GetWeather)
-			answer = weatherReport
+	if s.LLM != nil {
+		answer, err := s.runAgent(ctx, query, s.historyMessages(ctx, conv), hits, onEvent)
+		if err == nil {
+			rag.Cite(ctx, hits...)
+			intent, city := resolveIntent(query, prev)
+			s.remember(ctx, conversation.Turn{Query: query, Answer: answer, Intent: intent, City: city})
+			return answer, http.StatusOK
+		}
+		if ctx.Err() != nil {
+			s.Logf(ctx, LevelWarning, loggerLLM, "Query stopped: %v", ctx.Err())
+			return "Sorry, answering took too long.", http.StatusGatewayTimeout
+		}
+		level := LevelWarning
+		if !llm.Retryable(err) {
+			level = LevelError // e.g. a wrong API key, which will not fix itself
+		}
+		s.Logf(ctx, level, loggerLLM, "LLM failed, falling back to keyword routing: %v", err)
+	}
+	turn := s.routeByKeyword(ctx, query, prev, hits)
+	s.remember(ctx, turn)
+	return turn.Answer, http.StatusOK
+}
+
+// routeByKeyword answers the query with the first tool whose keyword it mentions. prev is
+// the previous turn of the session, if any, for follow-up questions (see resolveIntent).
+// When no tool matches, the best of hits, the passages of documents found for the query,
+// is the answer.
+func (s *Service) routeByKeyword(ctx context.Context, query string, prev conversation.Turn, hits []rag.Hit) conversation.Turn {
+	intent, city := resolveIntent(query, prev)
+	turn := conversation.Turn{Query: query, Intent: intent, City: city}
+	switch intent {
+	case IntentTime:
+		turn.Answer = tools.GetCurrentDateTime()
+	case IntentWeather:
+		if city == "" {
+			city = s.askForCity(ctx)
+			turn.City = city
+		}
+		if city != "" {
+			turn.Answer, _ = s.cityWeather(ctx, city)
+		} else {
+			turn.Answer = s.render(ctx, prompts.NameAskCity, prompts.Data{Query: query})
+		}
+	default:
+		if toolName, args, ok := s.matchRemoteTool(query); ok {
+			turn.Intent = IntentRemote
+			turn.Answer = s.callRemoteTool(ctx, toolName, args)
+		} else if len(hits) > 0 {
+			turn.Intent = IntentDocuments
+			turn.Answer = s.render(ctx, prompts.NameDocument, prompts.Data{Query: query, Excerpts: excerpts(hits[:1])})
+			rag.Cite(ctx, hits[0])
 		} else {
-			answer = "Please specify a city for weather information. E.g., 'What's the weather in London?'"
+			turn.Answer = s.render(ctx, prompts.NameGreeting, prompts.Data{Query: query})
 		}
-	} else {
-		answer = "Hello! I am a simple assistant. I can tell you the current time or the weather in a major city. Try asking me about 'time' or 'weather in London'."
 	}
+	return turn
+}
 
-	return answer, http.StatusOK
+// cityWeather fetches the weather report for one city, giving up after 3 seconds.
+func (s *Service) cityWeather(ctx context.Context, city string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second) // Set a timeout for the request context
+	defer cancel()
+
+	s.Logf(ctx, LevelInfo, loggerWeather, "Invoking GetWeather tool for city: %s", city)
+	s.prompts().Mark(ctx, prompts.NameWeather) // GetWeather words its answer with it
+	weatherReport, err := tools.GetData(ctx, city, tools.GetWeather)
+	if err != nil {
+		s.logWeatherError(ctx, city, err)
+	}
+	return weatherReport, err
 }
 
 // GetMultiCityWeather takes a context and a slice of city names, returning a map of reports and an HTTP status code.
 func (s *Service) GetMultiCityWeather(ctx context.Context, cities []string) (map[string]string, int) {
+	return s.GetMultiCityWeatherWithProgress(ctx, cities, nil)
+}
+
+// CityProgress is called each time one city of a multi-city request finishes.
+// completed counts the cities done so far, out of total.
+type CityProgress func(completed, total int, city, report string)
+
+// GetMultiCityWeatherWithProgress works like GetMultiCityWeather but calls onProgress (if not nil)
+// as each city completes. If ctx is cancelled it stops waiting, marks the unfinished cities
+// as cancelled and returns http.StatusGatewayTimeout.
+func (s *Service) GetMultiCityWeatherWithProgress(ctx context.Context, cities []string, onProgress CityProgress) (map[string]string, int) {
 	reports := make(map[string]string)
 	var wg sync.WaitGroup
 
@@ -58,6 +178,7 @@ func (s *Service) GetMultiCityWeather(ctx context.Context, cities []string) (map
 		Report string
 	}
 	resultsChan := make(chan cityReport, len(cities))
+	s.prompts().Mark(ctx, prompts.NameWeather)
 
 	for _, city := range cities {
 		wg.Add(1)
@@ -66,6 +187,7 @@ func (s *Service) GetMultiCityWeather(ctx context.Context, cities []string) (map
 			// Pass the context received by GetMultiCityWeather down to GetData
 			result, err := tools.GetData(ctx, currentCity, tools.GetWeather) // Reuse GetWeather via GetData
 			if err != nil {
+				s.logWeatherError(ctx, currentCity, err)
 				result = fmt.Sprintf("Weather data for %s could not be found.", currentCity)
 			}
 			resultsChan <- cityReport{City: currentCity, Report: result}
@@ -77,11 +199,29 @@ func (s *Service) GetMultiCityWeather(ctx context.Context, cities []string) (map
 		close(resultsChan)
 	}()
 
-	for res := range resultsChan {
-		reports[res.City] = res.Report
+	completed := 0
+	for {
+		select {
+		case res, ok := <-resultsChan:
+			if !ok {
+				return reports, http.StatusOK // If all individual requests handle their own errors, overall OK
+			}
+			reports[res.City] = res.Report
+			completed++
+			if onProgress != nil {
+				onProgress(completed, len(cities), res.City, res.Report)
+			}
+		case <-ctx.Done():
+			// The goroutines can still finish: resultsChan is buffered, so nothing leaks.
+			s.Logf(ctx, LevelWarning, loggerWeather, "Multi-city weather stopped after %d of %d cities: %v", completed, len(cities), ctx.Err())
+			for _, city := range cities {
+				if _, done := reports[city]; !done {
+					reports[city] = fmt.Sprintf("Weather request for %s was cancelled.", city)
+				}
+			}
+			return reports, http.StatusGatewayTimeout
+		}
 	}
-
-	return reports, http.StatusOK // If all individual requests handle their own errors, overall OK
 }
 
 func (s *Service) GetWeatherForCitiesFromQuery(ctx context.Context, query string) (map[string]string, int) {
@@ -90,12 +230,63 @@ func (s *Service) GetWeatherForCitiesFromQuery(ctx context.Context, query string
 
 	reports, err := tools.GetWeatherForCities(ctx, cities) // Return the result of the private function.
 	if err != nil {
-		log.Printf("Error fetching weather reports: %v", err)
+		s.Logf(ctx, LevelError, loggerWeather, "Error fetching weather reports: %v", err)
 		return nil, http.StatusInternalServerError
 	}
 	return reports, http.StatusOK // Return the reports and HTTP status OK.
 }
 
+// askForCity asks the user which city they mean when the transport supports it (see AskUser).
+// An answer that is not one of the offered cities is asked again, up to askCityAttempts
+// times. It returns "" if it cannot ask or gets no usable answer.
+func (s *Service) askForCity(ctx context.Context) string {
+	ask := askUserFrom(ctx)
+	if ask == nil {
+		return ""
+	}
+	choices := tools.WeatherCities()
+	question := "Which city would you like the weather for?"
+	for range askCityAttempts {
+		answer, err := ask(ctx, question, choices)
+		if err != nil {
+			if !errors.Is(err, ErrNoAnswer) {
+				s.Logf(ctx, LevelWarning, loggerAssistant, "Could not ask the user for a city: %v", err)
+			}
+			return ""
+		}
+		if city, ok := matchChoice(answer, choices); ok {
+			return city
+		}
+		s.Logf(ctx, LevelNotice, loggerAssistant, "The user answered %q, which is not one of the offered cities", answer)
+		question = fmt.Sprintf("I have no weather for %q. Which of these cities would you like the weather for?", answer)
+	}
+	return ""
+}
+
+// askCityAttempts is how many times askForCity asks before giving up.
+const askCityAttempts = 2
+
+// matchChoice returns the choice answer names, ignoring case and surrounding spaces.
+func matchChoice(answer string, choices []string) (string, bool) {
+	answer = strings.TrimSpace(answer)
+	for _, choice := range choices {
+		if strings.EqualFold(answer, choice) {
+			return choice, true
+		}
+	}
+	return "", false
+}
+
+// logWeatherError logs why tools.GetData returned no weather for city: a timeout or
+// cancellation is a warning, an unknown city only a notice.
+func (s *Service) logWeatherError(ctx context.Context, city string, err error) {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		s.Logf(ctx, LevelWarning, loggerWeather, "Weather request for %s stopped: %v", city, err)
+		return
+	}
+	s.Logf(ctx, LevelNotice, loggerWeather, "No weather data for city: %s", city)
+}
+
 // --- Helper functions (copy from your old main.go if they were there) ---
 // You might put these in a separate internal/util package or keep them private to assistant package.
 
@@ -105,24 +296,13 @@ func contains(s, substr string) bool {
 
 func extractCity(query string) string {
 	// Simplified extraction, actual LLM parsing would be more complex
-	lowerQuery := strings.ToLower(query)
-	cities := []string{"lisbon", "london", "new york", "paris", "tokyo", "porto"}
-	for _, city := range cities {
-		if strings.Contains(lowerQuery, city) {
-			return strings.Title(city) // Capitalize for consistency
-		}
+	if cities := tools.ExtractCitiesFromQuery(query); len(cities) > 0 {
+		return cities[0] // Already capitalized in the known-city list
 	}
 	return ""
 }
 
+// ExtractCitiesFromQuery returns every known city mentioned in the query (see tools.KnownCities).
 func ExtractCitiesFromQuery(query string) []string {
-	knownCities := []string{"Lisbon", "London", "New York", "Paris", "Berlin", "Madrid"}
-	var foundCities []string
-
-	for _, city := range knownCities {
-		if strings.Contains(strings.ToLower(query), strings.ToLower(city)) {
-			foundCities = append(foundCities, city)
-		}
-	}
-	return foundCities
+	return tools.ExtractCitiesFromQuery(query)
 }
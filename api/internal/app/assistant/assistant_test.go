@@ -0,0 +1,130 @@
+package assistant
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"gonuxt-context-assistant/internal/llm/llmtest"
+)
+
+func TestProcessQueryWithScriptedModel(t *testing.T) {
+	tests := []struct {
+		script   string
+		query    string
+		answer   string // the whole answer, or a part of the fallback's
+		calls    []string
+		fallback bool
+	}{
+		{"weather_lisbon.json", "What's the weather in Lisbon?", "It is sunny in Lisbon, 28°C.", []string{`get_weather {"city":"Lisbon"}`}, false},
+		{"capitals_parallel.json", "What are the capitals of Portugal and the United Kingdom?", "Lisbon and London.",
+			[]string{`get_capital {"country":"Portugal"}`, `get_capital {"country":"United Kingdom"}`}, false},
+		// The model is overloaded: the query is answered by keyword routing instead.
+		{"model_unavailable.json", "What's the weather in Lisbon?", "Lisbon", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.script, func(t *testing.T) {
+			mock := llmtest.NewMock(llmtest.RecordedScript(tt.script))
+			svc := NewService()
+			svc.LLM = mock
+
+			var (
+				mu    sync.Mutex
+				calls []string
+			)
+			answer, status := svc.ProcessQueryWithEvents(context.Background(), tt.query, func(e Event) {
+				if e.Type != EventToolStart {
+					return
+				}
+				var args bytes.Buffer
+				if err := json.Compact(&args, e.Arguments); err != nil {
+					t.Error(err)
+				}
+				mu.Lock()
+				calls = append(calls, e.Tool+" "+args.String())
+				mu.Unlock()
+			})
+
+			if err := mock.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if status != http.StatusOK {
+				t.Errorf("status = %d, want 200", status)
+			}
+			if tt.fallback && !strings.Contains(answer, tt.answer) || !tt.fallback && answer != tt.answer {
+				t.Errorf("answer = %q, want %q", answer, tt.answer)
+			}
+			slices.Sort(calls) // calls of one turn run concurrently
+			if !slices.Equal(calls, tt.calls) {
+				t.Errorf("tool calls = %q, want %q", calls, tt.calls)
+			}
+		})
+	}
+}
+
+func TestProcessQueryReportsUnexpectedPrompts(t *testing.T) {
+	mock := llmtest.NewMock(llmtest.RecordedScript("weather_lisbon.json"))
+	svc := NewService()
+	svc.LLM = mock
+
+	// The script expects a question about Lisbon; the assistant hides the mismatch by
+	// routing the query by keyword, but the mock still reports it.
+	answer, status := svc.ProcessQuery(context.Background(), "What's the weather in Paris?")
+	if status != http.StatusOK || !strings.Contains(answer, "Paris") {
+		t.Errorf("answer = %q (%d), want the keyword routing answer", answer, status)
+	}
+	if err := mock.Err(); err == nil || !strings.Contains(err.Error(), "Lisbon") {
+		t.Errorf("mock.Err() = %v, want the mismatch of the first turn", err)
+	}
+}
+
+func TestAskForCityOnlyAcceptsTheOfferedCities(t *testing.T) {
+	tests := []struct {
+		name    string
+		answers []string // "" stands for declining
+		asked   int
+		city    string // the city of the answer, "" for the prompt to name one
+	}{
+		{"offered city", []string{"Lisbon"}, 1, "Lisbon"},
+		{"other case", []string{"  tokyo "}, 1, "Tokyo"},
+		{"asked again", []string{"Atlantis", "Paris"}, 2, "Paris"},
+		{"never offered", []string{"Atlantis", "El Dorado", "Paris"}, 2, ""},
+		{"declined", []string{""}, 1, ""},
+	}
+	for _, tt := range tests {
+		var questions []string
+		ask := func(ctx context.Context, question string, choices []string) (string, error) {
+			if !slices.Contains(choices, "Lisbon") {
+				t.Errorf("%s: choices = %q, want the weather cities", tt.name, choices)
+			}
+			questions = append(questions, question)
+			answer := tt.answers[len(questions)-1]
+			if answer == "" {
+				return "", ErrNoAnswer
+			}
+			return answer, nil
+		}
+
+		answer, status := NewService().ProcessQuery(WithAskUser(context.Background(), ask), "What's the weather like?")
+		if status != http.StatusOK {
+			t.Errorf("%s: status = %d, want 200", tt.name, status)
+		}
+		if len(questions) != tt.asked {
+			t.Errorf("%s: asked %d times (%q), want %d", tt.name, len(questions), questions, tt.asked)
+		}
+		if tt.asked > 1 && !strings.Contains(questions[1], tt.answers[0]) {
+			t.Errorf("%s: second question = %q, want it to mention %q", tt.name, questions[1], tt.answers[0])
+		}
+		if tt.city != "" && !strings.Contains(answer, tt.city) {
+			t.Errorf("%s: answer = %q, want the weather in %s", tt.name, answer, tt.city)
+		}
+		if tt.city == "" && (strings.Contains(answer, "Atlantis") || strings.Contains(answer, "Paris")) {
+			t.Errorf("%s: answer = %q, want no weather", tt.name, answer)
+		}
+	}
+}
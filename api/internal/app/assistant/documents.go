@@ -0,0 +1,41 @@
+package assistant
+
+import (
+	"context"
+
+	"gonuxt-context-assistant/internal/prompts"
+	"gonuxt-context-assistant/internal/rag"
+)
+
+// With a document index (Service.Documents) every query is also looked up in the user's
+// documents. A language model gets the passages found next to the tools (the "context"
+// prompt); without one, a query no tool matches is answered with the best passage. The
+// passages an answer was based on are cited as its sources (see rag.Start).
+
+// searchDocuments returns the passages of the documents relevant to query, or nil.
+func (s *Service) searchDocuments(ctx context.Context, query string) []rag.Hit {
+	if s.Documents == nil {
+		return nil
+	}
+	hits, err := s.Documents.Search(ctx, query)
+	if err != nil {
+		s.Logf(ctx, LevelWarning, loggerAssistant, "Document search: %v", err)
+	}
+	if len(hits) > 0 {
+		s.Logf(ctx, LevelDebug, loggerAssistant, "Found %d passage(s) for the query, best in %q", len(hits), hits[0].Title)
+	}
+	return hits
+}
+
+// excerpts numbers hits for the prompt templates. A heading repeating the title of its
+// document is left out.
+func excerpts(hits []rag.Hit) []prompts.Excerpt {
+	list := make([]prompts.Excerpt, len(hits))
+	for i, hit := range hits {
+		list[i] = prompts.Excerpt{N: i + 1, Title: hit.Title, Heading: hit.Heading, Text: hit.Text}
+		if hit.Heading == hit.Title {
+			list[i].Heading = ""
+		}
+	}
+	return list
+}
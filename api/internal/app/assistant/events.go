@@ -0,0 +1,48 @@
+package assistant
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Events let a caller follow a query while it is answered, e.g. to stream the answer to
+// the browser as it is written (see the /ask/stream handler).
+
+// Kinds of Event.
+const (
+	EventToken     = "token"      // a piece of the model's answer
+	EventToolStart = "tool_start" // the model called a tool
+	EventToolEnd   = "tool_end"   // the tool returned
+)
+
+// Event is something that happened while answering a query.
+type Event struct {
+	Type string `json:"type"`
+
+	Text string `json:"text,omitempty"` // EventToken
+
+	// Tool events; the ID links the start of a call to its end.
+	ToolCallID string          `json:"id,omitempty"`
+	Tool       string          `json:"tool,omitempty"`
+	Arguments  json.RawMessage `json:"arguments,omitempty"` // EventToolStart
+	Result     string          `json:"result,omitempty"`    // EventToolEnd
+}
+
+// QueryEvents receives the events of one query. Tool events of concurrent calls arrive
+// from several goroutines at once.
+type QueryEvents func(Event)
+
+// ProcessQueryWithEvents is ProcessQuery, reporting to onEvent as the answer is written
+// and tools are called. Tokens only come from a model; keyword routing produces just the
+// returned answer. Tokens of an attempt that failed may already have been sent when the
+// query falls back to keyword routing, so the returned answer is the one to keep.
+func (s *Service) ProcessQueryWithEvents(ctx context.Context, query string, onEvent QueryEvents) (string, int) {
+	return s.processQuery(ctx, query, onEvent)
+}
+
+// emit sends e to onEvent, if there is one.
+func (onEvent QueryEvents) emit(e Event) {
+	if onEvent != nil {
+		onEvent(e)
+	}
+}
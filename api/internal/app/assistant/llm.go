@@ -0,0 +1,117 @@
+package assistant
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gonuxt-context-assistant/internal/app/mcpclient"
+	"gonuxt-context-assistant/internal/llm"
+	"gonuxt-context-assistant/internal/mcp"
+	"gonuxt-context-assistant/internal/tools"
+)
+
+// With a language model configured (Service.LLM) the model reads the query and picks the
+// tools to call (see runAgent), instead of the keyword matching in routeByKeyword. Its
+// system prompt is the "system" prompt of the registry (see internal/prompts).
+
+// localTools are the functions of internal/tools offered to the model. They mirror the
+// MCP tools of the same names (see mcpserver).
+var localTools = []llm.Tool{
+	{
+		Name:        "get_current_datetime",
+		Description: "Returns the current date and time.",
+		Parameters:  &mcp.Schema{Type: "object"},
+	},
+	{
+		Name:        "get_weather",
+		Description: "Returns the current weather for a single city.",
+		Parameters: &mcp.Schema{
+			Type:       "object",
+			Properties: map[string]*mcp.Schema{"city": {Type: "string", Description: "City name, e.g. Lisbon"}},
+			Required:   []string{"city"},
+		},
+	},
+	{
+		Name:        "get_multi_city_weather",
+		Description: "Returns the current weather for several cities at once.",
+		Parameters: &mcp.Schema{
+			Type: "object",
+			Properties: map[string]*mcp.Schema{
+				"cities": {Type: "array", Items: &mcp.Schema{Type: "string"}, MinItems: 1},
+			},
+			Required: []string{"cities"},
+		},
+	},
+	{
+		Name:        "get_capital",
+		Description: "Returns the capital city of a country.",
+		Parameters: &mcp.Schema{
+			Type:       "object",
+			Properties: map[string]*mcp.Schema{"country": {Type: "string", Description: "Country name, e.g. Portugal"}},
+			Required:   []string{"country"},
+		},
+	},
+}
+
+// toolDefinitions lists every tool the model may call: the local ones and those of
+// external MCP servers.
+func (s *Service) toolDefinitions() []llm.Tool {
+	defs := append([]llm.Tool(nil), localTools...)
+	for _, tool := range s.RemoteTools() {
+		defs = append(defs, llm.Tool{Name: tool.Name, Description: tool.Description, Parameters: tool.InputSchema})
+	}
+	return defs
+}
+
+// runTool executes one tool call of the model and returns the result as text. Problems
+// are returned as text too, so the model can see them.
+func (s *Service) runTool(ctx context.Context, call llm.ToolCall) string {
+	var args struct {
+		City    string   `json:"city"`
+		Cities  []string `json:"cities"`
+		Country string   `json:"country"`
+	}
+	if err := json.Unmarshal(call.Arguments, &args); err != nil {
+		return fmt.Sprintf("Invalid arguments for %s: %v", call.Name, err)
+	}
+
+	switch call.Name {
+	case "get_current_datetime":
+		return tools.GetCurrentDateTime()
+	case "get_weather":
+		report, err := s.cityWeather(ctx, args.City)
+		if err != nil {
+			return fmt.Sprintf("Weather data for %s could not be found.", args.City)
+		}
+		return report
+	case "get_multi_city_weather":
+		reports, _ := s.GetMultiCityWeather(ctx, args.Cities)
+		cities := make([]string, 0, len(reports))
+		for city := range reports {
+			cities = append(cities, city)
+		}
+		sort.Strings(cities)
+		lines := make([]string, 0, len(cities))
+		for _, city := range cities {
+			lines = append(lines, reports[city])
+		}
+		return strings.Join(lines, "\n")
+	case "get_capital":
+		if capital, found := tools.CapitalOf(args.Country); found {
+			return fmt.Sprintf("The capital of %s is %s.", args.Country, capital)
+		}
+		return tools.GetCapital(args.Country)
+	}
+
+	if s.Remote != nil && strings.Contains(call.Name, mcpclient.NamespaceSeparator) {
+		var remoteArgs map[string]any
+		if err := json.Unmarshal(call.Arguments, &remoteArgs); err != nil {
+			return fmt.Sprintf("Invalid arguments for %s: %v", call.Name, err)
+		}
+		return s.callRemoteTool(ctx, call.Name, remoteArgs)
+	}
+	return "Unknown tool: " + call.Name
+}
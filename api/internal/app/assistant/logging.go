@@ -0,0 +1,48 @@
+package assistant
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// LogLevel is the severity of a service log record. The names are the syslog ones MCP
+// uses, so records can be forwarded to MCP clients unchanged.
+type LogLevel string
+
+// Levels used by the service, least severe first.
+const (
+	LevelDebug   LogLevel = "debug"
+	LevelInfo    LogLevel = "info"
+	LevelNotice  LogLevel = "notice"
+	LevelWarning LogLevel = "warning"
+	LevelError   LogLevel = "error"
+)
+
+// Logger names, so whoever receives the records can tell where they come from.
+const (
+	loggerAssistant = "assistant"
+	loggerWeather   = "weather"
+	loggerRemote    = "remote"
+	loggerLLM       = "llm"
+)
+
+// LogSink receives every record the service logs, e.g. to forward it to MCP clients
+// (see mcpserver.LogSink). ctx is the one of the query or tool call being logged about,
+// so a record can follow the request it belongs to. It is called synchronously and must
+// not block.
+type LogSink func(ctx context.Context, level LogLevel, logger, message string)
+
+// Logf writes a record to s.Logger (the standard logger when nil) and hands it to s.LogSink.
+// logger names the part of the service that logs, e.g. "weather".
+func (s *Service) Logf(ctx context.Context, level LogLevel, logger, format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	if s.Logger != nil {
+		s.Logger.Print(message)
+	} else {
+		log.Print(message)
+	}
+	if s.LogSink != nil {
+		s.LogSink(ctx, level, logger, message)
+	}
+}
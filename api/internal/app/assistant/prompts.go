@@ -0,0 +1,44 @@
+package assistant
+
+import (
+	"context"
+
+	"gonuxt-context-assistant/internal/prompts"
+)
+
+// The assistant's texts (system prompt, greeting, ...) come from the prompt registry
+// (Service.Prompts), so they can be changed without recompiling. The versions rendered for
+// a query are saved with its turn (see remember).
+
+// prompts returns the prompt registry, the built-in prompts by default.
+func (s *Service) prompts() *prompts.Registry {
+	if s.Prompts != nil {
+		return s.Prompts
+	}
+	return prompts.Default()
+}
+
+// render renders the named prompt with the tools filled in. A prompt that fails to render
+// is logged and replaced by its built-in version, so a broken file never breaks an answer.
+func (s *Service) render(ctx context.Context, name string, data prompts.Data) string {
+	if data.Tools == nil {
+		data.Tools = s.promptTools()
+	}
+	text, err := s.prompts().Render(ctx, name, data)
+	if err == nil {
+		return text
+	}
+	s.Logf(ctx, LevelError, loggerAssistant, "Prompt %s failed, using the built-in one: %v", name, err)
+	text, _ = prompts.Default().Render(ctx, name, data)
+	return text
+}
+
+// promptTools lists the tools the assistant can use, for the templates.
+func (s *Service) promptTools() []prompts.Tool {
+	defs := s.toolDefinitions()
+	list := make([]prompts.Tool, 0, len(defs))
+	for _, def := range defs {
+		list = append(list, prompts.Tool{Name: def.Name, Description: def.Description})
+	}
+	return list
+}
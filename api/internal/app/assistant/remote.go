@@ -0,0 +1,122 @@
+package assistant
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"gonuxt-context-assistant/internal/app/mcpclient"
+	"gonuxt-context-assistant/internal/mcp"
+)
+
+// Tools from external MCP servers (see mcpclient) are reached by keyword, like the local
+// ones: a query that mentions a remote tool's name, e.g. "docs search goroutines" for
+// docs__search, calls it with the query as its single text argument.
+
+const remoteToolTimeout = 10 * time.Second
+
+// matchRemoteTool finds the remote tool named most precisely in the query and builds its
+// arguments. "self get capital" prefers self__get_capital over other servers' get_capital.
+func (s *Service) matchRemoteTool(query string) (string, map[string]any, bool) {
+	if s.Remote == nil {
+		return "", nil, false
+	}
+	lowerQuery := strings.ToLower(query)
+	var (
+		bestName  string
+		bestArgs  map[string]any
+		bestMatch int
+	)
+	for _, tool := range s.Remote.Tools() {
+		match := mentionsTool(lowerQuery, tool.Name)
+		if match <= bestMatch {
+			continue
+		}
+		if args, ok := argumentsFromQuery(tool.InputSchema, query); ok {
+			bestName, bestArgs, bestMatch = tool.Name, args, match
+		}
+	}
+	return bestName, bestArgs, bestMatch > 0
+}
+
+// callRemoteTool invokes a remote tool and flattens its text content into an answer.
+func (s *Service) callRemoteTool(ctx context.Context, name string, args map[string]any) string {
+	ctx, cancel := context.WithTimeout(ctx, remoteToolTimeout)
+	defer cancel()
+
+	s.Logf(ctx, LevelInfo, loggerRemote, "Invoking remote tool %s", name)
+	result, err := s.Remote.CallTool(ctx, name, args)
+	if err != nil {
+		s.Logf(ctx, LevelError, loggerRemote, "Remote tool %s failed: %v", name, err)
+		return "Sorry, the " + name + " tool is not available right now."
+	}
+
+	var parts []string
+	for _, content := range result.Content {
+		if content.Type == "text" && content.Text != "" {
+			parts = append(parts, content.Text)
+		}
+	}
+	if len(parts) == 0 {
+		return "The " + name + " tool returned no text."
+	}
+	return strings.Join(parts, "\n")
+}
+
+// mentionsTool reports how precisely the query names the tool: the length of the longest
+// form found, 0 if none. Forms are the full name ("docs__search"), the tool's own name
+// ("search") and both with separators read as spaces ("docs search").
+func mentionsTool(lowerQuery, name string) int {
+	name = strings.ToLower(name)
+	candidates := []string{name, spaced(name)}
+	if _, toolName, ok := strings.Cut(name, mcpclient.NamespaceSeparator); ok {
+		candidates = append(candidates, toolName, spaced(toolName))
+	}
+	longest := 0
+	for _, candidate := range candidates {
+		if len(candidate) >= 3 && len(candidate) > longest && strings.Contains(lowerQuery, candidate) {
+			longest = len(candidate)
+		}
+	}
+	return longest
+}
+
+func spaced(name string) string {
+	return strings.Join(strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' }), " ")
+}
+
+// argumentsFromQuery fills a tool's arguments from a plain query. Only tools that need
+// nothing, or a single text argument, can be called this way.
+func argumentsFromQuery(inputSchema any, query string) (map[string]any, bool) {
+	schema, _ := inputSchema.(map[string]any)
+	properties, _ := schema["properties"].(map[string]any)
+	required, _ := schema["required"].([]any)
+
+	var textProps []string
+	for name, prop := range properties {
+		if p, ok := prop.(map[string]any); ok && p["type"] == "string" {
+			textProps = append(textProps, name)
+		}
+	}
+
+	switch {
+	case len(required) == 0 && len(textProps) == 0:
+		return map[string]any{}, true
+	case len(required) == 1:
+		name, _ := required[0].(string)
+		if p, ok := properties[name].(map[string]any); ok && p["type"] == "string" {
+			return map[string]any{name: query}, true
+		}
+	case len(required) == 0 && len(textProps) == 1:
+		return map[string]any{textProps[0]: query}, true
+	}
+	return nil, false
+}
+
+// RemoteTools lists the tools currently available from external MCP servers.
+func (s *Service) RemoteTools() []mcp.Tool {
+	if s.Remote == nil {
+		return nil
+	}
+	return s.Remote.Tools()
+}
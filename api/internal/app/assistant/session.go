@@ -0,0 +1,153 @@
+package assistant
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"gonuxt-context-assistant/internal/app/conversation"
+	"gonuxt-context-assistant/internal/llm"
+	"gonuxt-context-assistant/internal/prompts"
+)
+
+// Sessions: with a conversation store (Service.Sessions) and a session ID on the request
+// context (WithSession), every answered query is remembered, and the next query of the
+// same session can build on it. The model sees the earlier turns as chat messages (or the
+// messages the client sent, see WithMessages); keyword routing falls back to the last
+// intent and city, so "What's the weather in Lisbon?" followed by "And in Paris?" answers
+// with the weather in Paris.
+
+// Intents recorded for each turn.
+const (
+	IntentTime      = "time"
+	IntentWeather   = "weather"
+	IntentRemote    = "remote"    // a tool of an external MCP server
+	IntentDocuments = "documents" // answered from the user's documents (see Service.Documents)
+)
+
+// followUpPrefixes start questions that continue the previous one.
+var followUpPrefixes = []string{"and ", "what about ", "how about ", "also ", "same ", "now "}
+
+type sessionKey struct{}
+
+// WithSession returns a context answering queries as part of the session id.
+func WithSession(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, sessionKey{}, id)
+}
+
+func sessionFrom(ctx context.Context) string {
+	id, _ := ctx.Value(sessionKey{}).(string)
+	return id
+}
+
+type messagesKey struct{}
+
+// WithMessages returns a context carrying the earlier messages of the conversation as the
+// client kept them. The model gets them instead of the session's history.
+func WithMessages(ctx context.Context, messages []llm.Message) context.Context {
+	return context.WithValue(ctx, messagesKey{}, messages)
+}
+
+func messagesFrom(ctx context.Context) []llm.Message {
+	messages, _ := ctx.Value(messagesKey{}).([]llm.Message)
+	return messages
+}
+
+// historyMessages returns the earlier messages for the model: the client's, or else the
+// turns of the session.
+func (s *Service) historyMessages(ctx context.Context, conv *conversation.Conversation) []llm.Message {
+	if messages := messagesFrom(ctx); len(messages) > 0 {
+		return messages
+	}
+	if conv == nil {
+		return nil
+	}
+	messages := make([]llm.Message, 0, 2*len(conv.Turns))
+	for _, turn := range conv.Turns {
+		messages = append(messages,
+			llm.Message{Role: llm.RoleUser, Content: turn.Query},
+			llm.Message{Role: llm.RoleAssistant, Content: turn.Answer},
+		)
+	}
+	return messages
+}
+
+// previousTurn replays the user's questions among messages through resolveIntent, so
+// keyword routing can follow up on a conversation kept by the client.
+func previousTurn(messages []llm.Message) conversation.Turn {
+	var prev conversation.Turn
+	for _, msg := range messages {
+		if msg.Role == llm.RoleUser {
+			intent, city := resolveIntent(msg.Content, prev)
+			prev = conversation.Turn{Query: msg.Content, Intent: intent, City: city}
+		}
+	}
+	return prev
+}
+
+// loadConversation returns the conversation of the context's session, or nil when there
+// is no session (or no store). A session without turns yet is not an error.
+func (s *Service) loadConversation(ctx context.Context) *conversation.Conversation {
+	id := sessionFrom(ctx)
+	if s.Sessions == nil || id == "" {
+		return nil
+	}
+	conv, err := s.Sessions.Get(ctx, id)
+	if errors.Is(err, conversation.ErrNotFound) {
+		return &conversation.Conversation{ID: id}
+	}
+	if err != nil {
+		s.Logf(ctx, LevelWarning, loggerAssistant, "Could not load session %s: %v", id, err)
+		return &conversation.Conversation{ID: id}
+	}
+	return conv
+}
+
+// remember appends the turn to the context's session, if there is one, with the versions
+// of the prompts rendered for it. The versions are logged for every answer.
+func (s *Service) remember(ctx context.Context, turn conversation.Turn) {
+	turn.Prompts = prompts.Versions(ctx)
+	if turn.Prompts != nil {
+		s.Logf(ctx, LevelDebug, loggerAssistant, "Answered with prompts %v", turn.Prompts)
+	}
+	id := sessionFrom(ctx)
+	if s.Sessions == nil || id == "" {
+		return
+	}
+	if err := s.Sessions.Append(ctx, id, turn); err != nil {
+		s.Logf(ctx, LevelWarning, loggerAssistant, "Could not save turn of session %s: %v", id, err)
+	}
+}
+
+// resolveIntent works out what the query asks for and about which city, using the
+// previous turn for follow-ups: a city alone continues a weather question, "and now?"
+// repeats the last intent, and a weather question without a city means the last one.
+// An empty intent means none of the built-in tools.
+func resolveIntent(query string, prev conversation.Turn) (intent, city string) {
+	city = extractCity(query)
+	switch {
+	case contains(query, "time") || contains(query, "date"):
+		intent = IntentTime
+	case contains(query, "weather"):
+		intent = IntentWeather
+	case prev.Intent == IntentWeather && city != "":
+		intent = IntentWeather
+	case prev.Intent != "" && prev.Intent != IntentRemote && isFollowUp(query):
+		intent = prev.Intent
+	}
+	if city == "" && intent != "" {
+		city = prev.City
+	}
+	return intent, city
+}
+
+// isFollowUp reports whether the query reads as a continuation, e.g. "and in Paris?".
+func isFollowUp(query string) bool {
+	q := strings.ToLower(strings.TrimSpace(query))
+	for _, prefix := range followUpPrefixes {
+		if strings.HasPrefix(q, prefix) || q == strings.TrimSpace(prefix)+"?" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,203 @@
+// Package conversation keeps the history of the assistant's sessions, so a follow-up like
+// "and in Paris?" can be answered from the turns before it. Store is the storage
+// interface; MemoryStore keeps everything in memory and is lost on restart.
+package conversation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned for a session that does not exist (or no longer does).
+var ErrNotFound = errors.New("conversation: session not found")
+
+// Turn is one question of a session and its answer.
+type Turn struct {
+	Query  string `json:"query"`
+	Answer string `json:"answer"`
+	// Intent is what the question asked for, e.g. "weather" or "time", and City the city
+	// it was about, if any. Follow-up questions fall back to them.
+	Intent string `json:"intent,omitempty"`
+	City   string `json:"city,omitempty"`
+	// Prompts are the versions of the prompts that produced the answer, by prompt name,
+	// e.g. {"system": "v2"} (see internal/prompts).
+	Prompts map[string]string `json:"prompts,omitempty"`
+	Time    time.Time         `json:"time"`
+}
+
+// Conversation is the history of one session, oldest turn first.
+type Conversation struct {
+	ID        string    `json:"id"`
+	Turns     []Turn    `json:"turns"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// LastTurn returns the most recent turn, or a zero Turn for an empty conversation.
+func (c *Conversation) LastTurn() Turn {
+	if c == nil || len(c.Turns) == 0 {
+		return Turn{}
+	}
+	return c.Turns[len(c.Turns)-1]
+}
+
+// Summary describes a session without its turns, for listings.
+type Summary struct {
+	ID        string    `json:"id"`
+	Turns     int       `json:"turns"`
+	LastQuery string    `json:"lastQuery,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Store keeps conversations. Implementations must be safe for concurrent use.
+type Store interface {
+	// Get returns a copy of the session's conversation, or ErrNotFound.
+	Get(ctx context.Context, id string) (*Conversation, error)
+	// Append adds a turn to the session, creating the session if needed.
+	Append(ctx context.Context, id string, turn Turn) error
+	// List returns every session, most recently updated first.
+	List(ctx context.Context) ([]Summary, error)
+	// Delete removes the session, or returns ErrNotFound.
+	Delete(ctx context.Context, id string) error
+}
+
+// NewID returns a random session ID: 32 lowercase hex digits.
+func NewID() string {
+	b := make([]byte, idBytes)
+	if _, err := rand.Read(b); err != nil {
+		panic("conversation: no randomness: " + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
+
+// idBytes is how much randomness a session ID holds.
+const idBytes = 16
+
+// ValidID reports whether id has the format of NewID. Whoever knows a session ID can read
+// the session, so IDs chosen by clients, which may be guessable, are not accepted.
+func ValidID(id string) bool {
+	if len(id) != 2*idBytes {
+		return false
+	}
+	for _, c := range id {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+const (
+	defaultMaxTurns = 50
+	defaultIdleTTL  = 24 * time.Hour
+)
+
+// MemoryStore is a Store in memory. Long sessions keep only their last MaxTurns turns,
+// and sessions idle for longer than IdleTTL are dropped.
+type MemoryStore struct {
+	MaxTurns int           // default 50
+	IdleTTL  time.Duration // default 24h
+
+	mu            sync.Mutex
+	conversations map[string]*Conversation
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{conversations: make(map[string]*Conversation)}
+}
+
+// Get implements Store.
+func (m *MemoryStore) Get(ctx context.Context, id string) (*Conversation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire()
+	conv, ok := m.conversations[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	out := *conv
+	out.Turns = append([]Turn(nil), conv.Turns...)
+	return &out, nil
+}
+
+// Append implements Store.
+func (m *MemoryStore) Append(ctx context.Context, id string, turn Turn) error {
+	if id == "" {
+		return errors.New("conversation: empty session ID")
+	}
+	if turn.Time.IsZero() {
+		turn.Time = time.Now()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire()
+	conv, ok := m.conversations[id]
+	if !ok {
+		conv = &Conversation{ID: id, CreatedAt: turn.Time}
+		m.conversations[id] = conv
+	}
+	conv.Turns = append(conv.Turns, turn)
+	if maxTurns := m.maxTurns(); len(conv.Turns) > maxTurns {
+		conv.Turns = append([]Turn(nil), conv.Turns[len(conv.Turns)-maxTurns:]...)
+	}
+	conv.UpdatedAt = turn.Time
+	return nil
+}
+
+// List implements Store.
+func (m *MemoryStore) List(ctx context.Context) ([]Summary, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire()
+	summaries := make([]Summary, 0, len(m.conversations))
+	for _, conv := range m.conversations {
+		summaries = append(summaries, Summary{
+			ID:        conv.ID,
+			Turns:     len(conv.Turns),
+			LastQuery: conv.LastTurn().Query,
+			CreatedAt: conv.CreatedAt,
+			UpdatedAt: conv.UpdatedAt,
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].UpdatedAt.After(summaries[j].UpdatedAt) })
+	return summaries, nil
+}
+
+// Delete implements Store.
+func (m *MemoryStore) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.conversations[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.conversations, id)
+	return nil
+}
+
+// expire drops idle sessions; m.mu must be held.
+func (m *MemoryStore) expire() {
+	ttl := m.IdleTTL
+	if ttl <= 0 {
+		ttl = defaultIdleTTL
+	}
+	for id, conv := range m.conversations {
+		if time.Since(conv.UpdatedAt) > ttl {
+			delete(m.conversations, id)
+		}
+	}
+}
+
+func (m *MemoryStore) maxTurns() int {
+	if m.MaxTurns > 0 {
+		return m.MaxTurns
+	}
+	return defaultMaxTurns
+}
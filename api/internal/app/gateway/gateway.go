@@ -0,0 +1,194 @@
+// Package gateway turns the API into an MCP gateway: it connects to the downstream MCP
+// servers listed in the configuration and re-exposes their tools, resources and prompts,
+// namespaced by server, on an mcp.Server (the one behind /mcp). Downstream servers that
+// are down are left out of the lists until they reconnect.
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"gonuxt-context-assistant/internal/app/mcpclient"
+	"gonuxt-context-assistant/internal/config"
+	"gonuxt-context-assistant/internal/mcp"
+)
+
+// Health states of the gateway as a whole.
+const (
+	StatusOK       = "ok"       // every downstream server is connected
+	StatusDegraded = "degraded" // at least one is connecting, failed or stopped
+)
+
+// Gateway keeps an mcp.Server in sync with the downstream servers.
+type Gateway struct {
+	server   *mcp.Server
+	upstream *mcpclient.Manager
+
+	mu        sync.Mutex // serialises sync
+	published published
+}
+
+// published is what sync registered last, so the next sync knows what to remove and
+// whether clients need a list_changed notification.
+type published struct {
+	tools     []mcp.Tool
+	resources []mcp.Resource
+	templates []mcp.ResourceTemplate
+	prompts   []mcp.Prompt
+}
+
+// Health is the state of the gateway and of each downstream server.
+type Health struct {
+	Status  string                   `json:"status"` // StatusOK or StatusDegraded
+	Servers []mcpclient.ServerHealth `json:"servers"`
+}
+
+// New prepares a gateway that publishes the servers' capabilities on server.
+// Nothing is connected until Start.
+func New(server *mcp.Server, servers map[string]config.MCPServer) *Gateway {
+	g := &Gateway{
+		server:   server,
+		upstream: mcpclient.NewManager(servers),
+	}
+	g.upstream.OnChange = g.sync
+	return g
+}
+
+// Start connects to every downstream server in the background.
+func (g *Gateway) Start(ctx context.Context) {
+	g.upstream.Start(ctx)
+}
+
+// Close disconnects from the downstream servers.
+func (g *Gateway) Close() {
+	g.upstream.Close()
+}
+
+// Health reports whether every downstream server is connected.
+func (g *Gateway) Health() Health {
+	health := Health{Status: StatusOK, Servers: g.upstream.Health()}
+	for _, server := range health.Servers {
+		if server.Status != mcpclient.StatusConnected {
+			health.Status = StatusDegraded
+		}
+	}
+	return health
+}
+
+// sync registers what the connected servers offer now and removes what they no longer
+// do (including everything of a server that went down), then tells the clients about
+// the lists that changed.
+func (g *Gateway) sync() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	old := g.published
+	now := published{
+		tools:     g.upstream.Tools(),
+		resources: g.upstream.Resources(),
+		templates: g.upstream.ResourceTemplates(),
+		prompts:   g.upstream.Prompts(),
+	}
+
+	// Register the current entries first so clients never see an item of a healthy
+	// server disappear, then drop the ones that are gone.
+	keep := make(map[string]bool)
+	for _, tool := range now.tools {
+		g.server.AddTool(tool, g.callTool) // replaces a tool with the same name
+		keep[tool.Name] = true
+	}
+	for _, tool := range old.tools {
+		if !keep[tool.Name] {
+			g.server.RemoveTool(tool.Name)
+		}
+	}
+
+	keep = make(map[string]bool)
+	for _, prompt := range now.prompts {
+		g.server.AddPrompt(prompt, g.getPrompt)
+		keep[prompt.Name] = true
+	}
+	for _, prompt := range old.prompts {
+		if !keep[prompt.Name] {
+			g.server.RemovePrompt(prompt.Name)
+		}
+	}
+
+	// AddResource and AddResourceTemplate append, so resources are swapped one by one.
+	keep = make(map[string]bool)
+	for _, resource := range now.resources {
+		g.server.RemoveResource(resource.URI)
+		g.server.AddResource(resource, g.readResource)
+		keep[resource.URI] = true
+	}
+	for _, resource := range old.resources {
+		if !keep[resource.URI] {
+			g.server.RemoveResource(resource.URI)
+		}
+	}
+
+	keep = make(map[string]bool)
+	for _, template := range now.templates {
+		g.server.RemoveResourceTemplate(template.URITemplate)
+		g.server.AddResourceTemplate(template, g.readResource, nil)
+		keep[template.URITemplate] = true
+	}
+	for _, template := range old.templates {
+		if !keep[template.URITemplate] {
+			g.server.RemoveResourceTemplate(template.URITemplate)
+		}
+	}
+	g.published = now
+
+	// Reconnect attempts of a failed server also end up here; stay quiet unless
+	// something clients can see is different.
+	if !sameJSON(old.tools, now.tools) {
+		g.server.NotifyToolListChanged()
+	}
+	if !sameJSON(old.resources, now.resources) || !sameJSON(old.templates, now.templates) {
+		g.server.NotifyResourceListChanged()
+	}
+	if !sameJSON(old.prompts, now.prompts) {
+		g.server.NotifyPromptListChanged()
+	}
+}
+
+// sameJSON compares two lists as clients would see them.
+func sameJSON(a, b any) bool {
+	x, errX := json.Marshal(a)
+	y, errY := json.Marshal(b)
+	return errX == nil && errY == nil && bytes.Equal(x, y)
+}
+
+func (g *Gateway) callTool(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var args any // nil sends no arguments at all, rather than null
+	if len(req.Params.Arguments) > 0 {
+		args = req.Params.Arguments
+	}
+	result, err := g.upstream.CallTool(ctx, req.Params.Name, args)
+	if errors.Is(err, mcpclient.ErrUnavailable) {
+		// The server went down after the client listed its tools.
+		return mcp.ErrorResult(fmt.Sprintf("%s is temporarily unavailable, try again later.", req.Params.Name)), nil
+	}
+	return result, err // protocol errors from downstream (*mcp.Error) are passed on as they are
+}
+
+func (g *Gateway) readResource(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	result, err := g.upstream.ReadResource(ctx, req.URI)
+	if errors.Is(err, mcpclient.ErrUnavailable) || errors.Is(err, mcpclient.ErrUnknownResource) {
+		return nil, mcp.ResourceNotFound(req.URI)
+	}
+	return result, err
+}
+
+func (g *Gateway) getPrompt(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	result, err := g.upstream.GetPrompt(ctx, req.Params.Name, req.Params.Arguments)
+	if errors.Is(err, mcpclient.ErrUnavailable) {
+		return nil, mcp.NewError(mcp.CodeInternalError, "%s is temporarily unavailable", req.Params.Name)
+	}
+	return result, err
+}
@@ -0,0 +1,200 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gonuxt-context-assistant/internal/app/mcpclient"
+	"gonuxt-context-assistant/internal/config"
+	"gonuxt-context-assistant/internal/mcp"
+)
+
+// flakyServer is a downstream MCP server over HTTP that can be taken down: while down
+// it answers 404 to everything, so existing sessions end and new ones cannot start.
+type flakyServer struct {
+	*httptest.Server
+	down atomic.Bool
+}
+
+func newFlakyServer(t *testing.T) *flakyServer {
+	t.Helper()
+	server := mcp.NewServer("fake", "1.0.0")
+	server.AddTool(mcp.Tool{Name: "echo", InputSchema: &mcp.Schema{Type: "object"}}, func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args struct {
+			Text string `json:"text"`
+		}
+		if err := req.Bind(&args); err != nil {
+			return nil, err
+		}
+		return mcp.TextResult(args.Text), nil
+	})
+	handler := mcp.NewHTTPHandler(server)
+
+	fs := &flakyServer{}
+	fs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fs.down.Load() {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(fs.Close)
+	return fs
+}
+
+// startGateway runs a gateway for servers behind an MCP endpoint and returns it with a
+// client connected to that endpoint and the notifications the client receives.
+func startGateway(t *testing.T, servers map[string]config.MCPServer) (*Gateway, *mcp.Client, <-chan *mcp.Message) {
+	t.Helper()
+	front := mcp.NewServer("gateway", "0.0.0")
+	gw := New(front, servers)
+	gw.Start(context.Background())
+	t.Cleanup(gw.Close)
+
+	endpoint := httptest.NewServer(mcp.NewHTTPHandler(front))
+	t.Cleanup(endpoint.Close)
+	notifications := make(chan *mcp.Message, 16)
+	client := mcp.NewClient("test", "0.0.0", &mcp.HTTPClientTransport{URL: endpoint.URL})
+	client.OnNotification = func(msg *mcp.Message) { notifications <- msg }
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Connect(ctx); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return gw, client, notifications
+}
+
+// waitStatus waits until the downstream server called name reports status.
+func waitStatus(t *testing.T, gw *Gateway, name, status string) mcpclient.ServerHealth {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		for _, server := range gw.Health().Servers {
+			if server.Name == name && server.Status == status {
+				return server
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s did not become %s: %+v", name, status, gw.Health())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func toolNames(t *testing.T, client *mcp.Client) []string {
+	t.Helper()
+	tools, err := client.ListTools(context.Background())
+	if err != nil {
+		t.Fatalf("tools/list: %v", err)
+	}
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+	return names
+}
+
+func TestHealth(t *testing.T) {
+	fake := newFlakyServer(t)
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close() // nothing listens on its address any more
+
+	tests := []struct {
+		name    string
+		servers map[string]config.MCPServer
+		status  string
+	}{
+		{"all connected", map[string]config.MCPServer{"fake": {URL: fake.URL}}, StatusOK},
+		{"one failed", map[string]config.MCPServer{"fake": {URL: fake.URL}, "broken": {URL: unreachable.URL}}, StatusDegraded},
+	}
+	for _, tt := range tests {
+		gw, _, _ := startGateway(t, tt.servers)
+		waitStatus(t, gw, "fake", mcpclient.StatusConnected)
+		if _, broken := tt.servers["broken"]; broken {
+			if h := waitStatus(t, gw, "broken", mcpclient.StatusFailed); h.LastError == "" || h.Failures == 0 {
+				t.Errorf("%s: broken = %+v, want the error and a failure", tt.name, h)
+			}
+		}
+		if health := gw.Health(); health.Status != tt.status || len(health.Servers) != len(tt.servers) {
+			t.Errorf("%s: health = %+v, want %s", tt.name, health, tt.status)
+		}
+		gw.Close()
+		for _, server := range gw.Health().Servers {
+			if server.Status != mcpclient.StatusStopped {
+				t.Errorf("%s: after Close, %s is %s", tt.name, server.Name, server.Status)
+			}
+		}
+	}
+}
+
+func TestFailedServersAreHiddenUntilTheyRecover(t *testing.T) {
+	fake := newFlakyServer(t)
+	gw, client, notifications := startGateway(t, map[string]config.MCPServer{"fake": {URL: fake.URL}})
+	ctx := context.Background()
+
+	waitStatus(t, gw, "fake", mcpclient.StatusConnected)
+	if names := toolNames(t, client); !slices.Contains(names, "fake__echo") {
+		t.Fatalf("tools = %q, want fake__echo", names)
+	}
+	result, err := client.CallTool(ctx, "fake__echo", map[string]string{"text": "hello"})
+	if err != nil || result.Content[0].Text != "hello" {
+		t.Fatalf("fake__echo = %+v, %v", result, err)
+	}
+
+	for len(notifications) > 0 {
+		<-notifications // the tools appearing
+	}
+
+	// The next call finds the session gone: the server is marked failed and its tools
+	// disappear from the gateway.
+	fake.down.Store(true)
+	if result, err := client.CallTool(ctx, "fake__echo", map[string]string{"text": "hello"}); err == nil && !result.IsError {
+		t.Errorf("call while down = %+v, want an error", result)
+	}
+	waitStatus(t, gw, "fake", mcpclient.StatusFailed)
+	if health := gw.Health(); health.Status != StatusDegraded {
+		t.Errorf("health while down = %+v", health)
+	}
+	if names := toolNames(t, client); slices.Contains(names, "fake__echo") {
+		t.Errorf("tools while down = %q, want fake__echo hidden", names)
+	}
+	waitListChanged(t, notifications)
+
+	// Once it answers again the gateway reconnects and exposes its tools again.
+	fake.down.Store(false)
+	if h := waitStatus(t, gw, "fake", mcpclient.StatusConnected); h.Failures == 0 {
+		t.Errorf("health after recovery = %+v, want the failure counted", h)
+	}
+	waitListChanged(t, notifications)
+	if names := toolNames(t, client); !slices.Contains(names, "fake__echo") {
+		t.Errorf("tools after recovery = %q, want fake__echo", names)
+	}
+	result, err = client.CallTool(ctx, "fake__echo", map[string]string{"text": "again"})
+	if err != nil || result.Content[0].Text != "again" {
+		t.Errorf("fake__echo after recovery = %+v, %v", result, err)
+	}
+	if health := gw.Health(); health.Status != StatusOK {
+		t.Errorf("health after recovery = %+v", health)
+	}
+}
+
+func waitListChanged(t *testing.T, notifications <-chan *mcp.Message) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-notifications:
+			if msg.Method == "notifications/tools/list_changed" {
+				return
+			}
+		case <-timeout:
+			t.Fatal("no notifications/tools/list_changed")
+		}
+	}
+}
@@ -0,0 +1,460 @@
+// Package mcpclient connects the assistant to external MCP servers. A Manager launches
+// (or dials) every configured server, keeps the connection alive across crashes and
+// re-exposes the discovered tools and prompts under "<server>__<name>" names, and
+// resources under "mcp://<server>/<uri>", so they cannot collide with the assistant's
+// own or with each other.
+package mcpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gonuxt-context-assistant/internal/config"
+	"gonuxt-context-assistant/internal/mcp"
+)
+
+// NamespaceSeparator joins a server name and one of its tool or prompt names.
+const NamespaceSeparator = "__"
+
+// ResourceScheme prefixes resource URIs with their server: "mcp://docs/file:///readme.md"
+// is file:///readme.md of the docs server.
+const ResourceScheme = "mcp://"
+
+// Connection states reported by Health.
+const (
+	StatusConnecting = "connecting" // first attempt still running
+	StatusConnected  = "connected"
+	StatusFailed     = "failed"  // last attempt failed or the connection dropped; retrying
+	StatusStopped    = "stopped" // Close was called
+)
+
+const (
+	clientName    = "gonuxt-context-assistant"
+	clientVersion = "0.1.0"
+
+	connectTimeout = 10 * time.Second
+	stableAfter    = time.Minute // a connection that lived this long resets the backoff
+)
+
+// Wait between reconnect attempts, doubling from minBackoff up to maxBackoff. Variables
+// so tests can shorten them.
+var (
+	minBackoff = 1 * time.Second
+	maxBackoff = 30 * time.Second
+)
+
+var (
+	// ErrUnknownTool is returned by CallTool for names no server provides.
+	ErrUnknownTool = errors.New("mcpclient: unknown tool")
+	// ErrUnavailable is returned by CallTool while the owning server is disconnected.
+	ErrUnavailable = errors.New("mcpclient: server unavailable")
+	// ErrUnknownResource is returned by ReadResource for URIs of no configured server.
+	ErrUnknownResource = errors.New("mcpclient: unknown resource")
+	// ErrUnknownPrompt is returned by GetPrompt for names no server provides.
+	ErrUnknownPrompt = errors.New("mcpclient: unknown prompt")
+
+	invalidNameChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+)
+
+// Manager owns the connections to all configured external MCP servers.
+type Manager struct {
+	// OnChange, when set before Start, is called after a server connects, disconnects or
+	// changes its tools, resources or prompts. It runs synchronously and must not block.
+	OnChange func()
+
+	servers []*remoteServer
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// ServerHealth is the connection state of one configured server.
+type ServerHealth struct {
+	Name      string             `json:"name"`
+	Transport string             `json:"transport"` // "stdio" or "http"
+	Status    string             `json:"status"`    // one of the Status constants
+	Since     time.Time          `json:"since"`     // when Status last changed
+	LastError string             `json:"lastError,omitempty"`
+	Failures  int                `json:"failures"` // failed attempts and dropped connections so far
+	Server    mcp.Implementation `json:"server,omitzero"`
+	Tools     int                `json:"tools"`
+	Resources int                `json:"resources"`
+	Prompts   int                `json:"prompts"`
+}
+
+// remoteServer is the live state of one configured server.
+type remoteServer struct {
+	name    string
+	cfg     config.MCPServer
+	changed func()
+
+	mu        sync.RWMutex
+	client    *mcp.Client // nil while disconnected
+	catalogue catalogue   // as announced by the server, without namespace
+	status    string
+	since     time.Time
+	lastError string
+	failures  int
+}
+
+// catalogue is everything a server offers.
+type catalogue struct {
+	tools     []mcp.Tool
+	resources []mcp.Resource
+	templates []mcp.ResourceTemplate
+	prompts   []mcp.Prompt
+}
+
+// NewManager prepares connections for the configured servers. Nothing is started until Start.
+func NewManager(servers map[string]config.MCPServer) *Manager {
+	m := &Manager{}
+	for name, cfg := range servers {
+		m.servers = append(m.servers, &remoteServer{
+			name:   invalidNameChars.ReplaceAllString(name, "_"),
+			cfg:    cfg,
+			status: StatusConnecting,
+			since:  time.Now(),
+		})
+	}
+	sort.Slice(m.servers, func(i, j int) bool { return m.servers[i].name < m.servers[j].name })
+	return m
+}
+
+// Start connects to every server in the background and keeps reconnecting whenever a
+// connection drops, until ctx is cancelled or Close is called.
+func (m *Manager) Start(ctx context.Context) {
+	ctx, m.cancel = context.WithCancel(ctx)
+	for _, rs := range m.servers {
+		rs.changed = m.changed
+		m.wg.Add(1)
+		go func(rs *remoteServer) {
+			defer m.wg.Done()
+			rs.supervise(ctx)
+		}(rs)
+	}
+}
+
+// Close disconnects from every server and stops the reconnect loops.
+func (m *Manager) Close() {
+	if m.cancel != nil {
+		m.cancel()
+	}
+	m.wg.Wait()
+}
+
+func (m *Manager) changed() {
+	if m.OnChange != nil {
+		m.OnChange()
+	}
+}
+
+// Tools returns the tools of every connected server, with namespaced names.
+func (m *Manager) Tools() []mcp.Tool {
+	var tools []mcp.Tool
+	for _, rs := range m.servers {
+		rs.mu.RLock()
+		for _, tool := range rs.catalogue.tools {
+			tool.Name = rs.name + NamespaceSeparator + tool.Name
+			tool.Description = fmt.Sprintf("[%s] %s", rs.name, tool.Description)
+			tools = append(tools, tool)
+		}
+		rs.mu.RUnlock()
+	}
+	return tools
+}
+
+// Resources returns the concrete resources of every connected server, with namespaced URIs.
+func (m *Manager) Resources() []mcp.Resource {
+	var resources []mcp.Resource
+	for _, rs := range m.servers {
+		rs.mu.RLock()
+		for _, resource := range rs.catalogue.resources {
+			resource.URI = rs.resourcePrefix() + resource.URI
+			resource.Name = rs.name + NamespaceSeparator + resource.Name
+			resource.Description = fmt.Sprintf("[%s] %s", rs.name, resource.Description)
+			resources = append(resources, resource)
+		}
+		rs.mu.RUnlock()
+	}
+	return resources
+}
+
+// ResourceTemplates returns the resource templates of every connected server, with
+// namespaced URI templates.
+func (m *Manager) ResourceTemplates() []mcp.ResourceTemplate {
+	var templates []mcp.ResourceTemplate
+	for _, rs := range m.servers {
+		rs.mu.RLock()
+		for _, template := range rs.catalogue.templates {
+			template.URITemplate = rs.resourcePrefix() + template.URITemplate
+			template.Name = rs.name + NamespaceSeparator + template.Name
+			template.Description = fmt.Sprintf("[%s] %s", rs.name, template.Description)
+			templates = append(templates, template)
+		}
+		rs.mu.RUnlock()
+	}
+	return templates
+}
+
+// Prompts returns the prompts of every connected server, with namespaced names.
+func (m *Manager) Prompts() []mcp.Prompt {
+	var prompts []mcp.Prompt
+	for _, rs := range m.servers {
+		rs.mu.RLock()
+		for _, prompt := range rs.catalogue.prompts {
+			prompt.Name = rs.name + NamespaceSeparator + prompt.Name
+			prompt.Description = fmt.Sprintf("[%s] %s", rs.name, prompt.Description)
+			prompts = append(prompts, prompt)
+		}
+		rs.mu.RUnlock()
+	}
+	return prompts
+}
+
+// Health returns the connection state of every configured server, sorted by name.
+func (m *Manager) Health() []ServerHealth {
+	health := make([]ServerHealth, 0, len(m.servers))
+	for _, rs := range m.servers {
+		rs.mu.RLock()
+		h := ServerHealth{
+			Name:      rs.name,
+			Transport: "stdio",
+			Status:    rs.status,
+			Since:     rs.since,
+			LastError: rs.lastError,
+			Failures:  rs.failures,
+			Tools:     len(rs.catalogue.tools),
+			Resources: len(rs.catalogue.resources) + len(rs.catalogue.templates),
+			Prompts:   len(rs.catalogue.prompts),
+		}
+		if rs.cfg.URL != "" {
+			h.Transport = "http"
+		}
+		if rs.client != nil {
+			h.Server = rs.client.ServerInfo()
+		}
+		rs.mu.RUnlock()
+		health = append(health, h)
+	}
+	return health
+}
+
+// CallTool invokes a namespaced tool on the server that owns it.
+func (m *Manager) CallTool(ctx context.Context, name string, args any) (*mcp.CallToolResult, error) {
+	rs, toolName, ok := m.lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTool, name)
+	}
+	client, err := rs.connected()
+	if err != nil {
+		return nil, err
+	}
+	return client.CallTool(ctx, toolName, args)
+}
+
+// ReadResource reads a namespaced resource URI from the server that owns it.
+// The contents come back with the namespaced URI, as the caller asked for it.
+func (m *Manager) ReadResource(ctx context.Context, uri string) (*mcp.ReadResourceResult, error) {
+	for _, rs := range m.servers {
+		original, ok := strings.CutPrefix(uri, rs.resourcePrefix())
+		if !ok {
+			continue
+		}
+		client, err := rs.connected()
+		if err != nil {
+			return nil, err
+		}
+		result, err := client.ReadResource(ctx, original)
+		if err != nil {
+			return nil, err
+		}
+		for i := range result.Contents {
+			result.Contents[i].URI = rs.resourcePrefix() + result.Contents[i].URI
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownResource, uri)
+}
+
+// GetPrompt renders a namespaced prompt on the server that owns it.
+func (m *Manager) GetPrompt(ctx context.Context, name string, args map[string]string) (*mcp.GetPromptResult, error) {
+	rs, promptName, ok := m.lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPrompt, name)
+	}
+	client, err := rs.connected()
+	if err != nil {
+		return nil, err
+	}
+	return client.GetPrompt(ctx, promptName, args)
+}
+
+// lookup splits a namespaced name into its server and the server's own name for it.
+func (m *Manager) lookup(name string) (*remoteServer, string, bool) {
+	for _, rs := range m.servers {
+		if local, ok := strings.CutPrefix(name, rs.name+NamespaceSeparator); ok {
+			return rs, local, true
+		}
+	}
+	return nil, "", false
+}
+
+// supervise keeps one server connected, backing off exponentially between attempts.
+func (rs *remoteServer) supervise(ctx context.Context) {
+	backoff := minBackoff
+	for {
+		client, err := rs.connect(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				rs.setClient(nil, catalogue{}, StatusStopped, nil)
+				return
+			}
+			log.Printf("MCP server %s: connect failed: %v", rs.name, err)
+			rs.setClient(nil, catalogue{}, StatusFailed, err)
+		} else {
+			connectedAt := time.Now()
+			select {
+			case <-ctx.Done():
+				client.Close()
+				rs.setClient(nil, catalogue{}, StatusStopped, nil)
+				return
+			case <-client.Done():
+				log.Printf("MCP server %s: connection lost, reconnecting", rs.name)
+				client.Close()
+				rs.setClient(nil, catalogue{}, StatusFailed, errors.New("connection lost"))
+			}
+			if time.Since(connectedAt) > stableAfter {
+				backoff = minBackoff
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			rs.setClient(nil, catalogue{}, StatusStopped, nil)
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// connect starts a client, performs the handshake and loads the server's catalogue.
+func (rs *remoteServer) connect(ctx context.Context) (*mcp.Client, error) {
+	client := mcp.NewClient(clientName, clientVersion, rs.transport())
+	client.OnNotification = func(msg *mcp.Message) {
+		switch msg.Method {
+		case "notifications/tools/list_changed", "notifications/resources/list_changed", "notifications/prompts/list_changed":
+			go rs.refresh(ctx, client)
+		}
+	}
+
+	connectCtx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+	if err := client.Connect(connectCtx); err != nil {
+		return nil, err
+	}
+	cat, err := loadCatalogue(connectCtx, client)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	rs.setClient(client, cat, StatusConnected, nil)
+	log.Printf("MCP server %s: connected to %s %s with %d tools", rs.name, client.ServerInfo().Name, client.ServerInfo().Version, len(cat.tools))
+	return client, nil
+}
+
+// loadCatalogue lists the tools and, if the server announced them, its resources and prompts.
+func loadCatalogue(ctx context.Context, client *mcp.Client) (catalogue, error) {
+	var (
+		cat  catalogue
+		err  error
+		caps = client.ServerCapabilities()
+	)
+	if cat.tools, err = client.ListTools(ctx); err != nil {
+		return catalogue{}, err
+	}
+	if caps.Resources != nil {
+		if cat.resources, err = client.ListResources(ctx); err != nil {
+			return catalogue{}, err
+		}
+		if cat.templates, err = client.ListResourceTemplates(ctx); err != nil {
+			return catalogue{}, err
+		}
+	}
+	if caps.Prompts != nil {
+		if cat.prompts, err = client.ListPrompts(ctx); err != nil {
+			return catalogue{}, err
+		}
+	}
+	return cat, nil
+}
+
+func (rs *remoteServer) refresh(ctx context.Context, client *mcp.Client) {
+	ctx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+	cat, err := loadCatalogue(ctx, client)
+	if err != nil {
+		log.Printf("MCP server %s: refreshing catalogue failed: %v", rs.name, err)
+		return
+	}
+	rs.mu.Lock()
+	current := rs.client == client // ignore answers from a connection we already replaced
+	if current {
+		rs.catalogue = cat
+	}
+	rs.mu.Unlock()
+	if current && rs.changed != nil {
+		rs.changed()
+	}
+}
+
+// setClient records a connection change. err explains a StatusFailed.
+func (rs *remoteServer) setClient(client *mcp.Client, cat catalogue, status string, err error) {
+	rs.mu.Lock()
+	rs.client = client
+	rs.catalogue = cat
+	if status != rs.status {
+		rs.since = time.Now()
+	}
+	rs.status = status
+	if err != nil {
+		rs.lastError = err.Error()
+		rs.failures++
+	}
+	rs.mu.Unlock()
+	if rs.changed != nil {
+		rs.changed()
+	}
+}
+
+// connected returns the live client, or ErrUnavailable while the server is down.
+func (rs *remoteServer) connected() (*mcp.Client, error) {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	if rs.client == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnavailable, rs.name)
+	}
+	return rs.client, nil
+}
+
+func (rs *remoteServer) resourcePrefix() string {
+	return ResourceScheme + rs.name + "/"
+}
+
+func (rs *remoteServer) transport() mcp.ClientTransport {
+	if rs.cfg.URL != "" {
+		return &mcp.HTTPClientTransport{URL: rs.cfg.URL, Headers: rs.cfg.Headers}
+	}
+	env := make([]string, 0, len(rs.cfg.Env))
+	for k, v := range rs.cfg.Env {
+		env = append(env, k+"="+v)
+	}
+	return &mcp.CommandTransport{Name: rs.name, Command: rs.cfg.Command, Args: rs.cfg.Args, Env: env}
+}
@@ -0,0 +1,146 @@
+package mcpclient
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"gonuxt-context-assistant/internal/config"
+	"gonuxt-context-assistant/internal/mcp"
+)
+
+// fakeServerEnv makes the test binary run as an MCP server on stdio instead of running
+// the tests, so the manager can launch it like any configured server.
+const fakeServerEnv = "MCPCLIENT_FAKE_SERVER"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakeServerEnv) == "1" {
+		serveFake()
+		return
+	}
+	minBackoff, maxBackoff = 20*time.Millisecond, 80*time.Millisecond
+	os.Exit(m.Run())
+}
+
+// serveFake offers "echo", and "crash", which makes the process exit in the middle of
+// the call.
+func serveFake() {
+	server := mcp.NewServer("fake", "1.0.0")
+	server.AddTool(mcp.Tool{Name: "echo", Description: "Echoes its text"}, func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args struct {
+			Text string `json:"text"`
+		}
+		if err := req.Bind(&args); err != nil {
+			return nil, err
+		}
+		return mcp.TextResult(args.Text), nil
+	})
+	server.AddTool(mcp.Tool{Name: "crash"}, func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		os.Exit(3)
+		return nil, nil
+	})
+	_ = server.ServeStdio(context.Background(), os.Stdin, os.Stdout)
+}
+
+func fakeServer(t *testing.T) config.MCPServer {
+	t.Helper()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config.MCPServer{Command: exe, Env: map[string]string{fakeServerEnv: "1"}}
+}
+
+// waitFor polls cond until it holds, failing the test after a few seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func status(m *Manager) ServerHealth { return m.Health()[0] }
+
+func TestManagerReconnectsAfterTheServerExits(t *testing.T) {
+	m := NewManager(map[string]config.MCPServer{"fake": fakeServer(t)})
+	m.Start(context.Background())
+	defer m.Close()
+	ctx := context.Background()
+
+	waitFor(t, "the first connection", func() bool { return status(m).Status == StatusConnected })
+	if h := status(m); h.Tools != 2 || h.Server.Name != "fake" || h.Transport != "stdio" {
+		t.Errorf("health = %+v", h)
+	}
+	result, err := m.CallTool(ctx, "fake__echo", map[string]string{"text": "hello"})
+	if err != nil || result.Content[0].Text != "hello" {
+		t.Fatalf("echo = %+v, %v", result, err)
+	}
+
+	// The call in flight when the process exits fails instead of waiting forever.
+	_, err = m.CallTool(ctx, "fake__crash", nil)
+	if !errors.Is(err, mcp.ErrClientClosed) {
+		t.Fatalf("crash: err = %v, want mcp.ErrClientClosed", err)
+	}
+
+	waitFor(t, "the reconnection", func() bool {
+		h := status(m)
+		return h.Status == StatusConnected && h.Failures == 1
+	})
+	if h := status(m); h.LastError != "connection lost" {
+		t.Errorf("last error = %q, want connection lost", h.LastError)
+	}
+	if result, err := m.CallTool(ctx, "fake__echo", map[string]string{"text": "again"}); err != nil || result.Content[0].Text != "again" {
+		t.Errorf("echo after reconnecting = %+v, %v", result, err)
+	}
+
+	m.Close()
+	if h := status(m); h.Status != StatusStopped || h.Tools != 0 {
+		t.Errorf("after Close: health = %+v", h)
+	}
+	if _, err := m.CallTool(ctx, "fake__echo", nil); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("after Close: err = %v, want ErrUnavailable", err)
+	}
+}
+
+func TestManagerBacksOffBetweenFailedAttempts(t *testing.T) {
+	m := NewManager(map[string]config.MCPServer{"broken": {Command: "/nonexistent/mcp-server"}})
+	var (
+		mu       sync.Mutex
+		attempts []time.Time
+	)
+	m.OnChange = func() {
+		mu.Lock()
+		attempts = append(attempts, time.Now())
+		mu.Unlock()
+	}
+	m.Start(context.Background())
+	waitFor(t, "five attempts", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(attempts) >= 5
+	})
+	m.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	// The waits double from minBackoff and stop growing at maxBackoff.
+	want := []time.Duration{minBackoff, 2 * minBackoff, maxBackoff, maxBackoff}
+	for i, least := range want {
+		if gap := attempts[i+1].Sub(attempts[i]); gap < least {
+			t.Errorf("wait %d = %v, want at least %v", i+1, gap, least)
+		}
+	}
+	if h := status(m); h.Status != StatusStopped || h.Failures < 5 || h.LastError == "" {
+		t.Errorf("health = %+v", h)
+	}
+	if _, err := m.CallTool(context.Background(), "nobody__echo", nil); !errors.Is(err, ErrUnknownTool) {
+		t.Errorf("err = %v, want ErrUnknownTool", err)
+	}
+}
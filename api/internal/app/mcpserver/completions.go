@@ -0,0 +1,25 @@
+package mcpserver
+
+import (
+	"context"
+
+	"gonuxt-context-assistant/internal/mcp"
+	"gonuxt-context-assistant/internal/tools"
+)
+
+// registerCompletions suggests city and country names wherever clients type them:
+// resource template variables and prompt arguments. The spec has no completions for tool
+// arguments; clients filling in get_weather or get_capital can complete the name of
+// weather://city/{name} or country://{name}/capital instead.
+func registerCompletions(server *mcp.Server) {
+	cities := func(ctx context.Context, req *mcp.CompleteRequest) ([]string, error) {
+		return tools.SuggestCities(req.Params.Argument.Value), nil
+	}
+	countries := func(ctx context.Context, req *mcp.CompleteRequest) ([]string, error) {
+		return tools.SuggestCountries(req.Params.Argument.Value), nil
+	}
+
+	server.AddCompletion(mcp.CompletionRef{Type: mcp.RefResource, URI: weatherCityTemplate}, "name", cities)
+	server.AddCompletion(mcp.CompletionRef{Type: mcp.RefResource, URI: capitalTemplate}, "name", countries)
+	server.AddCompletion(mcp.CompletionRef{Type: mcp.RefPrompt, Name: "travel_briefing"}, "country", countries)
+}
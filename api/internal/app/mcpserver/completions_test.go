@@ -0,0 +1,58 @@
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+
+	"gonuxt-context-assistant/internal/mcp"
+)
+
+func TestCompletions(t *testing.T) {
+	client, _ := connect(t)
+
+	tests := []struct {
+		name     string
+		ref      mcp.CompletionRef
+		argument string
+		value    string
+		want     string
+	}{
+		{"city of the weather template", mcp.CompletionRef{Type: mcp.RefResource, URI: "weather://city/{name}"}, "name", "lis", "Lisbon"},
+		{"country of the capital template", mcp.CompletionRef{Type: mcp.RefResource, URI: "country://{name}/capital"}, "name", "portu", "Portugal"},
+		{"country of travel_briefing", mcp.CompletionRef{Type: mcp.RefPrompt, Name: "travel_briefing"}, "country", "united", "United Kingdom"},
+	}
+	for _, tt := range tests {
+		var params mcp.CompleteParams
+		params.Ref = tt.ref
+		params.Argument.Name = tt.argument
+		params.Argument.Value = tt.value
+		raw, err := client.Call(context.Background(), "completion/complete", params)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var result mcp.CompleteResult
+		if err := json.Unmarshal(raw, &result); err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Contains(result.Completion.Values, tt.want) {
+			t.Errorf("%s: values = %v, want %s among them", tt.name, result.Completion.Values, tt.want)
+		}
+	}
+}
+
+func TestCompletionsRejectToolReferences(t *testing.T) {
+	client, _ := connect(t)
+
+	_, err := client.Call(context.Background(), "completion/complete", map[string]any{
+		"ref":      map[string]string{"type": "ref/tool", "name": "get_weather"},
+		"argument": map[string]string{"name": "city", "value": "lis"},
+	})
+	var rpcErr *mcp.Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != mcp.CodeInvalidParams {
+		t.Errorf("err = %v, want invalid params", err)
+	}
+}
@@ -0,0 +1,39 @@
+// Package mcpserver exposes the assistant over MCP. It owns the mapping between MCP
+// tools and the functions in internal/tools / assistant.Service, so the transports in
+// cmd/ only have to pick how clients connect.
+package mcpserver
+
+import (
+	"context"
+
+	"gonuxt-context-assistant/internal/app/assistant"
+	"gonuxt-context-assistant/internal/mcp"
+)
+
+const (
+	serverName    = "gonuxt-context-assistant"
+	serverVersion = "0.1.0"
+)
+
+// New builds an MCP server that exposes the assistant's tools, reference data and prompts.
+func New(svc *assistant.Service) *mcp.Server {
+	server := mcp.NewServer(serverName, serverVersion)
+	server.Instructions = "Tools for the current date and time, simulated city weather and country capitals."
+
+	registerTools(server, svc)
+	registerResources(server)
+	registerPrompts(server)
+	registerCompletions(server)
+	return server
+}
+
+// LogSink forwards the assistant's log records to the server's MCP clients as
+// notifications/message. Each client only gets the levels it asked for with logging/setLevel;
+// records logged during a tool call reach its caller on the stream of that call.
+//
+//	svc.LogSink = mcpserver.LogSink(server)
+func LogSink(server *mcp.Server) assistant.LogSink {
+	return func(ctx context.Context, level assistant.LogLevel, logger, message string) {
+		server.LogContext(ctx, mcp.LoggingLevel(level), logger, message)
+	}
+}
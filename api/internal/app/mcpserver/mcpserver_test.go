@@ -0,0 +1,105 @@
+package mcpserver
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"gonuxt-context-assistant/internal/app/assistant"
+	"gonuxt-context-assistant/internal/mcp"
+)
+
+// pipeTransport connects an mcp.Client to a server in the same process through the stdio
+// transport, so tests exercise the same code as cmd/mcp without starting a subprocess.
+type pipeTransport struct {
+	server *mcp.Server
+
+	mu        sync.Mutex
+	enc       *json.Encoder
+	toServer  *io.PipeWriter
+	cancel    context.CancelFunc
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (t *pipeTransport) Start(ctx context.Context, handle func(*mcp.Message)) error {
+	ctx, t.cancel = context.WithCancel(context.Background())
+	serverIn, toServer := io.Pipe()
+	fromServer, serverOut := io.Pipe()
+	t.toServer = toServer
+	t.enc = json.NewEncoder(toServer)
+	t.done = make(chan struct{})
+
+	go func() {
+		t.server.ServeStdio(ctx, serverIn, serverOut)
+		serverOut.Close()
+	}()
+	go func() {
+		scanner := bufio.NewScanner(fromServer)
+		for scanner.Scan() {
+			var msg mcp.Message
+			if json.Unmarshal(scanner.Bytes(), &msg) == nil {
+				handle(&msg)
+			}
+		}
+	}()
+	return nil
+}
+
+func (t *pipeTransport) Send(ctx context.Context, msg *mcp.Message) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.enc.Encode(msg)
+}
+
+func (t *pipeTransport) Done() <-chan struct{} { return t.done }
+
+func (t *pipeTransport) Close() error {
+	t.closeOnce.Do(func() {
+		t.cancel()
+		t.toServer.Close()
+		close(t.done)
+	})
+	return nil
+}
+
+// connect starts the assistant's MCP server and returns a client connected to it, and
+// the notifications the client receives.
+func connect(t *testing.T) (*mcp.Client, <-chan *mcp.Message) {
+	t.Helper()
+	notifications := make(chan *mcp.Message, 16)
+	server := New(assistant.NewService())
+	t.Cleanup(server.Close) // stops watching the data
+	client := mcp.NewClient("test", "0.0.0", &pipeTransport{server: server})
+	client.OnNotification = func(msg *mcp.Message) { notifications <- msg }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Connect(ctx); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client, notifications
+}
+
+// waitNotification returns the next notification of the given method, failing the test
+// if none arrives in time.
+func waitNotification(t *testing.T, notifications <-chan *mcp.Message, method string) *mcp.Message {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case msg := <-notifications:
+			if msg.Method == method {
+				return msg
+			}
+		case <-timeout:
+			t.Fatalf("no %s notification", method)
+			return nil
+		}
+	}
+}
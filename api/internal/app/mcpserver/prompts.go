@@ -0,0 +1,97 @@
+package mcpserver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gonuxt-context-assistant/internal/app/assistant"
+	"gonuxt-context-assistant/internal/mcp"
+	"gonuxt-context-assistant/internal/tools"
+)
+
+// promptDefinition pairs a published prompt with the function that renders it.
+type promptDefinition struct {
+	prompt mcp.Prompt
+	render mcp.PromptHandler
+}
+
+// promptCatalogue is the list of prompts published over MCP.
+// To add a prompt, append a definition here; registerPrompts takes care of the rest.
+var promptCatalogue = []promptDefinition{
+	{
+		prompt: mcp.Prompt{
+			Name:        "compare_weather",
+			Title:       "Compare weather between cities",
+			Description: "Asks the model to compare the current weather of the cities mentioned in a question.",
+			Arguments: []mcp.PromptArgument{
+				{Name: "query", Description: "Free-form question naming the cities, e.g. 'Lisbon or Paris this weekend?'", Required: true},
+			},
+		},
+		render: renderCompareWeather,
+	},
+	{
+		prompt: mcp.Prompt{
+			Name:        "travel_briefing",
+			Title:       "Travel briefing for a country",
+			Description: "Prepares a short travel briefing for a country, starting from its capital and current weather.",
+			Arguments: []mcp.PromptArgument{
+				{Name: "country", Description: "Country to brief on, e.g. Portugal", Required: true},
+			},
+		},
+		render: renderTravelBriefing,
+	},
+}
+
+// registerPrompts publishes every prompt in the catalogue.
+func registerPrompts(server *mcp.Server) {
+	for _, def := range promptCatalogue {
+		server.AddPrompt(def.prompt, def.render)
+	}
+}
+
+func renderCompareWeather(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	query := req.Params.Arguments["query"]
+	cities := assistant.ExtractCitiesFromQuery(query)
+	if len(cities) < 2 {
+		return nil, mcp.NewError(mcp.CodeInvalidParams, "the query must mention at least two known cities, found %d", len(cities))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Compare the current weather in %s.\n\n", strings.Join(cities, ", "))
+	b.WriteString("Current reports:\n")
+	for _, city := range cities {
+		if report, found := tools.WeatherReport(city); found {
+			fmt.Fprintf(&b, "- %s: %s\n", city, report)
+		} else {
+			fmt.Fprintf(&b, "- %s: no report available\n", city)
+		}
+	}
+	fmt.Fprintf(&b, "\nThe original question was: %q. Answer it, pointing out which city has the nicer weather and why.", query)
+
+	return &mcp.GetPromptResult{
+		Description: "Weather comparison for " + strings.Join(cities, ", "),
+		Messages:    []mcp.PromptMessage{mcp.UserMessage(b.String())},
+	}, nil
+}
+
+func renderTravelBriefing(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	country := strings.TrimSpace(req.Params.Arguments["country"])
+	capital, found := tools.CapitalOf(country)
+	if !found {
+		return nil, mcp.NewError(mcp.CodeInvalidParams, "%s", tools.GetCapital(country))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Write a short travel briefing for %s.\n\n", country)
+	fmt.Fprintf(&b, "The capital is %s.", capital)
+	if report, found := tools.WeatherReport(capital); found {
+		fmt.Fprintf(&b, " The weather there is currently %s", report)
+	}
+	b.WriteString("\n\nCover what to pack for the current weather, and keep it under 150 words.")
+
+	return &mcp.GetPromptResult{
+		Description: "Travel briefing for " + country,
+		Messages:    []mcp.PromptMessage{mcp.UserMessage(b.String())},
+	}, nil
+}
@@ -0,0 +1,148 @@
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"gonuxt-context-assistant/internal/mcp"
+	"gonuxt-context-assistant/internal/tools"
+)
+
+// URIs of the reference datasets in internal/tools.
+const (
+	weatherIndexURI     = "weather://cities"
+	weatherCityTemplate = "weather://city/{name}"
+	capitalsIndexURI    = "country://capitals"
+	capitalTemplate     = "country://{name}/capital"
+
+	jsonMimeType = "application/json"
+)
+
+// registerResources publishes the weather and capital datasets and forwards dataset
+// changes to subscribed clients until the server is closed. City and country names are
+// case-insensitive, so subscriptions are too: weather://city/Lisbon is notified like
+// weather://city/lisbon.
+func registerResources(server *mcp.Server) {
+	server.CanonicalURI = strings.ToLower
+
+	server.AddResource(mcp.Resource{
+		URI:         weatherIndexURI,
+		Name:        "weather-cities",
+		Title:       "Weather for every known city",
+		Description: "All simulated weather reports, one entry per city.",
+		MimeType:    jsonMimeType,
+	}, readWeatherIndex)
+
+	server.AddResourceTemplate(mcp.ResourceTemplate{
+		URITemplate: weatherCityTemplate,
+		Name:        "weather-city",
+		Title:       "Weather for a city",
+		Description: "The simulated weather report for a single city, e.g. weather://city/lisbon.",
+		MimeType:    jsonMimeType,
+	}, readCityWeather, listCityWeather)
+
+	server.AddResource(mcp.Resource{
+		URI:         capitalsIndexURI,
+		Name:        "country-capitals",
+		Title:       "Country capitals",
+		Description: "Every country with a known capital.",
+		MimeType:    jsonMimeType,
+	}, readCapitalsIndex)
+
+	server.AddResourceTemplate(mcp.ResourceTemplate{
+		URITemplate: capitalTemplate,
+		Name:        "country-capital",
+		Title:       "Capital of a country",
+		Description: "The capital city of a single country, e.g. country://portugal/capital.",
+		MimeType:    jsonMimeType,
+	}, readCapital, listCapitals)
+
+	unwatch := tools.Watch(func(change tools.DataChange) {
+		switch change.Dataset {
+		case tools.DatasetWeather:
+			server.NotifyResourceUpdated(weatherIndexURI)
+			server.NotifyResourceUpdated(cityWeatherURI(change.Key))
+		case tools.DatasetCapitals:
+			server.NotifyResourceUpdated(capitalsIndexURI)
+			server.NotifyResourceUpdated(capitalURI(change.Key))
+		}
+		if change.Added {
+			server.NotifyResourceListChanged()
+		}
+	})
+	server.OnClose(unwatch)
+}
+
+func cityWeatherURI(city string) string {
+	return mcp.ExpandURI(weatherCityTemplate, map[string]string{"name": strings.ToLower(city)})
+}
+
+func capitalURI(country string) string {
+	return mcp.ExpandURI(capitalTemplate, map[string]string{"name": strings.ToLower(country)})
+}
+
+func readWeatherIndex(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	var reports []tools.Weather
+	for _, city := range tools.WeatherCities() {
+		if weather, found := tools.WeatherFor(city); found {
+			reports = append(reports, weather)
+		}
+	}
+	return jsonResource(req.URI, reports)
+}
+
+func readCityWeather(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	weather, found := tools.WeatherFor(req.Vars["name"])
+	if !found {
+		return nil, mcp.ResourceNotFound(req.URI)
+	}
+	return jsonResource(req.URI, weather)
+}
+
+func listCityWeather() []mcp.Resource {
+	var resources []mcp.Resource
+	for _, city := range tools.WeatherCities() {
+		resources = append(resources, mcp.Resource{
+			URI:      cityWeatherURI(city),
+			Name:     "weather-" + strings.ReplaceAll(strings.ToLower(city), " ", "-"),
+			Title:    "Weather in " + city,
+			MimeType: jsonMimeType,
+		})
+	}
+	return resources
+}
+
+func readCapitalsIndex(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	return jsonResource(req.URI, tools.Capitals())
+}
+
+func readCapital(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	for _, entry := range tools.Capitals() {
+		if strings.EqualFold(entry.Country, req.Vars["name"]) {
+			return jsonResource(req.URI, entry)
+		}
+	}
+	return nil, mcp.ResourceNotFound(req.URI)
+}
+
+func listCapitals() []mcp.Resource {
+	var resources []mcp.Resource
+	for _, entry := range tools.Capitals() {
+		resources = append(resources, mcp.Resource{
+			URI:      capitalURI(entry.Country),
+			Name:     "capital-" + strings.ReplaceAll(strings.ToLower(entry.Country), " ", "-"),
+			Title:    "Capital of " + entry.Country,
+			MimeType: jsonMimeType,
+		})
+	}
+	return resources
+}
+
+func jsonResource(uri string, v any) (*mcp.ReadResourceResult, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return mcp.TextResource(uri, jsonMimeType, string(data)), nil
+}
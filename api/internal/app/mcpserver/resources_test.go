@@ -0,0 +1,115 @@
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"gonuxt-context-assistant/internal/tools"
+)
+
+func TestSubscribedClientIsNotifiedOfWeatherUpdates(t *testing.T) {
+	client, notifications := connect(t)
+	ctx := context.Background()
+
+	original, _ := tools.WeatherFor("Lisbon")
+	t.Cleanup(func() { tools.SetWeather(original) })
+
+	if _, err := client.Call(ctx, "resources/subscribe", map[string]string{"uri": "weather://city/lisbon"}); err != nil {
+		t.Fatalf("resources/subscribe: %v", err)
+	}
+
+	tools.SetWeather(tools.Weather{City: "Lisbon", Condition: "rainy", Temperature: 17})
+
+	msg := waitNotification(t, notifications, "notifications/resources/updated")
+	var params struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		t.Fatal(err)
+	}
+	if params.URI != "weather://city/lisbon" {
+		t.Errorf("updated uri = %q, want weather://city/lisbon", params.URI)
+	}
+
+	// The resource already reflects the change.
+	result, err := client.ReadResource(ctx, "weather://city/lisbon")
+	if err != nil {
+		t.Fatalf("resources/read: %v", err)
+	}
+	var weather tools.Weather
+	if err := json.Unmarshal([]byte(result.Contents[0].Text), &weather); err != nil {
+		t.Fatal(err)
+	}
+	if weather.Condition != "rainy" || weather.Report != "rainy with 17°C." {
+		t.Errorf("weather after update = %+v", weather)
+	}
+}
+
+func TestUnsubscribedResourcesAreNotNotified(t *testing.T) {
+	client, notifications := connect(t)
+	ctx := context.Background()
+
+	t.Cleanup(func() { tools.SetCapital("Portugal", "Lisbon") })
+
+	if _, err := client.Call(ctx, "resources/subscribe", map[string]string{"uri": "country://portugal/capital"}); err != nil {
+		t.Fatalf("resources/subscribe: %v", err)
+	}
+	if _, err := client.Call(ctx, "resources/unsubscribe", map[string]string{"uri": "country://portugal/capital"}); err != nil {
+		t.Fatalf("resources/unsubscribe: %v", err)
+	}
+	if _, err := client.Call(ctx, "resources/subscribe", map[string]string{"uri": "country://capitals"}); err != nil {
+		t.Fatalf("resources/subscribe: %v", err)
+	}
+
+	tools.SetCapital("Portugal", "Porto")
+
+	msg := waitNotification(t, notifications, "notifications/resources/updated")
+	if uri := string(msg.Params); uri != `{"uri":"country://capitals"}` {
+		t.Errorf("notified %s, want only country://capitals", uri)
+	}
+	// Notifications are written before the answer to a later request.
+	if err := client.Ping(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-notifications:
+		if msg.Method == "notifications/resources/updated" {
+			t.Errorf("unexpected notification %s", msg.Params)
+		}
+	default:
+	}
+}
+
+func TestSubscriptionsIgnoreCase(t *testing.T) {
+	client, notifications := connect(t)
+	ctx := context.Background()
+
+	original, _ := tools.WeatherFor("Paris")
+	t.Cleanup(func() { tools.SetWeather(original) })
+
+	if _, err := client.Call(ctx, "resources/subscribe", map[string]string{"uri": "weather://city/Paris"}); err != nil {
+		t.Fatalf("resources/subscribe: %v", err)
+	}
+	tools.SetWeather(tools.Weather{City: "paris", Condition: "foggy", Temperature: 9})
+
+	msg := waitNotification(t, notifications, "notifications/resources/updated")
+	if uri := string(msg.Params); uri != `{"uri":"weather://city/Paris"}` {
+		t.Errorf("notified %s, want the URI as subscribed", uri)
+	}
+
+	if _, err := client.Call(ctx, "resources/unsubscribe", map[string]string{"uri": "weather://city/PARIS"}); err != nil {
+		t.Fatalf("resources/unsubscribe: %v", err)
+	}
+	tools.SetWeather(tools.Weather{City: "Paris", Condition: "sunny", Temperature: 21})
+	if err := client.Ping(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-notifications:
+		if msg.Method == "notifications/resources/updated" {
+			t.Errorf("notified %s after unsubscribing", msg.Params)
+		}
+	default:
+	}
+}
@@ -0,0 +1,320 @@
+package mcpserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"gonuxt-context-assistant/internal/app/assistant"
+	"gonuxt-context-assistant/internal/mcp"
+	"gonuxt-context-assistant/internal/tools"
+)
+
+// Output schemas shared by several tools. Every tool returns its data as
+// structuredContent matching these, next to the usual sentence in content.
+var (
+	weatherSchema = &mcp.Schema{
+		Type: "object",
+		Properties: map[string]*mcp.Schema{
+			"city":        {Type: "string"},
+			"condition":   {Type: "string", Description: "e.g. sunny, cloudy"},
+			"temperature": {Type: "number"},
+			"unit":        {Type: "string", Enum: []string{tools.UnitCelsius}},
+			"report":      {Type: "string", Description: "Human readable summary"},
+		},
+		Required: []string{"city", "condition", "temperature", "unit"},
+	}
+
+	multiCityWeatherSchema = &mcp.Schema{
+		Type: "object",
+		Properties: map[string]*mcp.Schema{
+			"reports":  {Type: "array", Items: weatherSchema},
+			"notFound": {Type: "array", Items: &mcp.Schema{Type: "string"}, Description: "Cities without weather data"},
+		},
+		Required: []string{"reports", "notFound"},
+	}
+
+	capitalSchema = &mcp.Schema{
+		Type: "object",
+		Properties: map[string]*mcp.Schema{
+			"country": {Type: "string"},
+			"capital": {Type: "string"},
+		},
+		Required: []string{"country", "capital"},
+	}
+
+	dateTimeSchema = &mcp.Schema{
+		Type: "object",
+		Properties: map[string]*mcp.Schema{
+			"datetime": {Type: "string", Description: "RFC 3339 timestamp"},
+			"timezone": {Type: "string"},
+			"unix":     {Type: "integer", Description: "Seconds since the Unix epoch"},
+		},
+		Required: []string{"datetime", "timezone", "unix"},
+	}
+
+	answerSchema = &mcp.Schema{
+		Type: "object",
+		Properties: map[string]*mcp.Schema{
+			"answer": {Type: "string"},
+		},
+		Required: []string{"answer"},
+	}
+)
+
+// loggerTools is the logger name of the records written when a tool is called.
+const loggerTools = "tools"
+
+// registerTools publishes the functions of internal/tools and assistant.Service as MCP tools.
+func registerTools(server *mcp.Server, svc *assistant.Service) {
+	// Every call goes through logged, so clients following the logs see each invocation.
+	addTool := func(tool mcp.Tool, handler mcp.ToolHandler) {
+		server.AddTool(tool, logged(svc, tool.Name, handler))
+	}
+
+	addTool(mcp.Tool{
+		Name:        "get_weather",
+		Title:       "Get weather",
+		Description: "Returns the current (simulated) weather for a single city.",
+		InputSchema: &mcp.Schema{
+			Type: "object",
+			Properties: map[string]*mcp.Schema{
+				"city": {Type: "string", Description: "City name, e.g. Lisbon"},
+			},
+			Required: []string{"city"},
+		},
+		OutputSchema: weatherSchema,
+		Annotations:  &mcp.ToolAnnotations{ReadOnlyHint: mcp.Bool(true), IdempotentHint: mcp.Bool(true), OpenWorldHint: mcp.Bool(false)},
+	}, getWeather(svc))
+
+	addTool(mcp.Tool{
+		Name:        "get_multi_city_weather",
+		Title:       "Get weather for several cities",
+		Description: "Fetches the weather for several cities concurrently.",
+		InputSchema: &mcp.Schema{
+			Type: "object",
+			Properties: map[string]*mcp.Schema{
+				"cities": {Type: "array", Items: &mcp.Schema{Type: "string"}, MinItems: 1, Description: "City names"},
+			},
+			Required: []string{"cities"},
+		},
+		OutputSchema: multiCityWeatherSchema,
+		Annotations:  &mcp.ToolAnnotations{ReadOnlyHint: mcp.Bool(true), IdempotentHint: mcp.Bool(true), OpenWorldHint: mcp.Bool(false)},
+	}, getMultiCityWeather(svc))
+
+	addTool(mcp.Tool{
+		Name:        "get_capital",
+		Title:       "Get capital",
+		Description: "Returns the capital city of a country.",
+		InputSchema: &mcp.Schema{
+			Type: "object",
+			Properties: map[string]*mcp.Schema{
+				"country": {Type: "string", Description: "Country name, e.g. Portugal"},
+			},
+			Required: []string{"country"},
+		},
+		OutputSchema: capitalSchema,
+		Annotations:  &mcp.ToolAnnotations{ReadOnlyHint: mcp.Bool(true), IdempotentHint: mcp.Bool(true), OpenWorldHint: mcp.Bool(false)},
+	}, getCapital)
+
+	addTool(mcp.Tool{
+		Name:        "ask_assistant",
+		Title:       "Ask the assistant",
+		Description: "Answers a free-form question about the time or a city's weather. If the city is missing the user is asked for it.",
+		InputSchema: &mcp.Schema{
+			Type: "object",
+			Properties: map[string]*mcp.Schema{
+				"query": {Type: "string", Description: "The question, e.g. 'What's the weather like?'"},
+			},
+			Required: []string{"query"},
+		},
+		OutputSchema: answerSchema,
+		// Not idempotent (answers depend on the clock) and may reach external MCP servers.
+		Annotations: &mcp.ToolAnnotations{ReadOnlyHint: mcp.Bool(true), IdempotentHint: mcp.Bool(false), OpenWorldHint: mcp.Bool(true)},
+	}, askAssistant(svc))
+
+	addTool(mcp.Tool{
+		Name:         "get_current_datetime",
+		Title:        "Get current date and time",
+		Description:  "Returns the server's current date and time.",
+		InputSchema:  &mcp.Schema{Type: "object"},
+		OutputSchema: dateTimeSchema,
+		Annotations:  &mcp.ToolAnnotations{ReadOnlyHint: mcp.Bool(true), IdempotentHint: mcp.Bool(false), OpenWorldHint: mcp.Bool(false)},
+	}, getCurrentDateTime)
+}
+
+func getWeather(svc *assistant.Service) mcp.ToolHandler {
+	return func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args struct {
+			City string `json:"city"`
+		}
+		if err := req.Bind(&args); err != nil {
+			return nil, err
+		}
+		city := strings.TrimSpace(args.City)
+		if city == "" {
+			return nil, mcp.NewError(mcp.CodeInvalidParams, "city is required")
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, 3*time.Second) // Same budget as ProcessQuery
+		defer cancel()
+
+		report, err := tools.GetData(ctx, city, tools.GetWeather)
+		if err != nil {
+			svc.Logf(ctx, assistant.LevelNotice, loggerTools, "No weather for %s: %v", city, err)
+			return mcp.ErrorResult(fmt.Sprintf("No weather information found for %s.", city)), nil
+		}
+		weather, _ := tools.WeatherFor(city)
+		return mcp.StructuredResult(report, weather), nil
+	}
+}
+
+func getMultiCityWeather(svc *assistant.Service) mcp.ToolHandler {
+	return func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args struct {
+			Cities []string `json:"cities"`
+		}
+		if err := req.Bind(&args); err != nil {
+			return nil, err
+		}
+		if len(args.Cities) == 0 {
+			return nil, mcp.NewError(mcp.CodeInvalidParams, "cities must contain at least one city")
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second) // Same budget as the async HTTP handler
+		defer cancel()
+
+		// Report each city as it completes. If the client sends notifications/cancelled the
+		// session cancels ctx, which stops the fan-out and the in-flight tools.GetData calls.
+		reports, status := svc.GetMultiCityWeatherWithProgress(ctx, args.Cities, func(completed, total int, city, report string) {
+			if err := req.ReportProgress(ctx, float64(completed), float64(total), report); err != nil {
+				svc.Logf(ctx, assistant.LevelWarning, loggerTools, "Could not report progress for %s: %v", city, err)
+			}
+		})
+		if status != http.StatusOK {
+			return mcp.ErrorResult(fmt.Sprintf("Weather request stopped before every city finished: %v", ctx.Err())), nil
+		}
+
+		// Map iteration order is random; sort so clients get stable output.
+		cities := make([]string, 0, len(reports))
+		for city := range reports {
+			cities = append(cities, city)
+		}
+		sort.Strings(cities)
+
+		structured := struct {
+			Reports  []tools.Weather `json:"reports"`
+			NotFound []string        `json:"notFound"`
+		}{Reports: []tools.Weather{}, NotFound: []string{}}
+		content := make([]mcp.Content, 0, len(cities))
+		for _, city := range cities {
+			content = append(content, mcp.TextContent(reports[city]))
+			if weather, found := tools.WeatherFor(city); found {
+				structured.Reports = append(structured.Reports, weather)
+			} else {
+				structured.NotFound = append(structured.NotFound, city)
+			}
+		}
+		return &mcp.CallToolResult{Content: content, StructuredContent: structured}, nil
+	}
+}
+
+func getCapital(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var args struct {
+		Country string `json:"country"`
+	}
+	if err := req.Bind(&args); err != nil {
+		return nil, err
+	}
+	country := strings.TrimSpace(args.Country)
+	if country == "" {
+		return nil, mcp.NewError(mcp.CodeInvalidParams, "country is required")
+	}
+	capital, found := tools.CapitalOf(country)
+	if !found {
+		return mcp.ErrorResult(tools.GetCapital(country)), nil
+	}
+	return mcp.StructuredResult(capital, tools.Capital{Country: country, Capital: capital}), nil
+}
+
+func getCurrentDateTime(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	now := time.Now()
+	zone, _ := now.Zone()
+	return mcp.StructuredResult(tools.FormatDateTime(now), map[string]any{
+		"datetime": now.Format(time.RFC3339),
+		"timezone": zone,
+		"unix":     now.Unix(),
+	}), nil
+}
+
+func askAssistant(svc *assistant.Service) mcp.ToolHandler {
+	return func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args struct {
+			Query string `json:"query"`
+		}
+		if err := req.Bind(&args); err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(args.Query) == "" {
+			return nil, mcp.NewError(mcp.CodeInvalidParams, "query is required")
+		}
+
+		// Let the assistant ask the user for anything the query leaves out.
+		ctx = assistant.WithAskUser(ctx, elicit(req.Session))
+		answer, _ := svc.ProcessQuery(ctx, args.Query)
+		return mcp.StructuredResult(answer, map[string]string{"answer": answer}), nil
+	}
+}
+
+// logged wraps a tool handler so each call, and how it ended, goes to the service log.
+func logged(svc *assistant.Service, name string, handler mcp.ToolHandler) mcp.ToolHandler {
+	return func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := string(req.Params.Arguments)
+		if args == "" {
+			args = "{}"
+		}
+		svc.Logf(ctx, assistant.LevelInfo, loggerTools, "Tool %s called by %s with %s", name, req.Session.ClientInfo().Name, args)
+		start := time.Now()
+		result, err := handler(ctx, req)
+		switch {
+		case err != nil:
+			svc.Logf(ctx, assistant.LevelError, loggerTools, "Tool %s failed: %v", name, err)
+		case result.IsError:
+			svc.Logf(ctx, assistant.LevelWarning, loggerTools, "Tool %s returned an error after %v", name, time.Since(start).Round(time.Millisecond))
+		default:
+			svc.Logf(ctx, assistant.LevelDebug, loggerTools, "Tool %s finished in %v", name, time.Since(start).Round(time.Millisecond))
+		}
+		return result, err
+	}
+}
+
+// elicitTimeout bounds how long a tool call waits for the user to answer a question.
+const elicitTimeout = 2 * time.Minute
+
+// elicit implements assistant.AskUser with MCP elicitation/create, offering the choices
+// as an enum so the client can render a picker.
+func elicit(ss *mcp.Session) assistant.AskUser {
+	return func(ctx context.Context, question string, choices []string) (string, error) {
+		ctx, cancel := context.WithTimeout(ctx, elicitTimeout)
+		defer cancel()
+
+		result, err := ss.Elicit(ctx, question, &mcp.Schema{
+			Type: "object",
+			Properties: map[string]*mcp.Schema{
+				"city": {Type: "string", Description: "City", Enum: choices},
+			},
+			Required: []string{"city"},
+		})
+		if err != nil {
+			return "", err
+		}
+		city, _ := result.Content["city"].(string)
+		if result.Action != mcp.ElicitAccept || city == "" {
+			return "", assistant.ErrNoAnswer
+		}
+		return city, nil
+	}
+}
@@ -0,0 +1,133 @@
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+
+	"gonuxt-context-assistant/internal/mcp"
+)
+
+func TestStructuredContentMatchesOutputSchema(t *testing.T) {
+	client, _ := connect(t)
+	ctx := context.Background()
+
+	listed, err := client.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("tools/list: %v", err)
+	}
+	schemas := make(map[string]*mcp.Schema)
+	for _, tool := range listed {
+		schemas[tool.Name] = decodeSchema(t, tool.OutputSchema)
+	}
+
+	tests := []struct {
+		tool string
+		args any
+	}{
+		{"get_weather", map[string]string{"city": "Lisbon"}},
+		{"get_multi_city_weather", map[string][]string{"cities": {"Lisbon", "Atlantis"}}},
+		{"get_capital", map[string]string{"country": "Portugal"}},
+		{"ask_assistant", map[string]string{"query": "What time is it?"}},
+		{"get_current_datetime", map[string]string{}},
+	}
+	for _, tt := range tests {
+		schema := schemas[tt.tool]
+		if schema == nil {
+			t.Errorf("%s: no outputSchema in tools/list", tt.tool)
+			continue
+		}
+		result, err := client.CallTool(ctx, tt.tool, tt.args)
+		if err != nil || result.IsError {
+			t.Errorf("%s: result = %+v, err = %v", tt.tool, result, err)
+			continue
+		}
+		if result.StructuredContent == nil {
+			t.Errorf("%s: no structuredContent", tt.tool)
+			continue
+		}
+		for _, problem := range validate(schema, result.StructuredContent, "structuredContent") {
+			t.Errorf("%s: %s", tt.tool, problem)
+		}
+	}
+}
+
+// decodeSchema turns a schema received over the wire back into an *mcp.Schema.
+func decodeSchema(t *testing.T, v any) *mcp.Schema {
+	t.Helper()
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema mcp.Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+	return &schema
+}
+
+// validate checks a decoded JSON value against the subset of JSON Schema that mcp.Schema
+// supports. Properties the schema does not declare count as mismatches too, so the
+// schema cannot silently fall behind the data.
+func validate(schema *mcp.Schema, v any, path string) []string {
+	var problems []string
+	switch schema.Type {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s = %v, want an object", path, v)}
+		}
+		for _, name := range schema.Required {
+			if _, ok := obj[name]; !ok {
+				problems = append(problems, fmt.Sprintf("%s.%s is required", path, name))
+			}
+		}
+		for name, value := range obj {
+			prop, ok := schema.Properties[name]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s.%s is not in the schema", path, name))
+				continue
+			}
+			problems = append(problems, validate(prop, value, path+"."+name)...)
+		}
+	case "array":
+		items, ok := v.([]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s = %v, want an array", path, v)}
+		}
+		if len(items) < schema.MinItems {
+			problems = append(problems, fmt.Sprintf("%s has %d items, want at least %d", path, len(items), schema.MinItems))
+		}
+		for i, item := range items {
+			if schema.Items != nil {
+				problems = append(problems, validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			return []string{fmt.Sprintf("%s = %v, want a string", path, v)}
+		}
+		if len(schema.Enum) > 0 && !slices.Contains(schema.Enum, s) {
+			problems = append(problems, fmt.Sprintf("%s = %q, want one of %q", path, s, schema.Enum))
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			return []string{fmt.Sprintf("%s = %v, want a number", path, v)}
+		}
+	case "integer":
+		if n, ok := v.(float64); !ok || n != float64(int64(n)) {
+			return []string{fmt.Sprintf("%s = %v, want an integer", path, v)}
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return []string{fmt.Sprintf("%s = %v, want a boolean", path, v)}
+		}
+	}
+	return problems
+}
@@ -0,0 +1,212 @@
+// Package auth makes the MCP endpoint an OAuth 2.1 protected resource, as the MCP
+// authorization spec asks for:
+//
+//   - it publishes Protected Resource Metadata (RFC 9728) naming the authorization server,
+//   - it only lets requests through with a valid bearer token (a JWT signed with a key from
+//     the authorization server's JWKS, issued for this resource as audience, not expired),
+//   - it maps scopes to tools, hiding and refusing the tools a token has no scopes for.
+//
+// Failures answer 401/403 with a WWW-Authenticate header pointing at the metadata, which
+// is how MCP clients find out where to get a token.
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"gonuxt-context-assistant/internal/config"
+	"gonuxt-context-assistant/internal/mcp"
+)
+
+// metadataPrefix is the well-known path of Protected Resource Metadata (RFC 9728).
+const metadataPrefix = "/.well-known/oauth-protected-resource"
+
+type tokenKey struct{}
+
+// WithToken returns a copy of ctx carrying the caller's token.
+func WithToken(ctx context.Context, token *Token) context.Context {
+	return context.WithValue(ctx, tokenKey{}, token)
+}
+
+// TokenFrom returns the token Protect validated for this request, or nil.
+func TokenFrom(ctx context.Context) *Token {
+	token, _ := ctx.Value(tokenKey{}).(*Token)
+	return token
+}
+
+// ResourceServer protects one resource (the MCP endpoint) with tokens from one
+// authorization server.
+type ResourceServer struct {
+	cfg      config.Auth
+	verifier *Verifier
+}
+
+// ProtectedResourceMetadata is the document served at MetadataPath (RFC 9728).
+type ProtectedResourceMetadata struct {
+	Resource               string   `json:"resource"`
+	AuthorizationServers   []string `json:"authorization_servers"`
+	ScopesSupported        []string `json:"scopes_supported,omitempty"`
+	BearerMethodsSupported []string `json:"bearer_methods_supported"`
+	ResourceName           string   `json:"resource_name,omitempty"`
+}
+
+// New creates a resource server from the auth section of the configuration.
+func New(cfg config.Auth) *ResourceServer {
+	audience := cfg.Audience
+	if audience == "" {
+		audience = cfg.Resource
+	}
+	return &ResourceServer{
+		cfg:      cfg,
+		verifier: NewVerifier(cfg.Issuer, audience, NewKeySet(cfg.Issuer, cfg.JWKSURL)),
+	}
+}
+
+// MetadataPath is where the metadata document is served: the well-known prefix followed
+// by the resource's path, e.g. /.well-known/oauth-protected-resource/mcp.
+func (rs *ResourceServer) MetadataPath() string {
+	path := "/"
+	if u, err := url.Parse(rs.cfg.Resource); err == nil {
+		path = u.Path
+	}
+	return strings.TrimSuffix(metadataPrefix+path, "/")
+}
+
+// MetadataURL is the absolute URL of the metadata document, sent in WWW-Authenticate.
+func (rs *ResourceServer) MetadataURL() string {
+	u, err := url.Parse(rs.cfg.Resource)
+	if err != nil {
+		return rs.cfg.Resource
+	}
+	return u.Scheme + "://" + u.Host + rs.MetadataPath()
+}
+
+// ServeMetadata answers GET requests for the Protected Resource Metadata.
+func (rs *ResourceServer) ServeMetadata(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	metadata := ProtectedResourceMetadata{
+		Resource:               rs.cfg.Resource,
+		AuthorizationServers:   []string{rs.cfg.Issuer},
+		ScopesSupported:        rs.scopesSupported(),
+		BearerMethodsSupported: []string{"header"},
+		ResourceName:           "gonuxt-context-assistant MCP server",
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(metadata); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+// Protect lets a request through to next only with a valid bearer token that has the
+// configured RequiredScopes. The token is available to next via TokenFrom.
+func (rs *ResourceServer) Protect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, ok := bearerToken(r)
+		if !ok {
+			// No error code when no credentials were sent at all (RFC 6750, section 3.1).
+			rs.challenge(w, http.StatusUnauthorized, "", "", nil)
+			return
+		}
+		token, err := rs.verifier.Verify(r.Context(), raw)
+		if err != nil {
+			log.Printf("Rejected token for %s %s: %v", r.Method, r.URL.Path, err)
+			rs.challenge(w, http.StatusUnauthorized, "invalid_token", err.Error(), nil)
+			return
+		}
+		if !token.HasScopes(rs.cfg.RequiredScopes...) {
+			rs.challenge(w, http.StatusForbidden, "insufficient_scope", "the token lacks required scopes", rs.cfg.RequiredScopes)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithToken(r.Context(), token)))
+	})
+}
+
+// AuthorizeTool implements mcp.Server.AuthorizeTool: it refuses tools whose scopes
+// (config ToolScopes) the caller's token was not granted.
+func (rs *ResourceServer) AuthorizeTool(ctx context.Context, tool mcp.Tool) error {
+	scopes := rs.ToolScopes(tool.Name)
+	if len(scopes) == 0 {
+		return nil
+	}
+	token := TokenFrom(ctx)
+	if token == nil || !token.HasScopes(scopes...) {
+		return &mcp.Error{
+			Code:    mcp.CodeInvalidRequest,
+			Message: fmt.Sprintf("insufficient_scope: %s requires %s", tool.Name, strings.Join(scopes, " ")),
+			Data:    map[string]any{"tool": tool.Name, "requiredScopes": scopes},
+		}
+	}
+	return nil
+}
+
+// ToolScopes returns the scopes needed for a tool: the entry of ToolScopes with the tool's
+// name, or else the one with the longest matching "prefix*" key.
+func (rs *ResourceServer) ToolScopes(name string) []string {
+	if scopes, ok := rs.cfg.ToolScopes[name]; ok {
+		return scopes
+	}
+	var (
+		best    []string
+		bestLen = -1
+	)
+	for pattern, scopes := range rs.cfg.ToolScopes {
+		prefix, ok := strings.CutSuffix(pattern, "*")
+		if ok && strings.HasPrefix(name, prefix) && len(prefix) > bestLen {
+			best, bestLen = scopes, len(prefix)
+		}
+	}
+	return best
+}
+
+func (rs *ResourceServer) scopesSupported() []string {
+	seen := make(map[string]bool)
+	for _, scope := range rs.cfg.RequiredScopes {
+		seen[scope] = true
+	}
+	for _, scopes := range rs.cfg.ToolScopes {
+		for _, scope := range scopes {
+			seen[scope] = true
+		}
+	}
+	scopes := make([]string, 0, len(seen))
+	for scope := range seen {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+	return scopes
+}
+
+// challenge answers with a WWW-Authenticate: Bearer header (RFC 6750 and RFC 9728).
+func (rs *ResourceServer) challenge(w http.ResponseWriter, status int, code, description string, scopes []string) {
+	params := []string{fmt.Sprintf("resource_metadata=%q", rs.MetadataURL())}
+	if code != "" {
+		params = append(params, fmt.Sprintf("error=%q", code))
+	}
+	if description != "" {
+		params = append(params, fmt.Sprintf("error_description=%q", strings.ReplaceAll(description, `"`, "'")))
+	}
+	if len(scopes) > 0 {
+		params = append(params, fmt.Sprintf("scope=%q", strings.Join(scopes, " ")))
+	}
+	w.Header().Set("WWW-Authenticate", "Bearer "+strings.Join(params, ", "))
+	http.Error(w, http.StatusText(status), status)
+}
+
+// bearerToken reads the token from the Authorization header. Tokens in the query string
+// are not accepted, as the MCP spec requires.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
@@ -0,0 +1,324 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gonuxt-context-assistant/internal/config"
+	"gonuxt-context-assistant/internal/mcp"
+)
+
+const testResource = "https://api.example.com/mcp"
+
+// testIssuer is a stand-in authorization server: it publishes its metadata and a JWKS,
+// and signs tokens with the key it currently publishes.
+type testIssuer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	kid     string
+	key     *rsa.PrivateKey
+	fetches int // requests for the JWKS
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	is := &testIssuer{}
+	is.rotate(t, "key-1")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/oauth-authorization-server", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": is.URL, "jwks_uri": is.URL + "/jwks"})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		is.mu.Lock()
+		defer is.mu.Unlock()
+		is.fetches++
+		json.NewEncoder(w).Encode(JWKS{Keys: []JWK{NewRSAJWK(is.kid, &is.key.PublicKey)}})
+	})
+	is.Server = httptest.NewServer(mux)
+	t.Cleanup(is.Close)
+	return is
+}
+
+// rotate replaces the signing key, as an authorization server does from time to time.
+func (is *testIssuer) rotate(t *testing.T, kid string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	is.mu.Lock()
+	is.kid, is.key = kid, key
+	is.mu.Unlock()
+}
+
+// claims returns valid claims for testResource with the given scopes.
+func (is *testIssuer) claims(scope string) Claims {
+	now := time.Now()
+	return Claims{
+		Issuer:   is.URL,
+		Subject:  "user-1",
+		Audience: Audience{testResource},
+		IssuedAt: now.Unix(),
+		Expiry:   now.Add(time.Hour).Unix(),
+		Scope:    scope,
+		ClientID: "test-client",
+	}
+}
+
+func (is *testIssuer) sign(t *testing.T, claims Claims) string {
+	t.Helper()
+	is.mu.Lock()
+	defer is.mu.Unlock()
+	token, err := SignRS256(claims, is.key, is.kid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func (is *testIssuer) jwksFetches() int {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+	return is.fetches
+}
+
+// unsignedToken builds a token with the given header, as an attacker would.
+func unsignedToken(t *testing.T, header map[string]string, claims Claims, sign func(input string) []byte) string {
+	t.Helper()
+	h, _ := json.Marshal(header)
+	c, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	return input + "." + base64.RawURLEncoding.EncodeToString(sign(input))
+}
+
+func TestVerify(t *testing.T) {
+	is := newTestIssuer(t)
+	verifier := NewVerifier(is.URL, testResource, NewKeySet(is.URL, ""))
+
+	wrongAudience := is.claims("mcp")
+	wrongAudience.Audience = Audience{"https://other.example.com/mcp"}
+	wrongIssuer := is.claims("mcp")
+	wrongIssuer.Issuer = "https://evil.example.com"
+	expired := is.claims("mcp")
+	expired.Expiry = time.Now().Add(-time.Hour).Unix()
+	noExpiry := is.claims("mcp")
+	noExpiry.Expiry = 0
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{"valid", is.sign(t, is.claims("mcp weather:read")), ""},
+		{"wrong audience", is.sign(t, wrongAudience), "not meant for"},
+		{"wrong issuer", is.sign(t, wrongIssuer), "issued by"},
+		{"expired", is.sign(t, expired), "expired"},
+		{"no expiry", is.sign(t, noExpiry), "no expiry"},
+		{"alg none", unsignedToken(t, map[string]string{"alg": "none", "kid": "key-1"}, is.claims("mcp"), func(string) []byte { return nil }), "unsupported signing algorithm"},
+		{"HS256 with the public key as secret", unsignedToken(t, map[string]string{"alg": "HS256", "kid": "key-1"}, is.claims("mcp"), func(input string) []byte {
+			mac := hmac.New(sha256.New, is.key.PublicKey.N.Bytes())
+			mac.Write([]byte(input))
+			return mac.Sum(nil)
+		}), "unsupported signing algorithm"},
+		{"tampered claims", func() string {
+			parts := strings.Split(is.sign(t, is.claims("mcp")), ".")
+			c, _ := json.Marshal(is.claims("mcp admin"))
+			parts[1] = base64.RawURLEncoding.EncodeToString(c)
+			return strings.Join(parts, ".")
+		}(), "invalid token signature"},
+		{"not a JWT", "abc.def", "not a JWT"},
+	}
+	for _, tt := range tests {
+		token, err := verifier.Verify(context.Background(), tt.token)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		case tt.wantErr == "" && !token.HasScopes("mcp", "weather:read"):
+			t.Errorf("%s: scopes = %v", tt.name, token.Scopes)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestUnknownKeyIDRefetchesTheKeySet(t *testing.T) {
+	is := newTestIssuer(t)
+	keys := NewKeySet(is.URL, "")
+	verifier := NewVerifier(is.URL, testResource, keys)
+	ctx := context.Background()
+
+	if _, err := verifier.Verify(ctx, is.sign(t, is.claims("mcp"))); err != nil {
+		t.Fatalf("first key: %v", err)
+	}
+	if _, err := verifier.Verify(ctx, is.sign(t, is.claims("mcp"))); err != nil || is.jwksFetches() != 1 {
+		t.Fatalf("cached key: err %v, %d fetches, want 1", err, is.jwksFetches())
+	}
+
+	is.rotate(t, "key-2")
+	rotated := is.sign(t, is.claims("mcp"))
+
+	// Right after a fetch an unknown key id is refused without asking the issuer again.
+	if _, err := verifier.Verify(ctx, rotated); err == nil || is.jwksFetches() != 1 {
+		t.Fatalf("rate limit: err %v, %d fetches, want an error and 1 fetch", err, is.jwksFetches())
+	}
+
+	keys.mu.Lock()
+	keys.fetchedAt = keys.fetchedAt.Add(-2 * minRefreshInterval)
+	keys.mu.Unlock()
+	if _, err := verifier.Verify(ctx, rotated); err != nil {
+		t.Fatalf("rotated key: %v", err)
+	}
+	if is.jwksFetches() != 2 {
+		t.Errorf("%d fetches, want 2", is.jwksFetches())
+	}
+}
+
+func newTestResourceServer(is *testIssuer) *ResourceServer {
+	return New(config.Auth{
+		Issuer:         is.URL,
+		Resource:       testResource,
+		RequiredScopes: []string{"mcp"},
+		ToolScopes: map[string][]string{
+			"get_weather": {"weather:read"},
+			"tickets__*":  {"tickets"},
+		},
+	})
+}
+
+func TestProtectChallenges(t *testing.T) {
+	is := newTestIssuer(t)
+	rs := newTestResourceServer(is)
+	protected := rs.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if TokenFrom(r.Context()) == nil {
+			t.Error("no token in the request context")
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	metadata := `resource_metadata="https://api.example.com/.well-known/oauth-protected-resource/mcp"`
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+		challenge     []string // parts of WWW-Authenticate
+		notChallenge  string
+	}{
+		{"no token", "", http.StatusUnauthorized, []string{metadata}, "error="},
+		{"token in another scheme", "Basic dXNlcjpwYXNz", http.StatusUnauthorized, []string{metadata}, "error="},
+		{"invalid token", "Bearer " + is.sign(t, Claims{Issuer: is.URL}), http.StatusUnauthorized, []string{metadata, `error="invalid_token"`}, ""},
+		{"missing required scope", "Bearer " + is.sign(t, is.claims("weather:read")), http.StatusForbidden, []string{metadata, `error="insufficient_scope"`, `scope="mcp"`}, ""},
+		{"valid", "Bearer " + is.sign(t, is.claims("mcp")), http.StatusNoContent, nil, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+		if tt.authorization != "" {
+			req.Header.Set("Authorization", tt.authorization)
+		}
+		rec := httptest.NewRecorder()
+		protected.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.status)
+		}
+		challenge := rec.Header().Get("WWW-Authenticate")
+		for _, part := range tt.challenge {
+			if !strings.Contains(challenge, part) {
+				t.Errorf("%s: WWW-Authenticate %q lacks %s", tt.name, challenge, part)
+			}
+		}
+		if tt.notChallenge != "" && strings.Contains(challenge, tt.notChallenge) {
+			t.Errorf("%s: WWW-Authenticate %q should not have %s", tt.name, challenge, tt.notChallenge)
+		}
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	is := newTestIssuer(t)
+	rs := newTestResourceServer(is)
+	if path := rs.MetadataPath(); path != "/.well-known/oauth-protected-resource/mcp" {
+		t.Errorf("MetadataPath = %q", path)
+	}
+
+	rec := httptest.NewRecorder()
+	rs.ServeMetadata(rec, httptest.NewRequest(http.MethodGet, rs.MetadataPath(), nil))
+	var metadata ProtectedResourceMetadata
+	if err := json.NewDecoder(rec.Body).Decode(&metadata); err != nil {
+		t.Fatal(err)
+	}
+	if metadata.Resource != testResource || !slices.Equal(metadata.AuthorizationServers, []string{is.URL}) {
+		t.Errorf("metadata = %+v", metadata)
+	}
+	if !slices.Equal(metadata.ScopesSupported, []string{"mcp", "tickets", "weather:read"}) {
+		t.Errorf("scopes_supported = %v", metadata.ScopesSupported)
+	}
+}
+
+func TestToolsAreFilteredByScopes(t *testing.T) {
+	is := newTestIssuer(t)
+	rs := newTestResourceServer(is)
+
+	server := mcp.NewServer("test", "0.0.0")
+	for _, name := range []string{"get_weather", "get_capital", "tickets__open"} {
+		server.AddTool(mcp.Tool{Name: name}, func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.TextResult("ok"), nil
+		})
+	}
+	server.AuthorizeTool = rs.AuthorizeTool
+	ts := httptest.NewServer(rs.Protect(mcp.NewHTTPHandler(server)))
+	defer ts.Close()
+
+	tests := []struct {
+		scope   string
+		visible []string
+		refused string
+	}{
+		{"mcp", []string{"get_capital"}, "get_weather"},
+		{"mcp weather:read", []string{"get_weather", "get_capital"}, "tickets__open"},
+		{"mcp weather:read tickets", []string{"get_weather", "get_capital", "tickets__open"}, ""},
+	}
+	for _, tt := range tests {
+		ctx := context.Background()
+		client := mcp.NewClient("test", "0.0.0", &mcp.HTTPClientTransport{
+			URL:     ts.URL,
+			Headers: map[string]string{"Authorization": "Bearer " + is.sign(t, is.claims(tt.scope))},
+		})
+		if err := client.Connect(ctx); err != nil {
+			t.Fatalf("%s: connect: %v", tt.scope, err)
+		}
+
+		list, err := client.ListTools(ctx)
+		if err != nil {
+			t.Fatalf("%s: tools/list: %v", tt.scope, err)
+		}
+		var names []string
+		for _, tool := range list {
+			names = append(names, tool.Name)
+		}
+		if !slices.Equal(names, tt.visible) {
+			t.Errorf("%s: tools = %v, want %v", tt.scope, names, tt.visible)
+		}
+
+		if tt.refused != "" {
+			_, err := client.CallTool(ctx, tt.refused, nil)
+			var rpcErr *mcp.Error
+			if !errors.As(err, &rpcErr) || !strings.Contains(rpcErr.Message, "insufficient_scope") {
+				t.Errorf("%s: calling %s: err = %v, want insufficient_scope", tt.scope, tt.refused, err)
+			}
+		}
+		client.Close()
+	}
+}
@@ -0,0 +1,236 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// keysMaxAge is how long fetched keys are trusted before they are fetched again.
+	keysMaxAge = time.Hour
+	// minRefreshInterval stops tokens with made-up key ids from hammering the issuer.
+	minRefreshInterval = time.Minute
+	fetchTimeout       = 10 * time.Second
+)
+
+// JWK is one key of a JSON Web Key Set (RFC 7517). Only public RSA and P-256 keys are used.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"` // RSA modulus
+	E   string `json:"e,omitempty"` // RSA exponent
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"` // EC coordinates
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set as served on an issuer's jwks_uri.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// KeySet fetches and caches the signing keys of an authorization server.
+// A token signed with an unknown key id triggers a refetch, so key rotation just works.
+type KeySet struct {
+	issuer  string
+	jwksURL string // empty until discovered from the issuer's metadata
+	client  *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey // by kid
+	fetchedAt time.Time
+}
+
+// NewKeySet creates a key set for issuer. jwksURL may be empty to discover it.
+func NewKeySet(issuer, jwksURL string) *KeySet {
+	return &KeySet{
+		issuer:  strings.TrimSuffix(issuer, "/"),
+		jwksURL: jwksURL,
+		client:  &http.Client{Timeout: fetchTimeout},
+	}
+}
+
+// Key returns the public key with the given id. An empty kid is accepted when the set
+// holds exactly one key.
+func (ks *KeySet) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	stale := time.Since(ks.fetchedAt) > keysMaxAge
+	if key, ok := ks.lookup(kid); ok && !stale {
+		return key, nil
+	}
+	if !stale && time.Since(ks.fetchedAt) < minRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if err := ks.refresh(ctx); err != nil {
+		if key, ok := ks.lookup(kid); ok {
+			return key, nil // keep using the old keys while the issuer is unreachable
+		}
+		return nil, fmt.Errorf("fetch signing keys: %w", err)
+	}
+	if key, ok := ks.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (ks *KeySet) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(ks.keys) == 1 {
+		for _, key := range ks.keys {
+			return key, true
+		}
+	}
+	key, ok := ks.keys[kid]
+	return key, ok
+}
+
+// refresh downloads the key set. The caller holds ks.mu.
+func (ks *KeySet) refresh(ctx context.Context) error {
+	ks.fetchedAt = time.Now() // also on failure, so a broken issuer is not retried per request
+	if ks.jwksURL == "" {
+		jwksURL, err := ks.discover(ctx)
+		if err != nil {
+			return err
+		}
+		ks.jwksURL = jwksURL
+	}
+
+	var set JWKS
+	if err := ks.getJSON(ctx, ks.jwksURL, &set); err != nil {
+		return err
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			continue // skip key types we cannot use instead of failing the whole set
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		return errors.New("no usable keys in " + ks.jwksURL)
+	}
+	ks.keys = keys
+	return nil
+}
+
+// discover reads jwks_uri from the issuer's OAuth (RFC 8414) or OpenID Connect metadata.
+func (ks *KeySet) discover(ctx context.Context) (string, error) {
+	var lastErr error
+	for _, path := range []string{"/.well-known/oauth-authorization-server", "/.well-known/openid-configuration"} {
+		var metadata struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := ks.getJSON(ctx, ks.issuer+path, &metadata); err != nil {
+			lastErr = err
+			continue
+		}
+		if metadata.JWKSURI == "" {
+			lastErr = fmt.Errorf("%s%s has no jwks_uri", ks.issuer, path)
+			continue
+		}
+		return metadata.JWKSURI, nil
+	}
+	return "", lastErr
+}
+
+func (ks *KeySet) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := ks.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decode %s: %w", url, err)
+	}
+	return nil
+}
+
+// PublicKey converts the JWK into an *rsa.PublicKey or *ecdsa.PublicKey.
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("RSA exponent too large")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if x.BitLen() > 256 || y.BitLen() > 256 {
+			return nil, errors.New("EC coordinates too large")
+		}
+		// crypto/ecdh rejects points that are not on the curve.
+		point := append([]byte{4}, append(x.FillBytes(make([]byte, 32)), y.FillBytes(make([]byte, 32))...)...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// NewRSAJWK describes an RSA public key as a JWK, e.g. for a JWKS endpoint.
+func NewRSAJWK(kid string, key *rsa.PublicKey) JWK {
+	return JWK{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid base64url integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
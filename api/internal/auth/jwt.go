@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+// leeway absorbs clock differences between us and the authorization server.
+const leeway = 30 * time.Second
+
+// Token is a validated access token.
+type Token struct {
+	Subject  string
+	ClientID string
+	Scopes   []string
+	Expiry   time.Time
+}
+
+// HasScopes reports whether the token was granted every one of scopes.
+func (t *Token) HasScopes(scopes ...string) bool {
+	for _, scope := range scopes {
+		if !slices.Contains(t.Scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// Claims are the JWT claims we read (RFC 7519 and RFC 9068, the JWT access token profile).
+type Claims struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub,omitempty"`
+	Audience  Audience `json:"aud"`
+	Expiry    int64    `json:"exp"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	Scope     string   `json:"scope,omitempty"` // space separated
+	ClientID  string   `json:"client_id,omitempty"`
+}
+
+// Audience is the "aud" claim, which may be a single string or an array.
+type Audience []string
+
+// UnmarshalJSON accepts both forms of "aud".
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return errors.New("aud must be a string or an array of strings")
+	}
+	*a = many
+	return nil
+}
+
+// Verifier validates JWT access tokens issued by one authorization server for one audience.
+type Verifier struct {
+	issuer   string
+	audience string
+	keys     *KeySet
+	now      func() time.Time
+}
+
+// NewVerifier creates a verifier that trusts keys from keys and requires tokens to be
+// issued by issuer for audience.
+func NewVerifier(issuer, audience string, keys *KeySet) *Verifier {
+	return &Verifier{issuer: issuer, audience: audience, keys: keys, now: time.Now}
+}
+
+// Verify checks the signature, issuer, audience and lifetime of a JWT access token.
+func (v *Verifier) Verify(ctx context.Context, raw string) (*Token, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("token is not a JWT")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("token header: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("token signature is not base64url")
+	}
+	key, err := v.keys.Key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("token claims: %w", err)
+	}
+	now := v.now()
+	switch {
+	case claims.Issuer != v.issuer:
+		return nil, fmt.Errorf("token issued by %q, expected %q", claims.Issuer, v.issuer)
+	case !slices.Contains(claims.Audience, v.audience):
+		return nil, fmt.Errorf("token is not meant for %s", v.audience)
+	case claims.Expiry == 0:
+		return nil, errors.New("token has no expiry")
+	case now.After(time.Unix(claims.Expiry, 0).Add(leeway)):
+		return nil, errors.New("token expired")
+	case claims.NotBefore != 0 && now.Add(leeway).Before(time.Unix(claims.NotBefore, 0)):
+		return nil, errors.New("token not valid yet")
+	}
+
+	return &Token{
+		Subject:  claims.Subject,
+		ClientID: claims.ClientID,
+		Scopes:   strings.Fields(claims.Scope),
+		Expiry:   time.Unix(claims.Expiry, 0),
+	}, nil
+}
+
+// verifySignature checks an RS256 or ES256 signature. Every other algorithm, "none"
+// included, is refused.
+func verifySignature(alg string, key crypto.PublicKey, signingInput string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signingInput))
+	switch alg {
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("RS256 token signed with a non-RSA key")
+		}
+		if err := rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], signature); err != nil {
+			return errors.New("invalid token signature")
+		}
+		return nil
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return errors.New("malformed ES256 signature")
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(ecKey, digest[:], r, s) {
+			return errors.New("invalid token signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported signing algorithm %q", alg)
+}
+
+// SignRS256 creates a JWT signed with key. It is what an authorization server does; the
+// API only uses it in the local stand-in server (cmd/authserver).
+func SignRS256(claims Claims, key *rsa.PrivateKey, kid string) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "at+jwt", "kid": kid})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return errors.New("not base64url")
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,369 @@
+// Package config loads the application configuration.
+//
+// Settings come from an optional JSON file (ASSISTANT_CONFIG, default "config.json" in
+// the working directory). A missing default file is not an error: every setting has a
+// sensible zero value so the API still starts with no configuration at all.
+//
+// A few settings that differ between environments can be overridden with environment
+// variables, e.g. LLM_PROVIDER=ollama for a local model during development.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+)
+
+const (
+	// EnvConfigFile names the environment variable pointing at the config file.
+	EnvConfigFile = "ASSISTANT_CONFIG"
+	// DefaultConfigFile is used when EnvConfigFile is not set.
+	DefaultConfigFile = "config.json"
+)
+
+// Environment variables overriding the llm section of the config file.
+const (
+	EnvLLMProvider = "LLM_PROVIDER"
+	EnvLLMModel    = "LLM_MODEL"
+	EnvLLMBaseURL  = "LLM_BASE_URL"
+)
+
+// EnvAdminToken holds the bearer token of the admin endpoints (/admin/...). Without it
+// they are disabled. Like API keys it is never read from the config file.
+const EnvAdminToken = "ASSISTANT_ADMIN_TOKEN"
+
+// AdminToken returns the token of the admin endpoints, or "" when they are disabled.
+func AdminToken() string {
+	return os.Getenv(EnvAdminToken)
+}
+
+// Config is the root of the configuration file.
+type Config struct {
+	// MCPServers are external MCP servers whose tools the assistant may call, keyed by
+	// the name used to namespace their tools. The format matches the one used by
+	// desktop MCP clients, so existing entries can be copied over.
+	MCPServers map[string]MCPServer `json:"mcpServers"`
+
+	// Gateway lists downstream MCP servers whose tools, resources and prompts are
+	// re-exposed, namespaced, on the API's own /mcp endpoint.
+	Gateway Gateway `json:"gateway"`
+
+	// Auth protects /mcp with OAuth 2.1 bearer tokens. /mcp is open while Issuer is empty.
+	Auth Auth `json:"auth"`
+
+	// LLM is the language model that picks the assistant's tools. Without a provider the
+	// assistant routes queries by keyword.
+	LLM LLM `json:"llm"`
+
+	// Prompts says where to find the assistant's prompt templates and which versions to use.
+	Prompts Prompts `json:"prompts"`
+
+	// RAG is the document search that grounds answers in local files.
+	RAG RAG `json:"rag"`
+}
+
+// Prompts configures the prompt registry (see internal/prompts). Without it the built-in
+// prompts are used, each in its newest version.
+type Prompts struct {
+	// Dir holds more prompt versions, one <name>/<version>.tmpl file each, e.g.
+	// "prompts/greeting/v2.tmpl". Relative paths start at the working directory.
+	Dir string `json:"dir,omitempty"`
+	// Versions picks the version of some prompts by name, e.g. {"system": "v1"}; the
+	// others use their newest version.
+	Versions map[string]string `json:"versions,omitempty"`
+}
+
+// Gateway configures the MCP gateway (see internal/app/gateway).
+type Gateway struct {
+	// MCPServers uses the same format as Config.MCPServers.
+	MCPServers map[string]MCPServer `json:"mcpServers"`
+}
+
+// MCPServer describes how to reach one external MCP server: either a Command to launch
+// (stdio transport) or a URL (Streamable HTTP transport).
+type MCPServer struct {
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Load reads the configuration file named by ASSISTANT_CONFIG, or config.json.
+func Load() (*Config, error) {
+	path, explicit := os.LookupEnv(EnvConfigFile)
+	if !explicit {
+		path = DefaultConfigFile
+	}
+
+	cfg, err := LoadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		cfg, err = &Config{}, nil // running without a config file is fine
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.LLM.applyEnv() {
+		if err := cfg.LLM.validate(); err != nil {
+			return nil, fmt.Errorf("invalid environment: %w", err)
+		}
+	}
+	return cfg, nil
+}
+
+// LoadFile reads and validates a configuration file.
+func LoadFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// Auth configures the MCP endpoint as an OAuth 2.1 protected resource (see internal/auth).
+type Auth struct {
+	// Issuer is the authorization server, e.g. "https://auth.example.com". Tokens must
+	// carry it as "iss".
+	Issuer string `json:"issuer"`
+	// JWKSURL is where the issuer publishes its signing keys. When empty it is read from
+	// the issuer's /.well-known/oauth-authorization-server (or openid-configuration).
+	JWKSURL string `json:"jwksUrl,omitempty"`
+	// Resource is the canonical URL of the MCP endpoint, e.g. "https://api.example.com/mcp".
+	// It is announced in the protected resource metadata and tokens must name it in "aud".
+	Resource string `json:"resource"`
+	// Audience overrides the "aud" value tokens must carry, for authorization servers that
+	// cannot put the resource URL there. Defaults to Resource.
+	Audience string `json:"audience,omitempty"`
+	// RequiredScopes are needed for any request to /mcp.
+	RequiredScopes []string `json:"requiredScopes,omitempty"`
+	// ToolScopes lists the scopes needed to see and call a tool. Keys are tool names or
+	// prefixes ending in "*", e.g. "tickets__*"; the longest match wins. Tools without
+	// an entry only need RequiredScopes.
+	ToolScopes map[string][]string `json:"toolScopes,omitempty"`
+}
+
+// LLM providers known to internal/llm.
+const (
+	ProviderOpenAI    = "openai"    // OpenAI or any server with an OpenAI-compatible API
+	ProviderAnthropic = "anthropic" // the Anthropic Messages API
+	ProviderOllama    = "ollama"    // a local Ollama server; needs no API key
+)
+
+// defaultAPIKeyEnv is where each provider's API key is read from by default.
+var defaultAPIKeyEnv = map[string]string{
+	ProviderOpenAI:    "OPENAI_API_KEY",
+	ProviderAnthropic: "ANTHROPIC_API_KEY",
+	ProviderOllama:    "",
+}
+
+// LLM selects the language model the assistant uses (see internal/llm).
+type LLM struct {
+	// Provider is the API to talk to: "openai", "anthropic" or "ollama". Empty disables
+	// the model.
+	Provider string `json:"provider"`
+	// Model is the provider's model name, e.g. "gpt-4o-mini".
+	Model string `json:"model"`
+	// BaseURL overrides the provider's endpoint, e.g. "http://localhost:8000/v1" for a
+	// local OpenAI-compatible server.
+	BaseURL string `json:"baseUrl,omitempty"`
+	// APIKeyEnv names the environment variable holding the API key, so the key itself
+	// never ends up in the config file. Defaults to e.g. OPENAI_API_KEY.
+	APIKeyEnv string `json:"apiKeyEnv,omitempty"`
+	// MaxTokens limits the length of each answer; Anthropic requires it (default 1024),
+	// the other providers use the model's default without it.
+	// For Ollama it is passed as num_predict.
+	MaxTokens int `json:"maxTokens,omitempty"`
+	// ContextTokens is the budget of the chat sent to the model (default 8000). Older
+	// turns are summarized or dropped to stay within it.
+	ContextTokens int `json:"contextTokens,omitempty"`
+	// MaxSteps limits how many times the model is called for one query (default 5).
+	MaxSteps int `json:"maxSteps,omitempty"`
+	// TimeoutSeconds limits the time the model and its tools may take for one query
+	// (default 30).
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+	// Prices lists what models cost, keyed by model name, to put a price on the tokens
+	// of each answer (see internal/usage). Models without an entry are counted but not
+	// priced.
+	Prices map[string]Price `json:"prices,omitempty"`
+}
+
+// Price is what a model costs, in US dollars per million tokens.
+type Price struct {
+	Input  float64 `json:"input"`  // prompt tokens
+	Output float64 `json:"output"` // completion tokens
+}
+
+// APIKey reads the provider's API key from the environment. It may be empty, e.g. for
+// local servers.
+func (l *LLM) APIKey() string {
+	env := l.APIKeyEnv
+	if env == "" {
+		env = defaultAPIKeyEnv[l.Provider]
+	}
+	if env == "" {
+		return ""
+	}
+	return os.Getenv(env)
+}
+
+// applyEnv overrides the provider, model and base URL with LLM_PROVIDER, LLM_MODEL and
+// LLM_BASE_URL when they are set, and reports whether any was. Switching the provider
+// drops the file's base URL and API key variable, which belong to the other provider.
+func (l *LLM) applyEnv() bool {
+	changed := false
+	if provider := os.Getenv(EnvLLMProvider); provider != "" {
+		if provider != l.Provider {
+			l.BaseURL, l.APIKeyEnv = "", ""
+		}
+		l.Provider = provider
+		changed = true
+	}
+	if model := os.Getenv(EnvLLMModel); model != "" {
+		l.Model = model
+		changed = true
+	}
+	if baseURL := os.Getenv(EnvLLMBaseURL); baseURL != "" {
+		l.BaseURL = baseURL
+		changed = true
+	}
+	return changed
+}
+
+// RAG configures the document search (see internal/rag). Without a directory no files are
+// read at startup.
+type RAG struct {
+	// Dir holds the Markdown, HTML and text files to search, read at startup including
+	// subdirectories.
+	Dir string `json:"dir,omitempty"`
+	// ChunkTokens is the size of the passages documents are cut into (default 200).
+	ChunkTokens int `json:"chunkTokens,omitempty"`
+	// TopK is the number of passages given to the model with a question (default 4).
+	TopK int `json:"topK,omitempty"`
+	// Embeddings optionally ranks passages by meaning too, not only by their words.
+	Embeddings *Embeddings `json:"embeddings,omitempty"`
+}
+
+// Embeddings selects an OpenAI-compatible embeddings API.
+type Embeddings struct {
+	// Model is the embedding model, e.g. "text-embedding-3-small" or, with Ollama,
+	// "nomic-embed-text".
+	Model string `json:"model"`
+	// BaseURL defaults to the OpenAI API; e.g. "http://localhost:11434/v1" for Ollama.
+	BaseURL string `json:"baseUrl,omitempty"`
+	// APIKeyEnv names the environment variable holding the API key (default
+	// OPENAI_API_KEY).
+	APIKeyEnv string `json:"apiKeyEnv,omitempty"`
+}
+
+// APIKey reads the embeddings API key from the environment. It may be empty, e.g. for
+// local servers.
+func (e *Embeddings) APIKey() string {
+	env := e.APIKeyEnv
+	if env == "" {
+		env = defaultAPIKeyEnv[ProviderOpenAI]
+	}
+	return os.Getenv(env)
+}
+
+func (c *Config) validate() error {
+	if err := validateServers("mcpServers", c.MCPServers); err != nil {
+		return err
+	}
+	if err := validateServers("gateway.mcpServers", c.Gateway.MCPServers); err != nil {
+		return err
+	}
+	if err := c.Auth.validate(); err != nil {
+		return err
+	}
+	if err := c.RAG.validate(); err != nil {
+		return err
+	}
+	return c.LLM.validate()
+}
+
+func validateServers(key string, servers map[string]MCPServer) error {
+	for name, server := range servers {
+		if name == "" {
+			return fmt.Errorf("%s: empty server name", key)
+		}
+		if (server.Command == "") == (server.URL == "") {
+			return fmt.Errorf("%s.%s: set exactly one of command or url", key, name)
+		}
+	}
+	return nil
+}
+
+func (a *Auth) validate() error {
+	if a.Issuer == "" {
+		return nil
+	}
+	if err := checkURL("auth.issuer", a.Issuer); err != nil {
+		return err
+	}
+	if err := checkURL("auth.resource", a.Resource); err != nil {
+		return err
+	}
+	if a.JWKSURL != "" {
+		return checkURL("auth.jwksUrl", a.JWKSURL)
+	}
+	return nil
+}
+
+func (r *RAG) validate() error {
+	if r.ChunkTokens < 0 || r.TopK < 0 {
+		return errors.New("rag.chunkTokens and rag.topK must not be negative")
+	}
+	if r.Embeddings == nil {
+		return nil
+	}
+	if r.Embeddings.Model == "" {
+		return errors.New("rag.embeddings.model: required")
+	}
+	if r.Embeddings.BaseURL != "" {
+		return checkURL("rag.embeddings.baseUrl", r.Embeddings.BaseURL)
+	}
+	return nil
+}
+
+func (l *LLM) validate() error {
+	if l.Provider == "" {
+		return nil
+	}
+	if _, known := defaultAPIKeyEnv[l.Provider]; !known {
+		return fmt.Errorf("llm.provider: unknown provider %q", l.Provider)
+	}
+	if l.Model == "" {
+		return errors.New("llm.model: required when llm.provider is set")
+	}
+	if l.MaxTokens < 0 || l.ContextTokens < 0 || l.MaxSteps < 0 || l.TimeoutSeconds < 0 {
+		return errors.New("llm.maxTokens, llm.contextTokens, llm.maxSteps and llm.timeoutSeconds must not be negative")
+	}
+	for model, price := range l.Prices {
+		if price.Input < 0 || price.Output < 0 {
+			return fmt.Errorf("llm.prices.%s: prices must not be negative", model)
+		}
+	}
+	if l.BaseURL != "" {
+		return checkURL("llm.baseUrl", l.BaseURL)
+	}
+	return nil
+}
+
+func checkURL(key, value string) error {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("%s: %q is not an absolute http(s) URL", key, value)
+	}
+	return nil
+}
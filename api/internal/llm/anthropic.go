@@ -0,0 +1,307 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// DefaultAnthropicURL is the base URL of the Anthropic API.
+	DefaultAnthropicURL = "https://api.anthropic.com"
+	// anthropicVersion is the API version we speak, sent in the anthropic-version header.
+	anthropicVersion = "2023-06-01"
+	// defaultMaxTokens is used when no limit is configured; the API requires one.
+	defaultMaxTokens = 1024
+)
+
+// Anthropic talks to the Anthropic Messages API (POST /v1/messages).
+type Anthropic struct {
+	BaseURL   string       // e.g. https://api.anthropic.com
+	APIKey    string       // sent in the x-api-key header
+	Model     string       // e.g. claude-sonnet-4-5
+	MaxTokens int          // limit of each answer
+	Client    *http.Client // defaults to http.DefaultClient
+}
+
+// NewAnthropic creates a provider for model. An empty baseURL means DefaultAnthropicURL
+// and maxTokens 0 means 1024.
+func NewAnthropic(baseURL, apiKey, model string, maxTokens int) *Anthropic {
+	if baseURL == "" {
+		baseURL = DefaultAnthropicURL
+	}
+	if maxTokens <= 0 {
+		maxTokens = defaultMaxTokens
+	}
+	return &Anthropic{BaseURL: strings.TrimSuffix(baseURL, "/"), APIKey: apiKey, Model: model, MaxTokens: maxTokens}
+}
+
+// Wire format of the Messages API. Messages hold content blocks: "text", "tool_use"
+// (the model calling a tool) and "tool_result" (our answer to a tool_use).
+type anthropicRequest struct {
+	Model      string               `json:"model"`
+	MaxTokens  int                  `json:"max_tokens"`
+	System     string               `json:"system,omitempty"`
+	Messages   []anthropicMessage   `json:"messages"`
+	Tools      []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice *anthropicToolChoice `json:"tool_choice,omitempty"`
+	Stream     bool                 `json:"stream,omitempty"`
+}
+
+type anthropicToolChoice struct {
+	Type string `json:"type"` // "auto", "any", "tool" or "none"
+}
+
+type anthropicMessage struct {
+	Role    string           `json:"role"` // "user" or "assistant"
+	Content []anthropicBlock `json:"content"`
+}
+
+type anthropicBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`        // text
+	ID        string          `json:"id,omitempty"`          // tool_use
+	Name      string          `json:"name,omitempty"`        // tool_use
+	Input     json.RawMessage `json:"input,omitempty"`       // tool_use
+	ToolUseID string          `json:"tool_use_id,omitempty"` // tool_result
+	Content   string          `json:"content,omitempty"`     // tool_result
+}
+
+type anthropicTool struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	InputSchema any    `json:"input_schema"`
+}
+
+type anthropicResponse struct {
+	Content    []anthropicBlock `json:"content"`
+	StopReason string           `json:"stop_reason"`
+	Usage      anthropicUsage   `json:"usage"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// anthropicEvent is the data of one server-sent event of a streamed answer.
+type anthropicEvent struct {
+	Type         string         `json:"type"`
+	Index        int            `json:"index"`
+	ContentBlock anthropicBlock `json:"content_block"` // content_block_start
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`         // text_delta
+		PartialJSON string `json:"partial_json"` // input_json_delta
+		StopReason  string `json:"stop_reason"`  // message_delta
+	} `json:"delta"`
+	Message struct {
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message"` // message_start
+	Usage anthropicUsage `json:"usage"` // message_delta
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Chat implements LLMProvider. With req.OnText set the answer is streamed.
+func (a *Anthropic) Chat(ctx context.Context, req Request) (*Response, error) {
+	body, err := json.Marshal(a.buildRequest(req))
+	if err != nil {
+		return nil, fmt.Errorf("llm: encode request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, a.BaseURL+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("anthropic-version", anthropicVersion)
+	if a.APIKey != "" {
+		httpReq.Header.Set("x-api-key", a.APIKey)
+	}
+
+	client := a.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("llm: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, readAPIError(resp) // same {"error": {"message": ...}} shape as OpenAI
+	}
+	if req.OnText != nil {
+		return readAnthropicStream(resp.Body, req.OnText)
+	}
+	var out anthropicResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("llm: decode response: %w", err)
+	}
+	answer := parseAnthropicContent(out.Content, out.StopReason)
+	answer.Usage = Usage{PromptTokens: out.Usage.InputTokens, CompletionTokens: out.Usage.OutputTokens}
+	return answer, nil
+}
+
+// buildRequest converts the chat. System messages become the system prompt, tool results
+// are sent as user messages, and consecutive messages of the same role are merged, since
+// the API wants user and assistant turns to alternate.
+func (a *Anthropic) buildRequest(req Request) anthropicRequest {
+	out := anthropicRequest{Model: a.Model, MaxTokens: a.MaxTokens, Stream: req.OnText != nil}
+	var system []string
+	for _, msg := range req.Messages {
+		var (
+			role   string
+			blocks []anthropicBlock
+		)
+		switch msg.Role {
+		case RoleSystem:
+			system = append(system, msg.Content)
+			continue
+		case RoleTool:
+			role = "user"
+			blocks = []anthropicBlock{{Type: "tool_result", ToolUseID: msg.ToolCallID, Content: msg.Content}}
+		case RoleAssistant:
+			role = "assistant"
+			if msg.Content != "" {
+				blocks = append(blocks, anthropicBlock{Type: "text", Text: msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				input := call.Arguments
+				if len(input) == 0 {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, anthropicBlock{Type: "tool_use", ID: call.ID, Name: call.Name, Input: input})
+			}
+		default:
+			role = "user"
+			blocks = []anthropicBlock{{Type: "text", Text: msg.Content}}
+		}
+
+		if n := len(out.Messages); n > 0 && out.Messages[n-1].Role == role {
+			out.Messages[n-1].Content = append(out.Messages[n-1].Content, blocks...)
+		} else {
+			out.Messages = append(out.Messages, anthropicMessage{Role: role, Content: blocks})
+		}
+	}
+	out.System = strings.Join(system, "\n\n")
+
+	for _, tool := range req.Tools {
+		out.Tools = append(out.Tools, anthropicTool{Name: tool.Name, Description: tool.Description, InputSchema: tool.Parameters})
+	}
+	if req.NoToolCalls && len(out.Tools) > 0 {
+		out.ToolChoice = &anthropicToolChoice{Type: "none"}
+	}
+	return out
+}
+
+// readAnthropicStream assembles a streamed answer from its events (message_start,
+// content_block_start/delta/stop, message_delta, message_stop), passing text to onText
+// as it arrives. The prompt tokens come with message_start, the final count of answer
+// tokens with message_delta.
+func readAnthropicStream(body io.Reader, onText func(string)) (*Response, error) {
+	var (
+		blocks     []anthropicBlock
+		inputs     = make(map[int]*strings.Builder) // partial JSON of tool_use blocks
+		stopReason string
+		usage      Usage
+	)
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue // "event:" lines repeat the type found in the data
+		}
+		var event anthropicEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &event); err != nil {
+			return nil, fmt.Errorf("llm: decode stream event: %w", err)
+		}
+
+		switch event.Type {
+		case "message_start":
+			usage.PromptTokens = event.Message.Usage.InputTokens
+			usage.CompletionTokens = event.Message.Usage.OutputTokens
+		case "content_block_start":
+			for len(blocks) <= event.Index {
+				blocks = append(blocks, anthropicBlock{})
+			}
+			blocks[event.Index] = event.ContentBlock
+		case "content_block_delta":
+			if event.Index >= len(blocks) {
+				return nil, errors.New("llm: stream delta for an unknown content block")
+			}
+			switch event.Delta.Type {
+			case "text_delta":
+				blocks[event.Index].Text += event.Delta.Text
+				onText(event.Delta.Text)
+			case "input_json_delta":
+				if inputs[event.Index] == nil {
+					inputs[event.Index] = &strings.Builder{}
+				}
+				inputs[event.Index].WriteString(event.Delta.PartialJSON)
+			}
+		case "content_block_stop":
+			if b := inputs[event.Index]; b != nil && b.Len() > 0 && event.Index < len(blocks) {
+				if !json.Valid([]byte(b.String())) {
+					return nil, fmt.Errorf("llm: model sent invalid JSON arguments for %s", blocks[event.Index].Name)
+				}
+				blocks[event.Index].Input = json.RawMessage(b.String())
+			}
+		case "message_delta":
+			if event.Delta.StopReason != "" {
+				stopReason = event.Delta.StopReason
+			}
+			if event.Usage.OutputTokens > 0 {
+				usage.CompletionTokens = event.Usage.OutputTokens
+			}
+		case "message_stop":
+			resp := parseAnthropicContent(blocks, stopReason)
+			resp.Usage = usage
+			return resp, nil
+		case "error":
+			return nil, &APIError{StatusCode: http.StatusOK, Type: event.Error.Type, Message: event.Error.Message}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("llm: read stream: %w", err)
+	}
+	return nil, errors.New("llm: stream ended before message_stop")
+}
+
+func parseAnthropicContent(blocks []anthropicBlock, stopReason string) *Response {
+	out := &Response{Message: Message{Role: RoleAssistant}, StopReason: stopReason}
+	var text []string
+	for _, block := range blocks {
+		switch block.Type {
+		case "text":
+			text = append(text, block.Text)
+		case "tool_use":
+			input := block.Input
+			if len(input) == 0 {
+				input = json.RawMessage("{}")
+			}
+			out.Message.ToolCalls = append(out.Message.ToolCalls, ToolCall{ID: block.ID, Name: block.Name, Arguments: input})
+		}
+	}
+	out.Message.Content = strings.Join(text, "")
+
+	switch stopReason {
+	case "end_turn", "stop_sequence":
+		out.StopReason = StopEnd
+	case "tool_use":
+		out.StopReason = StopToolUse
+	case "max_tokens":
+		out.StopReason = StopMaxTokens
+	}
+	return out
+}
@@ -0,0 +1,135 @@
+package llm_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"gonuxt-context-assistant/internal/llm"
+	"gonuxt-context-assistant/internal/llm/llmtest"
+)
+
+func chatAnthropic(t *testing.T, reply llmtest.Reply, onText func(string)) (*llm.Response, llmtest.Received, error) {
+	t.Helper()
+	srv := llmtest.NewServer(reply)
+	defer srv.Close()
+	resp, err := llm.NewAnthropic(srv.URL, "sk-ant-test", "claude-test", 512).Chat(context.Background(), llm.Request{
+		Messages: []llm.Message{
+			{Role: llm.RoleSystem, Content: "Use the tools."},
+			{Role: llm.RoleUser, Content: "Weather in Lisbon and Paris?"},
+		},
+		Tools:  []llm.Tool{weatherTool},
+		OnText: onText,
+	})
+	return resp, srv.Received()[0], err
+}
+
+func TestAnthropicAnswer(t *testing.T) {
+	resp, received, err := chatAnthropic(t, llmtest.Recorded("anthropic/answer.json"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(resp.Message.Content, "It is sunny and 28°C in Lisbon") || len(resp.Message.ToolCalls) != 0 {
+		t.Errorf("message = %+v", resp.Message)
+	}
+	if resp.StopReason != llm.StopEnd {
+		t.Errorf("stop reason = %q, want %q", resp.StopReason, llm.StopEnd)
+	}
+	if want := (llm.Usage{PromptTokens: 701, CompletionTokens: 31}); resp.Usage != want {
+		t.Errorf("usage = %+v, want %+v", resp.Usage, want)
+	}
+
+	if received.Path != "/v1/messages" || received.Model != "claude-test" || received.Authorization != "sk-ant-test" {
+		t.Errorf("request = %+v", received)
+	}
+	if received.System != "Use the tools." || len(received.Messages) != 1 || received.MaxTokens != 512 || received.Stream {
+		t.Errorf("system = %q, messages = %+v, max_tokens = %d, stream = %v", received.System, received.Messages, received.MaxTokens, received.Stream)
+	}
+	if !slices.Equal(received.ToolNames, []string{"get_weather"}) {
+		t.Errorf("tools = %v", received.ToolNames)
+	}
+}
+
+func TestAnthropicToolUse(t *testing.T) {
+	resp, _, err := chatAnthropic(t, llmtest.Recorded("anthropic/tool_use.json"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StopReason != llm.StopToolUse || resp.Message.Content != "I'll look up the weather in both cities." {
+		t.Errorf("stop reason = %q, content = %q", resp.StopReason, resp.Message.Content)
+	}
+	calls := resp.Message.ToolCalls
+	if len(calls) != 2 {
+		t.Fatalf("tool calls = %+v, want 2", calls)
+	}
+	for i, want := range []struct{ id, args string }{{"toolu_01A09q90qw90lq917835lq9", `{"city": "Lisbon"}`}, {"toolu_01T1x1fJ34qAmk2tNTrN7Up6", `{"city": "Paris"}`}} {
+		if calls[i].ID != want.id || calls[i].Name != "get_weather" || string(calls[i].Arguments) != want.args {
+			t.Errorf("call %d = %+v, want %s %s", i, calls[i], want.id, want.args)
+		}
+	}
+}
+
+func TestAnthropicStreamedAnswer(t *testing.T) {
+	var pieces []string
+	resp, received, err := chatAnthropic(t, llmtest.Recorded("anthropic/answer.sse"), func(text string) { pieces = append(pieces, text) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !received.Stream {
+		t.Error("the request did not ask for a stream")
+	}
+	if want := []string{"It is rainy", " in Tokyo, with", " 15°C."}; !slices.Equal(pieces, want) {
+		t.Errorf("OnText received %q, want %q", pieces, want)
+	}
+	if resp.Message.Content != "It is rainy in Tokyo, with 15°C." || resp.StopReason != llm.StopEnd {
+		t.Errorf("message = %+v, stop reason = %q", resp.Message, resp.StopReason)
+	}
+	if want := (llm.Usage{PromptTokens: 598, CompletionTokens: 14}); resp.Usage != want {
+		t.Errorf("usage = %+v, want %+v", resp.Usage, want)
+	}
+}
+
+func TestAnthropicStreamedToolUse(t *testing.T) {
+	var streamed strings.Builder
+	resp, _, err := chatAnthropic(t, llmtest.Recorded("anthropic/tool_use.sse"), func(text string) { streamed.WriteString(text) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if streamed.String() != "Let me check the weather in Tokyo." || resp.Message.Content != streamed.String() {
+		t.Errorf("streamed %q, content %q", streamed.String(), resp.Message.Content)
+	}
+	calls := resp.Message.ToolCalls
+	if resp.StopReason != llm.StopToolUse || len(calls) != 1 {
+		t.Fatalf("stop reason = %q, tool calls = %+v", resp.StopReason, calls)
+	}
+	if calls[0].ID != "toolu_01T1x1fJ34qAmk2tNTrN7Up6" || calls[0].Name != "get_weather" || string(calls[0].Arguments) != `{"city": "Tokyo"}` {
+		t.Errorf("tool call = %+v, want get_weather for Tokyo", calls[0])
+	}
+}
+
+func TestAnthropicOverloaded(t *testing.T) {
+	overloaded := llmtest.Recorded("anthropic/overloaded.json")
+	overloaded.Status = 529
+	tests := []struct {
+		name   string
+		reply  llmtest.Reply
+		onText func(string)
+		status int
+	}{
+		{"error answer", overloaded, nil, 529},
+		{"error event of a stream", llmtest.Recorded("anthropic/overloaded.sse"), func(string) {}, 200},
+	}
+	for _, tt := range tests {
+		_, _, err := chatAnthropic(t, tt.reply, tt.onText)
+		var apiErr *llm.APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status || apiErr.Type != "overloaded_error" || apiErr.Message != "Overloaded" {
+			t.Errorf("%s: err = %#v, want an overloaded_error", tt.name, err)
+			continue
+		}
+		if !errors.Is(err, llm.ErrUnavailable) || !llm.Retryable(err) {
+			t.Errorf("%s: err = %v, want a retryable llm.ErrUnavailable", tt.name, err)
+		}
+	}
+}
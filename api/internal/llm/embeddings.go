@@ -0,0 +1,97 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Embedder turns texts into vectors whose cosine similarity says how close their meaning
+// is. Document search uses it next to keyword ranking (see internal/rag).
+type Embedder interface {
+	// Embed returns one vector per text, in the order of texts.
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// OpenAIEmbeddings talks to the embeddings API of OpenAI or of any compatible server,
+// e.g. Ollama at http://localhost:11434/v1.
+type OpenAIEmbeddings struct {
+	BaseURL string       // e.g. https://api.openai.com/v1
+	APIKey  string       // sent as a bearer token; may be empty for local servers
+	Model   string       // e.g. text-embedding-3-small
+	Client  *http.Client // defaults to http.DefaultClient
+}
+
+// NewOpenAIEmbeddings creates an Embedder for model. An empty baseURL means DefaultOpenAIURL.
+func NewOpenAIEmbeddings(baseURL, apiKey, model string) *OpenAIEmbeddings {
+	if baseURL == "" {
+		baseURL = DefaultOpenAIURL
+	}
+	return &OpenAIEmbeddings{BaseURL: strings.TrimSuffix(baseURL, "/"), APIKey: apiKey, Model: model}
+}
+
+// Wire format of POST /embeddings.
+type openAIEmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type openAIEmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embed implements Embedder.
+func (o *OpenAIEmbeddings) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	body, err := json.Marshal(openAIEmbeddingRequest{Model: o.Model, Input: texts})
+	if err != nil {
+		return nil, fmt.Errorf("llm: encode request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, o.BaseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if o.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+o.APIKey)
+	}
+
+	client := o.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("llm: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, readAPIError(resp)
+	}
+	var out openAIEmbeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("llm: decode response: %w", err)
+	}
+	vectors := make([][]float32, len(texts))
+	for _, item := range out.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, fmt.Errorf("llm: embedding index %d out of range", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	for i, vector := range vectors {
+		if len(vector) == 0 {
+			return nil, fmt.Errorf("llm: no embedding for input %d", i)
+		}
+	}
+	return vectors, nil
+}
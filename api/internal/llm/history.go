@@ -0,0 +1,194 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultContextTokens is the prompt budget when none is configured. It is well below
+	// the context window of current models, which also leaves room for the answer.
+	DefaultContextTokens = 8000
+	// summaryTokens is set aside for the summary of trimmed turns, or a quarter of the
+	// room left for earlier turns if that is less.
+	summaryTokens = 300
+	// maxCachedSummaries bounds the summaries kept for reuse.
+	maxCachedSummaries = 256
+)
+
+// ErrContextTooLong is returned by HistoryManager.Fit when the system prompt, the current
+// question and its tool results alone exceed the budget.
+var ErrContextTooLong = errors.New("llm: the question does not fit in the context budget")
+
+const summarizePrompt = "Summarize the conversation below in at most 100 words. Keep names, cities, " +
+	"numbers and anything the user asked for that is still open. Reply with the summary only."
+
+// summaryPrefix starts the system message holding the summary, so a later Fit of the same
+// chat (the next step of an agent, say) recognises it.
+const summaryPrefix = "Summary of the earlier conversation: "
+
+// HistoryManager keeps a chat within a token budget before it is sent to the model. When
+// the chat is too long, the oldest turns are dropped and, with a Summarizer, replaced by a
+// short summary written by the model. System messages and the current turn (the last user
+// message and the tool calls and results after it) are always kept, and a tool result is
+// never separated from the call it answers. A chat holds at most one summary: when Fit
+// shortens a chat it already summarized, the old summary is summarized with the turns.
+type HistoryManager struct {
+	Budget     int         // prompt tokens, tool schemas included; default DefaultContextTokens
+	Summarizer LLMProvider // optional: without one old turns are simply dropped
+
+	mu        sync.Mutex
+	summaries map[string]string // by hash of the summarized transcript
+}
+
+// NewHistoryManager creates a manager for budget tokens (0 means DefaultContextTokens).
+func NewHistoryManager(budget int, summarizer LLMProvider) *HistoryManager {
+	return &HistoryManager{Budget: budget, Summarizer: summarizer}
+}
+
+// MaxTokens returns the budget in use.
+func (h *HistoryManager) MaxTokens() int {
+	if h != nil && h.Budget > 0 {
+		return h.Budget
+	}
+	return DefaultContextTokens
+}
+
+// Fit returns messages, shortened if needed so that they and tools fit in the budget.
+// The messages passed in are not modified.
+func (h *HistoryManager) Fit(ctx context.Context, messages []Message, tools []Tool) ([]Message, error) {
+	budget := h.MaxTokens()
+	if EstimateRequestTokens(messages, tools) <= budget {
+		return messages, nil
+	}
+
+	current := len(messages)
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == RoleUser {
+			current = i
+			break
+		}
+	}
+
+	// Split the earlier messages into system messages, the summary of an earlier Fit and
+	// turns; a turn starts at a user message and holds the answers and tool messages after it.
+	var (
+		system   []Message
+		previous *Message
+		turns    [][]Message
+	)
+	for _, msg := range messages[:current] {
+		switch {
+		case msg.Role == RoleSystem && h.Summarizer != nil && strings.HasPrefix(msg.Content, summaryPrefix):
+			previous = &msg
+		case msg.Role == RoleSystem:
+			system = append(system, msg)
+		case msg.Role == RoleUser || len(turns) == 0:
+			turns = append(turns, []Message{msg})
+		default:
+			turns[len(turns)-1] = append(turns[len(turns)-1], msg)
+		}
+	}
+
+	room := budget - EstimateRequestTokens(system, tools) - EstimateRequestTokens(messages[current:], nil)
+	if room < 0 {
+		return nil, ErrContextTooLong
+	}
+	if h.Summarizer != nil {
+		room -= min(summaryTokens, room/4)
+	}
+
+	// Keep the most recent turns that fit.
+	cut := len(turns)
+	for cut > 0 {
+		n := EstimateRequestTokens(turns[cut-1], nil)
+		if n > room {
+			break
+		}
+		room -= n
+		cut--
+	}
+
+	out := append([]Message(nil), system...)
+	if cut > 0 && h.Summarizer != nil {
+		var dropped []Message
+		if previous != nil {
+			dropped = append(dropped, *previous)
+		}
+		for _, turn := range turns[:cut] {
+			dropped = append(dropped, turn...)
+		}
+		summary, err := h.summarize(ctx, dropped)
+		switch {
+		case err == nil:
+			previous = &Message{Role: RoleSystem, Content: summaryPrefix + summary}
+		case ctx.Err() != nil:
+			return nil, ctx.Err()
+		}
+		// Without a new summary the old turns are dropped, which still answers the question.
+	}
+	if previous != nil {
+		out = append(out, *previous)
+	}
+	for _, turn := range turns[cut:] {
+		out = append(out, turn...)
+	}
+	return append(out, messages[current:]...), nil
+}
+
+// summarize asks the Summarizer for a summary of messages, reusing an earlier summary of
+// the same messages: a session's old turns are the same from one question to the next.
+func (h *HistoryManager) summarize(ctx context.Context, messages []Message) (string, error) {
+	var transcript strings.Builder
+	for _, msg := range messages {
+		switch {
+		case msg.Role == RoleSystem:
+			fmt.Fprintf(&transcript, "%s\n", msg.Content) // the summary of the turns before
+		case msg.Role == RoleTool:
+			fmt.Fprintf(&transcript, "tool result: %s\n", msg.Content)
+		case len(msg.ToolCalls) > 0:
+			for _, call := range msg.ToolCalls {
+				fmt.Fprintf(&transcript, "%s called %s %s\n", msg.Role, call.Name, call.Arguments)
+			}
+			if msg.Content != "" {
+				fmt.Fprintf(&transcript, "%s: %s\n", msg.Role, msg.Content)
+			}
+		default:
+			fmt.Fprintf(&transcript, "%s: %s\n", msg.Role, msg.Content)
+		}
+	}
+	sum := sha256.Sum256([]byte(transcript.String()))
+	key := hex.EncodeToString(sum[:])
+
+	h.mu.Lock()
+	summary, ok := h.summaries[key]
+	h.mu.Unlock()
+	if ok {
+		return summary, nil
+	}
+
+	resp, err := h.Summarizer.Chat(ctx, Request{Messages: []Message{
+		{Role: RoleSystem, Content: summarizePrompt},
+		{Role: RoleUser, Content: transcript.String()},
+	}})
+	if err != nil {
+		return "", err
+	}
+	summary = strings.TrimSpace(resp.Message.Content)
+	if summary == "" {
+		return "", errors.New("llm: empty summary")
+	}
+
+	h.mu.Lock()
+	if h.summaries == nil || len(h.summaries) >= maxCachedSummaries {
+		h.summaries = make(map[string]string)
+	}
+	h.summaries[key] = summary
+	h.mu.Unlock()
+	return summary, nil
+}
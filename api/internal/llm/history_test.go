@@ -0,0 +1,218 @@
+package llm_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"gonuxt-context-assistant/internal/llm"
+)
+
+// words returns text of about n tokens.
+func words(n int) string { return strings.Repeat("abcd", n) }
+
+// summarizer answers every request with "summary N" and records the transcripts.
+type summarizer struct {
+	transcripts []string
+	err         error
+}
+
+func (s *summarizer) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.transcripts = append(s.transcripts, req.Messages[len(req.Messages)-1].Content)
+	return &llm.Response{Message: llm.Message{Role: llm.RoleAssistant, Content: fmt.Sprintf("summary %d", len(s.transcripts))}}, nil
+}
+
+// chat builds a system prompt, n earlier turns of two 100-token messages and a question.
+func chat(n int) []llm.Message {
+	messages := []llm.Message{{Role: llm.RoleSystem, Content: "You are a helpful assistant."}}
+	for i := range n {
+		messages = append(messages,
+			llm.Message{Role: llm.RoleUser, Content: fmt.Sprintf("question %d %s", i+1, words(100))},
+			llm.Message{Role: llm.RoleAssistant, Content: fmt.Sprintf("answer %d %s", i+1, words(100))},
+		)
+	}
+	return append(messages, llm.Message{Role: llm.RoleUser, Content: "And now?"})
+}
+
+// summaries returns the summary messages of messages.
+func summaries(messages []llm.Message) []string {
+	var out []string
+	for _, msg := range messages {
+		if msg.Role == llm.RoleSystem && strings.HasPrefix(msg.Content, "Summary of the earlier conversation: ") {
+			out = append(out, strings.TrimPrefix(msg.Content, "Summary of the earlier conversation: "))
+		}
+	}
+	return out
+}
+
+func TestFitStaysWithinTheBudget(t *testing.T) {
+	tests := []struct {
+		name   string
+		turns  int
+		budget int
+		kept   int // earlier turns kept
+	}{
+		{"fits", 3, 1000, 3},
+		{"exactly fits", 3, llm.EstimateRequestTokens(chat(3), nil), 3},
+		{"one turn too many", 3, llm.EstimateRequestTokens(chat(3), nil) - 1, 2},
+		{"room for one turn", 5, 300, 1},
+		{"room for the question only", 5, 100, 0},
+	}
+	for _, tt := range tests {
+		messages := chat(tt.turns)
+		fitted, err := llm.NewHistoryManager(tt.budget, nil).Fit(context.Background(), messages, nil)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if n := llm.EstimateRequestTokens(fitted, nil); n > tt.budget {
+			t.Errorf("%s: %d tokens, budget %d", tt.name, n, tt.budget)
+		}
+		if want := 2 + 2*tt.kept; len(fitted) != want {
+			t.Errorf("%s: %d messages, want %d", tt.name, len(fitted), want)
+			continue
+		}
+		// The system prompt, the most recent turns and the question are kept, in order.
+		if fitted[0].Content != messages[0].Content || fitted[len(fitted)-1].Content != messages[len(messages)-1].Content {
+			t.Errorf("%s: lost the system prompt or the question: %v", tt.name, fitted)
+		}
+		for i, msg := range fitted[1 : len(fitted)-1] {
+			if want := messages[len(messages)-1-2*tt.kept+i]; msg.Content != want.Content {
+				t.Errorf("%s: message %d = %.20q, want %.20q", tt.name, i+1, msg.Content, want.Content)
+			}
+		}
+	}
+}
+
+func TestFitKeepsToolResultsWithTheirCall(t *testing.T) {
+	call := llm.ToolCall{ID: "call_1", Name: "get_weather", Arguments: json.RawMessage(`{"city":"Lisbon"}`)}
+	messages := []llm.Message{
+		{Role: llm.RoleSystem, Content: "You are a helpful assistant."},
+		{Role: llm.RoleUser, Content: "Weather in Lisbon? " + words(100)},
+		{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{call}},
+		{Role: llm.RoleTool, ToolCallID: "call_1", Content: words(100)},
+		{Role: llm.RoleAssistant, Content: "Sunny."},
+		{Role: llm.RoleUser, Content: "And Paris?"},
+		{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{{ID: "call_2", Name: "get_weather", Arguments: json.RawMessage(`{"city":"Paris"}`)}}},
+		{Role: llm.RoleTool, ToolCallID: "call_2", Content: "clear, 20°C"},
+	}
+	// Room for the current turn and part of the first one: the whole first turn goes.
+	budget := llm.EstimateRequestTokens(messages, nil) - 50
+	fitted, err := llm.NewHistoryManager(budget, nil).Fit(context.Background(), messages, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fitted) != 4 || fitted[1].Content != "And Paris?" || fitted[3].ToolCallID != "call_2" {
+		t.Errorf("fitted = %+v, want the system prompt and the current turn", fitted)
+	}
+}
+
+func TestFitCountsTheTools(t *testing.T) {
+	messages := chat(2)
+	budget := llm.EstimateRequestTokens(messages, nil)
+	hm := llm.NewHistoryManager(budget, nil)
+	if fitted, _ := hm.Fit(context.Background(), messages, nil); len(fitted) != len(messages) {
+		t.Errorf("without tools: %d messages, want %d", len(fitted), len(messages))
+	}
+	if fitted, _ := hm.Fit(context.Background(), messages, []llm.Tool{weatherTool}); len(fitted) >= len(messages) {
+		t.Errorf("with tools: %d messages, want fewer than %d", len(fitted), len(messages))
+	}
+}
+
+func TestFitContextTooLong(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []llm.Message
+		tools    []llm.Tool
+	}{
+		{"long question", []llm.Message{{Role: llm.RoleUser, Content: words(200)}}, nil},
+		{"long system prompt", []llm.Message{{Role: llm.RoleSystem, Content: words(150)}, {Role: llm.RoleUser, Content: words(60)}}, nil},
+		{"long tool result", []llm.Message{
+			{Role: llm.RoleUser, Content: "Weather in Lisbon?"},
+			{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{{ID: "call_1", Name: "get_weather", Arguments: json.RawMessage(`{}`)}}},
+			{Role: llm.RoleTool, ToolCallID: "call_1", Content: words(200)},
+		}, nil},
+		{"tools", []llm.Message{{Role: llm.RoleUser, Content: words(140)}}, []llm.Tool{weatherTool}},
+	}
+	for _, tt := range tests {
+		_, err := llm.NewHistoryManager(150, &summarizer{}).Fit(context.Background(), tt.messages, tt.tools)
+		if !errors.Is(err, llm.ErrContextTooLong) {
+			t.Errorf("%s: err = %v, want ErrContextTooLong", tt.name, err)
+		}
+	}
+}
+
+func TestFitSummarizesDroppedTurns(t *testing.T) {
+	s := &summarizer{}
+	hm := llm.NewHistoryManager(800, s)
+	messages := chat(5)
+
+	fitted, err := hm.Fit(context.Background(), messages, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := summaries(fitted); len(got) != 1 || got[0] != "summary 1" {
+		t.Fatalf("summaries = %q, want one", got)
+	}
+	if fitted[1].Role != llm.RoleSystem || !strings.Contains(s.transcripts[0], "question 1") || strings.Contains(s.transcripts[0], "question 5") {
+		t.Errorf("summary at %v of transcript %.40q", fitted[1], s.transcripts[0])
+	}
+	if n := llm.EstimateRequestTokens(fitted, nil); n > 800 {
+		t.Errorf("%d tokens, budget 800", n)
+	}
+
+	// The same old turns are not summarized twice.
+	again, _ := hm.Fit(context.Background(), messages, nil)
+	if len(s.transcripts) != 1 || summaries(again)[0] != "summary 1" {
+		t.Errorf("summarized %d times, want the summary reused", len(s.transcripts))
+	}
+
+	// Without a summary the old turns are still dropped.
+	fitted, err = llm.NewHistoryManager(800, &summarizer{err: errors.New("overloaded")}).Fit(context.Background(), messages, nil)
+	if err != nil || len(summaries(fitted)) != 0 || llm.EstimateRequestTokens(fitted, nil) > 800 {
+		t.Errorf("failed summary: %d messages, %v", len(fitted), err)
+	}
+}
+
+func TestFitMergesTheEarlierSummary(t *testing.T) {
+	s := &summarizer{}
+	hm := llm.NewHistoryManager(800, s)
+	ctx := context.Background()
+
+	// Each step of an agent fits the chat it got from the previous step plus the new
+	// tool call and result; the chat must hold one summary, however many steps run.
+	messages := chat(5)
+	for step := 1; step <= 4; step++ {
+		fitted, err := hm.Fit(ctx, messages, nil)
+		if err != nil {
+			t.Fatalf("step %d: %v", step, err)
+		}
+		if got := summaries(fitted); len(got) != 1 || got[0] != fmt.Sprintf("summary %d", len(s.transcripts)) {
+			t.Fatalf("step %d: summaries = %q, want the latest only", step, got)
+		}
+		if n := llm.EstimateRequestTokens(fitted, nil); n > 800 {
+			t.Errorf("step %d: %d tokens, budget 800", step, n)
+		}
+		id := fmt.Sprintf("call_%d", step)
+		messages = append(fitted,
+			llm.Message{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{{ID: id, Name: "search", Arguments: json.RawMessage(`{}`)}}},
+			llm.Message{Role: llm.RoleTool, ToolCallID: id, Content: words(150)},
+		)
+	}
+
+	if len(s.transcripts) < 2 {
+		t.Fatalf("summarized %d times, want the growing chat summarized again", len(s.transcripts))
+	}
+	// Each new summary covers the previous one and the turns dropped since.
+	for i, transcript := range s.transcripts[1:] {
+		if !strings.Contains(transcript, fmt.Sprintf("summary %d", i+1)) {
+			t.Errorf("transcript %d = %.60q, want the previous summary in it", i+2, transcript)
+		}
+	}
+}
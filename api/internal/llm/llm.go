@@ -0,0 +1,148 @@
+// Package llm talks to large language models. The assistant only needs one thing from a
+// model: given the conversation so far and the tools it may use, what does it say next,
+// and which tools does it want to call? LLMProvider is that question; each supported API
+// (OpenAI-compatible servers, Anthropic, a local Ollama) implements it.
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"gonuxt-context-assistant/internal/config"
+)
+
+// Role says who wrote a message.
+type Role string
+
+const (
+	RoleSystem    Role = "system"    // instructions for the model
+	RoleUser      Role = "user"      // the person asking
+	RoleAssistant Role = "assistant" // the model, possibly asking for tool calls
+	RoleTool      Role = "tool"      // the result of one tool call
+)
+
+// Message is one turn of a chat.
+type Message struct {
+	Role    Role   `json:"role"`
+	Content string `json:"content,omitempty"`
+
+	// ToolCalls are the tools an assistant message asks to run.
+	ToolCalls []ToolCall `json:"toolCalls,omitempty"`
+	// ToolCallID links a tool message to the call it answers.
+	ToolCallID string `json:"toolCallId,omitempty"`
+}
+
+// ToolCall is a request from the model to run one tool.
+type ToolCall struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"` // a JSON object
+}
+
+// Tool describes a tool the model may call.
+type Tool struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters"` // JSON schema of the arguments object
+}
+
+// Request is one call to the model.
+type Request struct {
+	Messages []Message
+	Tools    []Tool
+
+	// NoToolCalls tells the model not to call any of the Tools, so it has to answer. The
+	// tools are still sent: a chat holding earlier tool calls must declare them.
+	NoToolCalls bool
+
+	// OnText, when set, asks for a streamed answer: it receives each piece of text as it
+	// arrives, before Chat returns the whole message. Providers that cannot stream
+	// ignore it.
+	OnText func(text string)
+}
+
+// Why the model stopped, normalised across providers.
+const (
+	StopEnd       = "end"        // the model finished its answer
+	StopToolUse   = "tool_use"   // the model wants the ToolCalls run
+	StopMaxTokens = "max_tokens" // the answer was cut off
+)
+
+// Response is the model's next message.
+type Response struct {
+	Message    Message // always RoleAssistant
+	StopReason string  // one of the Stop constants, or what the provider sent
+	Usage      Usage   // tokens billed for this call, as reported by the provider
+}
+
+// Usage counts the tokens of one model call. Both are zero when the provider did not
+// report them.
+type Usage struct {
+	PromptTokens     int `json:"promptTokens"`
+	CompletionTokens int `json:"completionTokens"`
+}
+
+// LLMProvider is a chat model that can call tools.
+type LLMProvider interface {
+	// Chat sends the conversation and returns the model's next message.
+	Chat(ctx context.Context, req Request) (*Response, error)
+}
+
+// Kinds of API errors. An *APIError matches one of them with errors.Is, whatever the
+// provider, e.g. errors.Is(err, llm.ErrRateLimited).
+var (
+	ErrUnauthorized = errors.New("llm: unauthorized")         // 401, 403: a missing or wrong API key
+	ErrRateLimited  = errors.New("llm: rate limited")         // 429
+	ErrUnavailable  = errors.New("llm: provider unavailable") // 5xx, e.g. Anthropic's 529 "overloaded"
+)
+
+// APIError is an error answer from a provider's HTTP API. Errors reported inside a
+// streamed answer have StatusCode 200 and only their Type tells what went wrong.
+type APIError struct {
+	StatusCode int
+	Type       string // the provider's error type when it sends one, e.g. "overloaded_error"
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("llm: HTTP %d: %s", e.StatusCode, e.Message)
+}
+
+// Is matches the error kinds above by status code, or by the provider's error type.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden ||
+			e.Type == "authentication_error" || e.Type == "permission_error"
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests || e.Type == "rate_limit_error"
+	case ErrUnavailable:
+		return e.StatusCode >= 500 || e.Type == "overloaded_error" || e.Type == "api_error"
+	}
+	return false
+}
+
+// Retryable reports whether err may go away by sending the same request again later:
+// the provider was rate limiting or unavailable.
+func Retryable(err error) bool {
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrUnavailable)
+}
+
+// New creates the provider named in the configuration. Without a provider it returns
+// nil, nil: the assistant then routes queries by keyword.
+func New(cfg config.LLM) (LLMProvider, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case config.ProviderOpenAI:
+		return NewOpenAI(cfg.BaseURL, cfg.APIKey(), cfg.Model, cfg.MaxTokens), nil
+	case config.ProviderAnthropic:
+		return NewAnthropic(cfg.BaseURL, cfg.APIKey(), cfg.Model, cfg.MaxTokens), nil
+	case config.ProviderOllama:
+		return NewOllama(cfg.BaseURL, cfg.Model, cfg.MaxTokens), nil
+	}
+	return nil, fmt.Errorf("llm: unknown provider %q", cfg.Provider)
+}
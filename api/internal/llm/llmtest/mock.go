@@ -0,0 +1,340 @@
+package llmtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"gonuxt-context-assistant/internal/llm"
+)
+
+// Mock is an llm.LLMProvider that replays a Script instead of calling a model, so code
+// built on a model (the assistant's agent loop, ProcessQuery) can be tested
+// deterministically and without a server:
+//
+//	mock := llmtest.NewMock(llmtest.MustLoadScript("testdata/weather.json"))
+//	svc := assistant.NewService()
+//	svc.LLM = mock
+//	answer, status := svc.ProcessQuery(ctx, "What's the weather in Lisbon?")
+//	if err := mock.Err(); err != nil {
+//		t.Fatal(err)
+//	}
+//
+// Each call to Chat is checked against the next turn of the script. A request the script
+// does not expect fails with a *MismatchError. Callers may hide that error (the assistant
+// falls back to keyword routing), so Err reports it again, together with turns that were
+// never reached.
+type Mock struct {
+	mu       sync.Mutex
+	script   *Script
+	next     int // index of the next turn
+	failures []error
+}
+
+// Script is a conversation with a model, turn by turn. Scripts are JSON files; YAML is
+// not supported, as the module has no YAML parser:
+//
+//	{
+//	  "name": "weather in Lisbon",
+//	  "turns": [
+//	    {
+//	      "expect": {"messages": [{"role": "user", "contains": "Lisbon"}], "tools": ["get_weather"]},
+//	      "reply": {"toolCalls": [{"name": "get_weather", "arguments": {"city": "Lisbon"}}]}
+//	    },
+//	    {
+//	      "expect": {"messages": [{"role": "tool", "contains": "sunny"}]},
+//	      "reply": {"content": "It is sunny in Lisbon."}
+//	    }
+//	  ]
+//	}
+type Script struct {
+	Name  string `json:"name,omitempty"`
+	Turns []Turn `json:"turns"`
+}
+
+// Turn is one model call: what the request must look like, and the answer.
+type Turn struct {
+	Expect Expect `json:"expect"`
+	Reply  Answer `json:"reply"`
+}
+
+// Expect describes the request of a turn. Empty fields are not checked.
+type Expect struct {
+	// Messages must match the last messages of the request, in order, e.g. only the
+	// tool results of the previous turn.
+	Messages []ExpectedMessage `json:"messages,omitempty"`
+	// Tools lists the names of the tools offered, in any order. An empty list (as
+	// opposed to a missing one) expects no tools at all.
+	Tools []string `json:"tools"`
+	// NoToolCalls expects a request that forbids tool calls (llm.Request.NoToolCalls).
+	NoToolCalls bool `json:"noToolCalls,omitempty"`
+	// System, when set, must be contained in the system message.
+	System string `json:"system,omitempty"`
+}
+
+// ExpectedMessage matches one message. Content must be equal; Contains is a substring
+// and ignores case.
+type ExpectedMessage struct {
+	Role     llm.Role `json:"role"`
+	Content  string   `json:"content,omitempty"`
+	Contains string   `json:"contains,omitempty"`
+}
+
+// Answer is the scripted answer of a turn: a message, possibly calling tools, or an
+// error as a provider would return it.
+type Answer struct {
+	Content    string         `json:"content,omitempty"`
+	ToolCalls  []llm.ToolCall `json:"toolCalls,omitempty"`
+	StopReason string         `json:"stopReason,omitempty"` // defaults to end or tool_use
+	Error      string         `json:"error,omitempty"`
+	Status     int            `json:"status,omitempty"` // HTTP status of the error, default 500
+	Usage      *llm.Usage     `json:"usage,omitempty"`  // estimated from the messages when missing
+}
+
+// MismatchError is returned by Chat for a request the script did not expect.
+type MismatchError struct {
+	Script string
+	Turn   int // 1-based; beyond the last turn when the script ran out
+	Reason string
+}
+
+func (e *MismatchError) Error() string {
+	name := ""
+	if e.Script != "" {
+		name = " " + e.Script
+	}
+	return fmt.Sprintf("llmtest: script%s, turn %d: %s", name, e.Turn, e.Reason)
+}
+
+// ParseScript reads a script from JSON.
+func ParseScript(data []byte) (*Script, error) {
+	var script Script
+	if err := json.Unmarshal(data, &script); err != nil {
+		return nil, fmt.Errorf("llmtest: parse script: %w", err)
+	}
+	if len(script.Turns) == 0 {
+		return nil, errors.New("llmtest: script has no turns")
+	}
+	return &script, nil
+}
+
+// LoadScript reads a script file. The script is named after the file unless it has a name.
+func LoadScript(path string) (*Script, error) {
+	if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+		return nil, fmt.Errorf("llmtest: %s: scripts are JSON, YAML is not supported", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	script, err := ParseScript(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if script.Name == "" {
+		script.Name = path
+	}
+	return script, nil
+}
+
+// MustLoadScript is LoadScript for test setup; it panics on error.
+func MustLoadScript(path string) *Script {
+	script, err := LoadScript(path)
+	if err != nil {
+		panic(err)
+	}
+	return script
+}
+
+// RecordedScript returns a script from fixtures/scripts, e.g. "weather_lisbon.json".
+func RecordedScript(name string) *Script {
+	data, err := fixtures.ReadFile("fixtures/scripts/" + name)
+	if err != nil {
+		panic("llmtest: unknown script " + name)
+	}
+	script, err := ParseScript(data)
+	if err != nil {
+		panic(err)
+	}
+	if script.Name == "" {
+		script.Name = name
+	}
+	return script
+}
+
+// NewMock creates a provider replaying script.
+func NewMock(script *Script) *Mock {
+	return &Mock{script: script}
+}
+
+// Chat implements llm.LLMProvider. With req.OnText set the content of the answer is
+// passed to it word by word, like a streamed answer.
+func (m *Mock) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	turnNumber := m.next + 1
+	if m.next >= len(m.script.Turns) {
+		err := m.fail(turnNumber, fmt.Sprintf("unexpected request after the last turn: %s", describeLast(req.Messages)))
+		m.mu.Unlock()
+		return nil, err
+	}
+	turn := m.script.Turns[m.next]
+	m.next++
+	if reason := turn.Expect.check(req); reason != "" {
+		err := m.fail(turnNumber, reason)
+		m.mu.Unlock()
+		return nil, err
+	}
+	m.mu.Unlock()
+
+	answer := turn.Reply
+	if answer.Error != "" {
+		status := answer.Status
+		if status == 0 {
+			status = 500
+		}
+		return nil, &llm.APIError{StatusCode: status, Message: answer.Error}
+	}
+
+	resp := &llm.Response{
+		Message:    llm.Message{Role: llm.RoleAssistant, Content: answer.Content},
+		StopReason: answer.StopReason,
+	}
+	for i, call := range answer.ToolCalls {
+		if call.ID == "" {
+			call.ID = fmt.Sprintf("call_%d_%d", turnNumber, i+1)
+		}
+		if len(call.Arguments) == 0 {
+			call.Arguments = json.RawMessage("{}")
+		}
+		resp.Message.ToolCalls = append(resp.Message.ToolCalls, call)
+	}
+	if answer.Usage != nil {
+		resp.Usage = *answer.Usage
+	} else {
+		resp.Usage = llm.Usage{
+			PromptTokens:     llm.EstimateRequestTokens(req.Messages, req.Tools),
+			CompletionTokens: llm.EstimateMessageTokens(resp.Message),
+		}
+	}
+	if resp.StopReason == "" {
+		resp.StopReason = llm.StopEnd
+		if len(resp.Message.ToolCalls) > 0 {
+			resp.StopReason = llm.StopToolUse
+		}
+	}
+	if req.OnText != nil {
+		for _, word := range strings.SplitAfter(answer.Content, " ") {
+			if word != "" {
+				req.OnText(word)
+			}
+		}
+	}
+	return resp, nil
+}
+
+// Err reports the requests that did not match the script and the turns that were never
+// reached; nil means the script was played exactly.
+func (m *Mock) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	errs := append([]error(nil), m.failures...)
+	if m.next < len(m.script.Turns) {
+		errs = append(errs, &MismatchError{
+			Script: m.script.Name,
+			Turn:   m.next + 1,
+			Reason: fmt.Sprintf("turn never reached (%d of %d turns played)", m.next, len(m.script.Turns)),
+		})
+	}
+	return errors.Join(errs...)
+}
+
+// Turns returns how many turns have been played.
+func (m *Mock) Turns() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.next
+}
+
+// fail records a mismatch; m.mu must be held.
+func (m *Mock) fail(turn int, reason string) error {
+	err := &MismatchError{Script: m.script.Name, Turn: turn, Reason: reason}
+	m.failures = append(m.failures, err)
+	return err
+}
+
+// check returns why req does not match, or "".
+func (e *Expect) check(req llm.Request) string {
+	if e.System != "" {
+		system := ""
+		for _, msg := range req.Messages {
+			if msg.Role == llm.RoleSystem {
+				system += msg.Content
+			}
+		}
+		if !strings.Contains(system, e.System) {
+			return fmt.Sprintf("system prompt does not contain %q", e.System)
+		}
+	}
+
+	if len(e.Messages) > len(req.Messages) {
+		return fmt.Sprintf("expected at least %d messages, got %d", len(e.Messages), len(req.Messages))
+	}
+	tail := req.Messages[len(req.Messages)-len(e.Messages):]
+	for i, want := range e.Messages {
+		got := tail[i]
+		if want.Role != "" && got.Role != want.Role {
+			return fmt.Sprintf("message %d: expected role %s, got %s", len(req.Messages)-len(tail)+i+1, want.Role, describe(got))
+		}
+		if want.Content != "" && got.Content != want.Content {
+			return fmt.Sprintf("message %d: expected %q, got %s", len(req.Messages)-len(tail)+i+1, want.Content, describe(got))
+		}
+		if want.Contains != "" && !strings.Contains(strings.ToLower(got.Content), strings.ToLower(want.Contains)) {
+			return fmt.Sprintf("message %d: expected it to contain %q, got %s", len(req.Messages)-len(tail)+i+1, want.Contains, describe(got))
+		}
+	}
+
+	if e.Tools != nil {
+		var got []string
+		for _, tool := range req.Tools {
+			got = append(got, tool.Name)
+		}
+		want := slices.Clone(e.Tools)
+		slices.Sort(got)
+		slices.Sort(want)
+		if !slices.Equal(got, want) {
+			return fmt.Sprintf("expected tools %v, got %v", want, got)
+		}
+	}
+	if e.NoToolCalls && !req.NoToolCalls {
+		return "expected a request forbidding tool calls"
+	}
+	return ""
+}
+
+func describe(msg llm.Message) string {
+	if len(msg.ToolCalls) > 0 {
+		var names []string
+		for _, call := range msg.ToolCalls {
+			names = append(names, call.Name)
+		}
+		return fmt.Sprintf("%s calling %s", msg.Role, strings.Join(names, ", "))
+	}
+	return fmt.Sprintf("%s %q", msg.Role, msg.Content)
+}
+
+func describeLast(messages []llm.Message) string {
+	if len(messages) == 0 {
+		return "no messages"
+	}
+	return "last message is " + describe(messages[len(messages)-1])
+}
@@ -0,0 +1,474 @@
+// Package llmtest provides a local stand-in for the chat APIs of internal/llm (OpenAI
+// Chat Completions, Anthropic Messages and Ollama's /api/chat), so code built on it can be
+// exercised without network access or API keys:
+//
+//	srv := llmtest.NewServer(
+//		llmtest.ToolCalls(llm.ToolCall{ID: "1", Name: "get_weather", Arguments: json.RawMessage(`{"city":"Lisbon"}`)}),
+//		llmtest.Text("It is sunny in Lisbon."),
+//	)
+//	defer srv.Close()
+//	provider := llm.NewOpenAI(srv.URL, "", "stand-in", 0)
+//
+// Besides scripted replies it can answer with recorded API responses from fixtures/
+// (see Recorded), e.g. an Anthropic tool_use message or a streamed answer.
+package llmtest
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gonuxt-context-assistant/internal/llm"
+)
+
+// Reply is one scripted answer of the stand-in.
+type Reply struct {
+	Message      llm.Message
+	FinishReason string // "stop", "tool_calls", "length"
+	Status       int    // non-zero for an error answer
+	Error        string
+	Delay        time.Duration // wait before answering, e.g. to try out timeouts
+	Usage        llm.Usage     // token counts to report; estimated when zero
+
+	body        []byte // a recorded response, sent as is (see Recorded)
+	contentType string
+}
+
+//go:embed fixtures
+var fixtures embed.FS
+
+// Recorded answers with a recorded API response from fixtures/, e.g.
+// "anthropic/tool_use.json". Files ending in .sse are sent as an event stream, as a
+// streamed answer. Set Status on the result to send it as an error answer.
+func Recorded(name string) Reply {
+	body, err := fixtures.ReadFile("fixtures/" + name)
+	if err != nil {
+		panic("llmtest: unknown fixture " + name)
+	}
+	contentType := "application/json"
+	if strings.HasSuffix(name, ".sse") {
+		contentType = "text/event-stream"
+	}
+	return Reply{body: body, contentType: contentType}
+}
+
+// Text is a final answer.
+func Text(content string) Reply {
+	return Reply{Message: llm.Message{Role: llm.RoleAssistant, Content: content}, FinishReason: "stop"}
+}
+
+// ToolCalls asks the client to run tools.
+func ToolCalls(calls ...llm.ToolCall) Reply {
+	return Reply{Message: llm.Message{Role: llm.RoleAssistant, ToolCalls: calls}, FinishReason: "tool_calls"}
+}
+
+// Error answers with an HTTP error in the OpenAI format.
+func Error(status int, message string) Reply {
+	return Reply{Status: status, Error: message}
+}
+
+// Received is a chat request as the stand-in received it.
+type Received struct {
+	Path          string // /chat/completions, /v1/messages or /api/chat
+	Model         string
+	System        string // Anthropic's system prompt; OpenAI sends it as a message
+	Messages      []ReceivedMessage
+	ToolNames     []string
+	ToolChoice    string // "none" when tool calls were forbidden; Ollama has no tool choice
+	MaxTokens     int    // max_tokens or, for Ollama, num_predict; 0 when not sent
+	Stream        bool
+	Authorization string // Authorization or, for Anthropic, x-api-key
+}
+
+// ReceivedMessage is one message of a Received request.
+type ReceivedMessage struct {
+	Role       string
+	Content    string
+	ToolCalls  []string // names of the tools an assistant message called
+	ToolCallID string
+}
+
+// Server answers POST /chat/completions, /v1/messages and /api/chat with its replies, in
+// order; /chat/completions streams them as server-sent events when asked to, and
+// /api/chat answers in Ollama's format, streamed as JSON lines when asked to.
+// Once they run out it answers 500, so an unexpected extra call is easy to spot.
+type Server struct {
+	*httptest.Server // URL is the base URL for llm.NewOpenAI
+
+	mu       sync.Mutex
+	replies  []Reply
+	received []Received
+}
+
+// NewServer starts a stand-in that answers with replies.
+func NewServer(replies ...Reply) *Server {
+	s := &Server{replies: replies}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/chat/completions", s.chat)
+	mux.HandleFunc("/v1/messages", s.chat)
+	mux.HandleFunc("/api/chat", s.chat)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Received returns every request received so far.
+func (s *Server) Received() []Received {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Received(nil), s.received...)
+}
+
+func (s *Server) chat(w http.ResponseWriter, r *http.Request) {
+	decode := decodeOpenAI
+	switch r.URL.Path {
+	case "/v1/messages":
+		decode = decodeAnthropic
+	case "/api/chat":
+		decode = decodeOllama
+	}
+	received, err := decode(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	received.Path = r.URL.Path
+
+	s.mu.Lock()
+	s.received = append(s.received, received)
+	if len(s.replies) == 0 {
+		s.mu.Unlock()
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("llmtest: no reply scripted for request %d", len(s.received)))
+		return
+	}
+	reply := s.replies[0]
+	s.replies = s.replies[1:]
+	s.mu.Unlock()
+
+	if reply.Delay > 0 {
+		select {
+		case <-time.After(reply.Delay):
+		case <-r.Context().Done():
+			return // the client gave up
+		}
+	}
+	switch {
+	case reply.body != nil:
+		status := reply.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		w.Header().Set("Content-Type", reply.contentType)
+		w.WriteHeader(status)
+		_, _ = w.Write(reply.body)
+	case reply.Status != 0 && r.URL.Path == "/api/chat":
+		writeJSON(w, reply.Status, map[string]any{"error": reply.Error})
+	case reply.Status != 0:
+		writeError(w, reply.Status, reply.Error)
+	case r.URL.Path == "/api/chat":
+		writeOllama(w, reply, received.Stream, usageOf(reply, received))
+	case received.Stream:
+		writeCompletionStream(w, reply, usageOf(reply, received))
+	default:
+		writeJSON(w, http.StatusOK, completion(reply, usageOf(reply, received)))
+	}
+}
+
+func decodeOpenAI(r *http.Request) (Received, error) {
+	var body struct {
+		Model      string `json:"model"`
+		MaxTokens  int    `json:"max_tokens"`
+		Stream     bool   `json:"stream"`
+		ToolChoice string `json:"tool_choice"` // the string form; the object form is not used
+		Messages   []struct {
+			Role       string  `json:"role"`
+			Content    *string `json:"content"`
+			ToolCallID string  `json:"tool_call_id"`
+			ToolCalls  []struct {
+				Function struct {
+					Name string `json:"name"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"messages"`
+		Tools []struct {
+			Function struct {
+				Name string `json:"name"`
+			} `json:"function"`
+		} `json:"tools"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return Received{}, err
+	}
+
+	received := Received{Model: body.Model, MaxTokens: body.MaxTokens, Stream: body.Stream, ToolChoice: body.ToolChoice, Authorization: r.Header.Get("Authorization")}
+	for _, m := range body.Messages {
+		msg := ReceivedMessage{Role: m.Role, ToolCallID: m.ToolCallID}
+		if m.Content != nil {
+			msg.Content = *m.Content
+		}
+		for _, tc := range m.ToolCalls {
+			msg.ToolCalls = append(msg.ToolCalls, tc.Function.Name)
+		}
+		received.Messages = append(received.Messages, msg)
+	}
+	for _, tool := range body.Tools {
+		received.ToolNames = append(received.ToolNames, tool.Function.Name)
+	}
+	return received, nil
+}
+
+// decodeAnthropic reads a Messages API request. Each tool_result block is listed as a
+// message of its own with role "tool", like OpenAI sends them.
+func decodeAnthropic(r *http.Request) (Received, error) {
+	var body struct {
+		Model      string `json:"model"`
+		MaxTokens  int    `json:"max_tokens"`
+		System     string `json:"system"`
+		Stream     bool   `json:"stream"`
+		ToolChoice struct {
+			Type string `json:"type"`
+		} `json:"tool_choice"`
+		Messages []struct {
+			Role    string `json:"role"`
+			Content []struct {
+				Type      string `json:"type"`
+				Text      string `json:"text"`
+				Name      string `json:"name"`
+				ToolUseID string `json:"tool_use_id"`
+				Content   string `json:"content"`
+			} `json:"content"`
+		} `json:"messages"`
+		Tools []struct {
+			Name string `json:"name"`
+		} `json:"tools"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return Received{}, err
+	}
+
+	received := Received{Model: body.Model, MaxTokens: body.MaxTokens, System: body.System, Stream: body.Stream, ToolChoice: body.ToolChoice.Type, Authorization: r.Header.Get("x-api-key")}
+	for _, m := range body.Messages {
+		msg := ReceivedMessage{Role: m.Role}
+		for _, block := range m.Content {
+			switch block.Type {
+			case "text":
+				msg.Content += block.Text
+			case "tool_use":
+				msg.ToolCalls = append(msg.ToolCalls, block.Name)
+			case "tool_result":
+				received.Messages = append(received.Messages, ReceivedMessage{Role: "tool", Content: block.Content, ToolCallID: block.ToolUseID})
+			}
+		}
+		if msg.Content != "" || len(msg.ToolCalls) > 0 {
+			received.Messages = append(received.Messages, msg)
+		}
+	}
+	for _, tool := range body.Tools {
+		received.ToolNames = append(received.ToolNames, tool.Name)
+	}
+	return received, nil
+}
+
+// decodeOllama reads an /api/chat request. Tool messages name their tool rather than a
+// call ID; the name is listed as the ToolCallID.
+func decodeOllama(r *http.Request) (Received, error) {
+	var body struct {
+		Model   string `json:"model"`
+		Stream  *bool  `json:"stream"`
+		Options struct {
+			NumPredict int `json:"num_predict"`
+		} `json:"options"`
+		Messages []struct {
+			Role      string `json:"role"`
+			Content   string `json:"content"`
+			ToolName  string `json:"tool_name"`
+			ToolCalls []struct {
+				Function struct {
+					Name string `json:"name"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"messages"`
+		Tools []struct {
+			Function struct {
+				Name string `json:"name"`
+			} `json:"function"`
+		} `json:"tools"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return Received{}, err
+	}
+
+	// Ollama streams unless "stream": false is sent.
+	received := Received{Model: body.Model, MaxTokens: body.Options.NumPredict, Stream: body.Stream == nil || *body.Stream}
+	for _, m := range body.Messages {
+		msg := ReceivedMessage{Role: m.Role, Content: m.Content, ToolCallID: m.ToolName}
+		for _, tc := range m.ToolCalls {
+			msg.ToolCalls = append(msg.ToolCalls, tc.Function.Name)
+		}
+		received.Messages = append(received.Messages, msg)
+	}
+	for _, tool := range body.Tools {
+		received.ToolNames = append(received.ToolNames, tool.Function.Name)
+	}
+	return received, nil
+}
+
+// writeOllama renders a reply in Ollama's format: one JSON object, or when streaming one
+// line per word of the answer followed by a final "done" line.
+func writeOllama(w http.ResponseWriter, reply Reply, stream bool, usage llm.Usage) {
+	var calls []map[string]any
+	for _, call := range reply.Message.ToolCalls {
+		calls = append(calls, map[string]any{
+			"function": map[string]any{"name": call.Name, "arguments": call.Arguments},
+		})
+	}
+	doneReason := "stop"
+	if reply.FinishReason == "length" {
+		doneReason = "length"
+	}
+	line := func(content string, calls []map[string]any, done bool) map[string]any {
+		message := map[string]any{"role": "assistant", "content": content}
+		if len(calls) > 0 {
+			message["tool_calls"] = calls
+		}
+		out := map[string]any{"model": "llmtest", "message": message, "done": done}
+		if done {
+			out["done_reason"] = doneReason
+			out["prompt_eval_count"] = usage.PromptTokens
+			out["eval_count"] = usage.CompletionTokens
+		}
+		return out
+	}
+
+	if !stream {
+		writeJSON(w, http.StatusOK, line(reply.Message.Content, calls, true))
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	for i, word := range strings.SplitAfter(reply.Message.Content, " ") {
+		if word == "" {
+			continue
+		}
+		var lineCalls []map[string]any
+		if i == 0 {
+			lineCalls = calls
+		}
+		_ = enc.Encode(line(word, lineCalls, false))
+	}
+	if reply.Message.Content == "" && len(calls) > 0 {
+		_ = enc.Encode(line("", calls, false))
+	}
+	_ = enc.Encode(line("", nil, true))
+}
+
+// usageOf returns the token counts to report for reply: its own, or an estimate.
+func usageOf(reply Reply, received Received) llm.Usage {
+	if reply.Usage != (llm.Usage{}) {
+		return reply.Usage
+	}
+	usage := llm.Usage{PromptTokens: llm.EstimateTokens(received.System)}
+	for _, msg := range received.Messages {
+		usage.PromptTokens += llm.EstimateMessageTokens(llm.Message{Content: msg.Content})
+	}
+	usage.CompletionTokens = llm.EstimateMessageTokens(reply.Message)
+	return usage
+}
+
+// completion renders a reply in the Chat Completions response format.
+func completion(reply Reply, usage llm.Usage) map[string]any {
+	message := map[string]any{"role": "assistant", "content": nil}
+	if reply.Message.Content != "" {
+		message["content"] = reply.Message.Content
+	}
+	var calls []map[string]any
+	for _, call := range reply.Message.ToolCalls {
+		calls = append(calls, map[string]any{
+			"id":       call.ID,
+			"type":     "function",
+			"function": map[string]any{"name": call.Name, "arguments": string(call.Arguments)},
+		})
+	}
+	if len(calls) > 0 {
+		message["tool_calls"] = calls
+	}
+	return map[string]any{
+		"id":      "chatcmpl-llmtest",
+		"object":  "chat.completion",
+		"choices": []map[string]any{{"index": 0, "message": message, "finish_reason": reply.FinishReason}},
+		"usage": map[string]any{
+			"prompt_tokens":     usage.PromptTokens,
+			"completion_tokens": usage.CompletionTokens,
+			"total_tokens":      usage.PromptTokens + usage.CompletionTokens,
+		},
+	}
+}
+
+// writeCompletionStream renders a reply as a streamed Chat Completion: one chunk per word
+// of the answer, each tool call split over several chunks (ID and name first, then the
+// arguments in two halves), a chunk with the finish reason, then the usage and [DONE].
+func writeCompletionStream(w http.ResponseWriter, reply Reply, usage llm.Usage) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	send := func(v any) {
+		data, _ := json.Marshal(v)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+	}
+	chunk := func(delta map[string]any, finishReason any) map[string]any {
+		return map[string]any{
+			"id":      "chatcmpl-llmtest",
+			"object":  "chat.completion.chunk",
+			"choices": []map[string]any{{"index": 0, "delta": delta, "finish_reason": finishReason}},
+		}
+	}
+
+	send(chunk(map[string]any{"role": "assistant", "content": ""}, nil))
+	for _, word := range strings.SplitAfter(reply.Message.Content, " ") {
+		if word != "" {
+			send(chunk(map[string]any{"content": word}, nil))
+		}
+	}
+	for i, call := range reply.Message.ToolCalls {
+		send(chunk(map[string]any{"tool_calls": []map[string]any{{
+			"index": i, "id": call.ID, "type": "function",
+			"function": map[string]any{"name": call.Name, "arguments": ""},
+		}}}, nil))
+		args, half := string(call.Arguments), len(call.Arguments)/2
+		for half > 0 && !utf8.RuneStart(args[half]) {
+			half-- // keep characters whole, they are sent as JSON strings
+		}
+		for _, part := range []string{args[:half], args[half:]} {
+			send(chunk(map[string]any{"tool_calls": []map[string]any{{
+				"index": i, "function": map[string]any{"arguments": part},
+			}}}, nil))
+		}
+	}
+	send(chunk(map[string]any{}, reply.FinishReason))
+	send(map[string]any{
+		"id":      "chatcmpl-llmtest",
+		"object":  "chat.completion.chunk",
+		"choices": []map[string]any{},
+		"usage": map[string]any{
+			"prompt_tokens":     usage.PromptTokens,
+			"completion_tokens": usage.CompletionTokens,
+			"total_tokens":      usage.PromptTokens + usage.CompletionTokens,
+		},
+	})
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]any{"error": map[string]any{"message": message, "type": "llmtest_error"}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,239 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultOllamaURL is where a local Ollama server listens.
+const DefaultOllamaURL = "http://localhost:11434"
+
+// Ollama talks to the chat API of a local Ollama server (POST /api/chat), or of any
+// server speaking the same protocol. It needs no API key, so the assistant can use a
+// model on the developer's machine, offline.
+type Ollama struct {
+	BaseURL   string       // e.g. http://localhost:11434
+	Model     string       // e.g. llama3.1 or qwen2.5; it must support tool calling
+	MaxTokens int          // limit of each answer (num_predict); 0 leaves the model's default
+	Client    *http.Client // defaults to http.DefaultClient
+}
+
+// NewOllama creates a provider for model. An empty baseURL means DefaultOllamaURL.
+func NewOllama(baseURL, model string, maxTokens int) *Ollama {
+	if baseURL == "" {
+		baseURL = DefaultOllamaURL
+	}
+	return &Ollama{BaseURL: strings.TrimSuffix(baseURL, "/"), Model: model, MaxTokens: maxTokens}
+}
+
+// Wire format of POST /api/chat. Tools are declared like OpenAI's, but tool call
+// arguments are a JSON object instead of a string, and calls carry no IDs.
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Tools    []openAITool    `json:"tools,omitempty"`
+	Stream   bool            `json:"stream"` // Ollama streams unless told otherwise
+	Options  map[string]any  `json:"options,omitempty"`
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"` // which tool a tool message answers
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"` // a JSON object
+	} `json:"function"`
+}
+
+// ollamaChunk is the whole answer, or one line of a streamed one. The token counts come
+// with the last one.
+type ollamaChunk struct {
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+// Chat implements LLMProvider. With req.OnText set the answer is streamed.
+func (o *Ollama) Chat(ctx context.Context, req Request) (*Response, error) {
+	body, err := json.Marshal(o.buildRequest(req))
+	if err != nil {
+		return nil, fmt.Errorf("llm: encode request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, o.BaseURL+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	client := o.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("llm: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, readOllamaError(resp)
+	}
+	if req.OnText != nil {
+		return readOllamaStream(resp.Body, req.OnText)
+	}
+	var out ollamaChunk
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("llm: decode response: %w", err)
+	}
+	return parseOllamaMessage(out)
+}
+
+// buildRequest converts the chat. Tool messages name their tool instead of a call ID,
+// so the name is looked up from the assistant message that made the call.
+func (o *Ollama) buildRequest(req Request) ollamaRequest {
+	out := ollamaRequest{Model: o.Model, Stream: req.OnText != nil}
+	if o.MaxTokens > 0 {
+		out.Options = map[string]any{"num_predict": o.MaxTokens}
+	}
+	toolNames := make(map[string]string) // call ID -> tool name
+	for _, msg := range req.Messages {
+		m := ollamaMessage{Role: string(msg.Role), Content: msg.Content}
+		for _, call := range msg.ToolCalls {
+			toolNames[call.ID] = call.Name
+			var tc ollamaToolCall
+			tc.Function.Name = call.Name
+			tc.Function.Arguments = call.Arguments
+			if len(tc.Function.Arguments) == 0 {
+				tc.Function.Arguments = json.RawMessage("{}")
+			}
+			m.ToolCalls = append(m.ToolCalls, tc)
+		}
+		if msg.Role == RoleTool {
+			m.ToolName = toolNames[msg.ToolCallID]
+		}
+		out.Messages = append(out.Messages, m)
+	}
+	if req.NoToolCalls {
+		return out // Ollama has no tool_choice; without tools the model can only answer
+	}
+	for _, tool := range req.Tools {
+		out.Tools = append(out.Tools, openAITool{
+			Type:     "function",
+			Function: openAIFunction{Name: tool.Name, Description: tool.Description, Parameters: tool.Parameters},
+		})
+	}
+	return out
+}
+
+// readOllamaStream assembles a streamed answer. Each line is a JSON object holding the
+// next piece of the message; tool calls arrive whole, and the last line has "done": true.
+func readOllamaStream(body io.Reader, onText func(string)) (*Response, error) {
+	var message ollamaMessage
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var chunk ollamaChunk
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return nil, fmt.Errorf("llm: decode stream line: %w", err)
+		}
+		if chunk.Error != "" {
+			return nil, &APIError{StatusCode: http.StatusOK, Message: chunk.Error}
+		}
+		if chunk.Message.Content != "" {
+			message.Content += chunk.Message.Content
+			onText(chunk.Message.Content)
+		}
+		message.ToolCalls = append(message.ToolCalls, chunk.Message.ToolCalls...)
+		if chunk.Done {
+			chunk.Message = message
+			return parseOllamaMessage(chunk)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("llm: read stream: %w", err)
+	}
+	return nil, errors.New("llm: stream ended before the answer was done")
+}
+
+// parseOllamaMessage reads the final chunk of an answer. It gives each tool call an ID,
+// which Ollama does not send; the IDs are random so the calls of different steps of a
+// chat never share one. Ollama also reports "stop" when the model called tools, so the
+// stop reason follows the tool calls.
+func parseOllamaMessage(chunk ollamaChunk) (*Response, error) {
+	msg, doneReason := chunk.Message, chunk.DoneReason
+	out := &Response{
+		Message:    Message{Role: RoleAssistant, Content: msg.Content},
+		StopReason: doneReason,
+		Usage:      Usage{PromptTokens: chunk.PromptEvalCount, CompletionTokens: chunk.EvalCount},
+	}
+	for _, tc := range msg.ToolCalls {
+		args := tc.Function.Arguments
+		if len(bytes.TrimSpace(args)) == 0 || string(args) == "null" {
+			args = json.RawMessage("{}")
+		}
+		if !json.Valid(args) {
+			return nil, fmt.Errorf("llm: model sent invalid JSON arguments for %s: %s", tc.Function.Name, args)
+		}
+		out.Message.ToolCalls = append(out.Message.ToolCalls, ToolCall{
+			ID:        newToolCallID(),
+			Name:      tc.Function.Name,
+			Arguments: args,
+		})
+	}
+
+	switch {
+	case len(out.Message.ToolCalls) > 0:
+		out.StopReason = StopToolUse
+	case doneReason == "stop":
+		out.StopReason = StopEnd
+	case doneReason == "length":
+		out.StopReason = StopMaxTokens
+	}
+	return out, nil
+}
+
+// newToolCallID returns an ID like OpenAI's "call_..." for a call Ollama sent without one.
+func newToolCallID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		panic("llm: no randomness: " + err.Error())
+	}
+	return "call_" + hex.EncodeToString(b)
+}
+
+// readOllamaError turns an error answer into an *APIError. Ollama sends {"error": "..."}.
+func readOllamaError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var body struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &body); err == nil && body.Error != "" {
+		return &APIError{StatusCode: resp.StatusCode, Message: body.Error}
+	}
+	message := strings.TrimSpace(string(data))
+	if message == "" {
+		message = resp.Status
+	}
+	return &APIError{StatusCode: resp.StatusCode, Message: message}
+}
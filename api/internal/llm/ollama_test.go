@@ -0,0 +1,210 @@
+package llm_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"gonuxt-context-assistant/internal/llm"
+	"gonuxt-context-assistant/internal/llm/llmtest"
+)
+
+func TestOllamaAnswer(t *testing.T) {
+	reply := llmtest.Text("It is sunny in Lisbon.")
+	reply.Usage = llm.Usage{PromptTokens: 42, CompletionTokens: 7}
+	srv := llmtest.NewServer(reply)
+	defer srv.Close()
+
+	resp, err := llm.NewOllama(srv.URL+"/", "llama-test", 256).Chat(context.Background(), llm.Request{
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: "Weather in Lisbon?"}},
+		Tools:    []llm.Tool{weatherTool},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Message.Content != "It is sunny in Lisbon." || resp.Message.Role != llm.RoleAssistant || len(resp.Message.ToolCalls) != 0 {
+		t.Errorf("message = %+v", resp.Message)
+	}
+	if resp.StopReason != llm.StopEnd || resp.Usage != reply.Usage {
+		t.Errorf("stop reason = %q, usage = %+v", resp.StopReason, resp.Usage)
+	}
+
+	received := srv.Received()[0]
+	if received.Path != "/api/chat" || received.Model != "llama-test" || received.Stream || received.MaxTokens != 256 {
+		t.Errorf("request = %+v", received)
+	}
+	if !slices.Equal(received.ToolNames, []string{"get_weather"}) {
+		t.Errorf("tools = %q", received.ToolNames)
+	}
+}
+
+func TestOllamaStreamedAnswer(t *testing.T) {
+	reply := llmtest.Text("It is sunny in Lisbon.")
+	reply.Usage = llm.Usage{PromptTokens: 42, CompletionTokens: 7}
+	srv := llmtest.NewServer(reply)
+	defer srv.Close()
+
+	var pieces []string
+	resp, err := llm.NewOllama(srv.URL, "llama-test", 0).Chat(context.Background(), llm.Request{
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: "Weather in Lisbon?"}},
+		OnText:   func(text string) { pieces = append(pieces, text) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !srv.Received()[0].Stream {
+		t.Error("the request did not ask for a stream")
+	}
+	if want := []string{"It ", "is ", "sunny ", "in ", "Lisbon."}; !slices.Equal(pieces, want) {
+		t.Errorf("OnText received %q, want %q", pieces, want)
+	}
+	if resp.Message.Content != "It is sunny in Lisbon." || resp.StopReason != llm.StopEnd || resp.Usage != reply.Usage {
+		t.Errorf("response = %+v", resp)
+	}
+}
+
+func TestOllamaToolCalls(t *testing.T) {
+	for _, stream := range []bool{false, true} {
+		// Two steps of the same chat, each calling tools.
+		srv := llmtest.NewServer(
+			llmtest.ToolCalls(
+				llm.ToolCall{Name: "get_weather", Arguments: json.RawMessage(`{"city":"Lisbon"}`)},
+				llm.ToolCall{Name: "get_weather", Arguments: json.RawMessage(`{"city":"Zürich"}`)},
+			),
+			llmtest.ToolCalls(llm.ToolCall{Name: "get_weather", Arguments: json.RawMessage(`{"city":"Paris"}`)}),
+		)
+		provider := llm.NewOllama(srv.URL, "llama-test", 0)
+		req := llm.Request{
+			Messages: []llm.Message{{Role: llm.RoleUser, Content: "Lisbon, Zürich or Paris?"}},
+			Tools:    []llm.Tool{weatherTool},
+		}
+		if stream {
+			req.OnText = func(text string) { t.Errorf("OnText(%q) for an answer without text", text) }
+		}
+
+		var calls []llm.ToolCall
+		for step := range 2 {
+			resp, err := provider.Chat(context.Background(), req)
+			if err != nil {
+				t.Fatalf("stream %v, step %d: %v", stream, step, err)
+			}
+			if resp.StopReason != llm.StopToolUse || resp.Message.Content != "" {
+				t.Errorf("stream %v, step %d: stop reason = %q, content = %q", stream, step, resp.StopReason, resp.Message.Content)
+			}
+			calls = append(calls, resp.Message.ToolCalls...)
+			req.Messages = append(req.Messages, resp.Message)
+			for _, call := range resp.Message.ToolCalls {
+				req.Messages = append(req.Messages, llm.Message{Role: llm.RoleTool, ToolCallID: call.ID, Content: "sunny"})
+			}
+		}
+		srv.Close()
+
+		if len(calls) != 3 {
+			t.Fatalf("stream %v: tool calls = %+v, want 3", stream, calls)
+		}
+		ids := make(map[string]bool)
+		for i, city := range []string{"Lisbon", "Zürich", "Paris"} {
+			if calls[i].Name != "get_weather" || string(calls[i].Arguments) != `{"city":"`+city+`"}` {
+				t.Errorf("stream %v: call %d = %+v, want %s", stream, i, calls[i], city)
+			}
+			if !strings.HasPrefix(calls[i].ID, "call_") || ids[calls[i].ID] {
+				t.Errorf("stream %v: call %d has ID %q, want a new call_ ID", stream, i, calls[i].ID)
+			}
+			ids[calls[i].ID] = true
+		}
+
+		// Tool results go back with the name of their tool, which Ollama uses instead of IDs.
+		sent := srv.Received()[1].Messages
+		if len(sent) != 4 || !slices.Equal(sent[1].ToolCalls, []string{"get_weather", "get_weather"}) ||
+			sent[2].Role != "tool" || sent[2].ToolCallID != "get_weather" {
+			t.Errorf("stream %v: second request = %+v", stream, sent)
+		}
+	}
+}
+
+func TestOllamaToolCallsWithoutArguments(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"message": {"role": "assistant", "content": "", "tool_calls": [{"function": {"name": "get_current_datetime"}}]}, "done": true, "done_reason": "stop"}`))
+	}))
+	defer srv.Close()
+
+	resp, err := llm.NewOllama(srv.URL, "llama-test", 0).Chat(context.Background(), llm.Request{
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: "What time is it?"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls := resp.Message.ToolCalls; len(calls) != 1 || calls[0].Name != "get_current_datetime" || string(calls[0].Arguments) != "{}" {
+		t.Errorf("tool calls = %+v, want get_current_datetime {}", calls)
+	}
+	if resp.StopReason != llm.StopToolUse {
+		t.Errorf("stop reason = %q, want %q", resp.StopReason, llm.StopToolUse)
+	}
+}
+
+func TestOllamaErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string // what the server sends
+		message   string
+		kind      error // nil: none of the kinds
+		retryable bool
+	}{
+		{"unknown model", http.StatusNotFound, `{"error": "model \"llama-test\" not found, try pulling it first"}`, `model "llama-test" not found, try pulling it first`, nil, false},
+		{"bad request", http.StatusBadRequest, `{"error": "invalid message"}`, "invalid message", nil, false},
+		{"overloaded", http.StatusServiceUnavailable, `{"error": "server busy"}`, "server busy", llm.ErrUnavailable, true},
+		{"plain text", http.StatusInternalServerError, "upstream crashed\n", "upstream crashed", llm.ErrUnavailable, true},
+		{"empty body", http.StatusBadGateway, "", "502 Bad Gateway", llm.ErrUnavailable, true},
+		{"rate limited", http.StatusTooManyRequests, `{"error": "slow down"}`, "slow down", llm.ErrRateLimited, true},
+		{"unauthorized", http.StatusUnauthorized, `{"error": "unauthorized"}`, "unauthorized", llm.ErrUnauthorized, false},
+		// Ollama may also fail after it started streaming, with a 200 status.
+		{"in the stream", http.StatusOK, `{"message": {"content": "It "}, "done": false}` + "\n" + `{"error": "out of memory"}` + "\n", "out of memory", nil, false},
+	}
+	kinds := []error{llm.ErrUnauthorized, llm.ErrRateLimited, llm.ErrUnavailable}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			w.Write([]byte(tt.body))
+		}))
+		_, err := llm.NewOllama(srv.URL, "llama-test", 0).Chat(context.Background(), llm.Request{
+			Messages: []llm.Message{{Role: llm.RoleUser, Content: "Hello"}},
+			OnText:   func(string) {},
+		})
+		srv.Close()
+
+		var apiErr *llm.APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status || apiErr.Message != tt.message {
+			t.Errorf("%s: err = %#v, want an *APIError with status %d and message %q", tt.name, err, tt.status, tt.message)
+			continue
+		}
+		for _, kind := range kinds {
+			if got := errors.Is(err, kind); got != (kind == tt.kind) {
+				t.Errorf("%s: errors.Is(err, %v) = %v", tt.name, kind, got)
+			}
+		}
+		if got := llm.Retryable(err); got != tt.retryable {
+			t.Errorf("%s: Retryable = %v, want %v", tt.name, got, tt.retryable)
+		}
+	}
+}
+
+func TestOllamaStreamCutShort(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"message": {"role": "assistant", "content": "It is"}, "done": false}` + "\n"))
+	}))
+	defer srv.Close()
+
+	_, err := llm.NewOllama(srv.URL, "llama-test", 0).Chat(context.Background(), llm.Request{
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: "Weather in Lisbon?"}},
+		OnText:   func(string) {},
+	})
+	if err == nil {
+		t.Error("no error for a stream without a done line")
+	}
+}
@@ -0,0 +1,321 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultOpenAIURL is the base URL of the OpenAI API.
+const DefaultOpenAIURL = "https://api.openai.com/v1"
+
+// OpenAI talks to the Chat Completions API of OpenAI or of any compatible server
+// (vLLM, llama.cpp, LM Studio, Groq, ...).
+type OpenAI struct {
+	BaseURL   string       // e.g. https://api.openai.com/v1
+	APIKey    string       // sent as a bearer token; may be empty for local servers
+	Model     string       // e.g. gpt-4o-mini
+	MaxTokens int          // limit of each answer; 0 leaves the server's default
+	Client    *http.Client // defaults to http.DefaultClient
+}
+
+// NewOpenAI creates a provider for model. An empty baseURL means DefaultOpenAIURL.
+func NewOpenAI(baseURL, apiKey, model string, maxTokens int) *OpenAI {
+	if baseURL == "" {
+		baseURL = DefaultOpenAIURL
+	}
+	return &OpenAI{BaseURL: strings.TrimSuffix(baseURL, "/"), APIKey: apiKey, Model: model, MaxTokens: maxTokens}
+}
+
+// Wire format of POST /chat/completions.
+type openAIRequest struct {
+	Model      string          `json:"model"`
+	Messages   []openAIMessage `json:"messages"`
+	Tools      []openAITool    `json:"tools,omitempty"`
+	ToolChoice string          `json:"tool_choice,omitempty"` // "none" forbids tool calls
+	MaxTokens  int             `json:"max_tokens,omitempty"`
+
+	Stream        bool                 `json:"stream,omitempty"`
+	StreamOptions *openAIStreamOptions `json:"stream_options,omitempty"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"` // a last chunk with the token counts
+}
+
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    *string          `json:"content"` // null for assistant messages with only tool calls
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"` // always "function"
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"` // JSON encoded as a string
+	} `json:"function"`
+}
+
+type openAITool struct {
+	Type     string         `json:"type"` // always "function"
+	Function openAIFunction `json:"function"`
+}
+
+type openAIFunction struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message      openAIMessage `json:"message"`
+		FinishReason string        `json:"finish_reason"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// openAIChunk is one server-sent event of a streamed answer. Tool calls arrive in
+// pieces: the first delta of a call carries its ID and name, the following ones
+// fragments of the arguments, all tied together by Index.
+type openAIChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content   string `json:"content"`
+			ToolCalls []struct {
+				Index    int    `json:"index"`
+				ID       string `json:"id"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"` // only in the last chunk
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type openAIError struct {
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Chat implements LLMProvider. With req.OnText set the answer is streamed.
+func (o *OpenAI) Chat(ctx context.Context, req Request) (*Response, error) {
+	body, err := json.Marshal(o.buildRequest(req))
+	if err != nil {
+		return nil, fmt.Errorf("llm: encode request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, o.BaseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if o.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+o.APIKey)
+	}
+
+	client := o.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("llm: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, readAPIError(resp)
+	}
+	if req.OnText != nil {
+		return readOpenAIStream(resp.Body, req.OnText)
+	}
+	var out openAIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("llm: decode response: %w", err)
+	}
+	if len(out.Choices) == 0 {
+		return nil, fmt.Errorf("llm: response has no choices")
+	}
+	answer, err := parseOpenAIChoice(out.Choices[0].Message, out.Choices[0].FinishReason)
+	if err != nil {
+		return nil, err
+	}
+	answer.Usage = Usage{PromptTokens: out.Usage.PromptTokens, CompletionTokens: out.Usage.CompletionTokens}
+	return answer, nil
+}
+
+func (o *OpenAI) buildRequest(req Request) openAIRequest {
+	out := openAIRequest{Model: o.Model, MaxTokens: o.MaxTokens}
+	if req.OnText != nil {
+		out.Stream = true
+		out.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	}
+	for _, msg := range req.Messages {
+		m := openAIMessage{Role: string(msg.Role), ToolCallID: msg.ToolCallID}
+		if msg.Content != "" || len(msg.ToolCalls) == 0 {
+			content := msg.Content
+			m.Content = &content
+		}
+		for _, call := range msg.ToolCalls {
+			tc := openAIToolCall{ID: call.ID, Type: "function"}
+			tc.Function.Name = call.Name
+			tc.Function.Arguments = string(call.Arguments)
+			m.ToolCalls = append(m.ToolCalls, tc)
+		}
+		out.Messages = append(out.Messages, m)
+	}
+	for _, tool := range req.Tools {
+		out.Tools = append(out.Tools, openAITool{
+			Type:     "function",
+			Function: openAIFunction{Name: tool.Name, Description: tool.Description, Parameters: tool.Parameters},
+		})
+	}
+	if req.NoToolCalls && len(out.Tools) > 0 {
+		out.ToolChoice = "none"
+	}
+	return out
+}
+
+// readOpenAIStream assembles a streamed answer from its chunks, passing text to onText
+// as it arrives. The stream ends with "data: [DONE]".
+func readOpenAIStream(body io.Reader, onText func(string)) (*Response, error) {
+	var (
+		content      strings.Builder
+		calls        []openAIToolCall
+		finishReason string
+		usage        Usage
+	)
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue // blank lines between events, or comments
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			msg := openAIMessage{ToolCalls: calls}
+			if content.Len() > 0 {
+				text := content.String()
+				msg.Content = &text
+			}
+			resp, err := parseOpenAIChoice(msg, finishReason)
+			if err != nil {
+				return nil, err
+			}
+			resp.Usage = usage
+			return resp, nil
+		}
+		var chunk openAIChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("llm: decode stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return nil, &APIError{StatusCode: http.StatusOK, Type: chunk.Error.Type, Message: chunk.Error.Message}
+		}
+		if chunk.Usage != nil {
+			usage = Usage{PromptTokens: chunk.Usage.PromptTokens, CompletionTokens: chunk.Usage.CompletionTokens}
+		}
+		if len(chunk.Choices) == 0 {
+			continue // the usage chunk has no choices
+		}
+		choice := chunk.Choices[0]
+		if choice.Delta.Content != "" {
+			content.WriteString(choice.Delta.Content)
+			onText(choice.Delta.Content)
+		}
+		for _, delta := range choice.Delta.ToolCalls {
+			if delta.Index < 0 || delta.Index > len(calls) {
+				return nil, errors.New("llm: stream delta for an unknown tool call")
+			}
+			if delta.Index == len(calls) {
+				calls = append(calls, openAIToolCall{Type: "function"})
+			}
+			call := &calls[delta.Index]
+			if delta.ID != "" {
+				call.ID = delta.ID
+			}
+			if delta.Function.Name != "" {
+				call.Function.Name = delta.Function.Name
+			}
+			call.Function.Arguments += delta.Function.Arguments
+		}
+		if choice.FinishReason != "" {
+			finishReason = choice.FinishReason
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("llm: read stream: %w", err)
+	}
+	return nil, errors.New("llm: stream ended before [DONE]")
+}
+
+func parseOpenAIChoice(msg openAIMessage, finishReason string) (*Response, error) {
+	out := &Response{Message: Message{Role: RoleAssistant}, StopReason: finishReason}
+	if msg.Content != nil {
+		out.Message.Content = *msg.Content
+	}
+	for _, tc := range msg.ToolCalls {
+		args := strings.TrimSpace(tc.Function.Arguments)
+		if args == "" {
+			args = "{}"
+		}
+		if !json.Valid([]byte(args)) {
+			return nil, fmt.Errorf("llm: model sent invalid JSON arguments for %s: %s", tc.Function.Name, args)
+		}
+		out.Message.ToolCalls = append(out.Message.ToolCalls, ToolCall{
+			ID:        tc.ID,
+			Name:      tc.Function.Name,
+			Arguments: json.RawMessage(args),
+		})
+	}
+
+	switch finishReason {
+	case "stop":
+		out.StopReason = StopEnd
+	case "tool_calls", "function_call":
+		out.StopReason = StopToolUse
+	case "length":
+		out.StopReason = StopMaxTokens
+	}
+	return out, nil
+}
+
+// readAPIError turns an error answer into an *APIError, using the message of an
+// OpenAI-style {"error": {"message": ...}} body when there is one.
+func readAPIError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var body openAIError
+	if err := json.Unmarshal(data, &body); err == nil && body.Error.Message != "" {
+		return &APIError{StatusCode: resp.StatusCode, Type: body.Error.Type, Message: body.Error.Message}
+	}
+	message := strings.TrimSpace(string(data))
+	if message == "" {
+		message = resp.Status
+	}
+	return &APIError{StatusCode: resp.StatusCode, Message: message}
+}
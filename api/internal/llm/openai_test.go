@@ -0,0 +1,297 @@
+package llm_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"gonuxt-context-assistant/internal/llm"
+	"gonuxt-context-assistant/internal/llm/llmtest"
+)
+
+var weatherTool = llm.Tool{
+	Name:        "get_weather",
+	Description: "Current weather of a city",
+	Parameters: map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"city": map[string]any{"type": "string"}},
+		"required":   []string{"city"},
+	},
+}
+
+func TestOpenAIAnswer(t *testing.T) {
+	reply := llmtest.Text("It is sunny in Lisbon.")
+	reply.Usage = llm.Usage{PromptTokens: 42, CompletionTokens: 7}
+	srv := llmtest.NewServer(reply)
+	defer srv.Close()
+
+	provider := llm.NewOpenAI(srv.URL, "sk-test", "gpt-test", 256)
+	resp, err := provider.Chat(context.Background(), llm.Request{
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: "Weather in Lisbon?"}},
+		Tools:    []llm.Tool{weatherTool},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Message.Content != "It is sunny in Lisbon." || resp.Message.Role != llm.RoleAssistant || len(resp.Message.ToolCalls) != 0 {
+		t.Errorf("message = %+v", resp.Message)
+	}
+	if resp.StopReason != llm.StopEnd {
+		t.Errorf("stop reason = %q, want %q", resp.StopReason, llm.StopEnd)
+	}
+	if resp.Usage != reply.Usage {
+		t.Errorf("usage = %+v, want %+v", resp.Usage, reply.Usage)
+	}
+
+	received := srv.Received()[0]
+	if received.Path != "/chat/completions" || received.Model != "gpt-test" || received.Authorization != "Bearer sk-test" {
+		t.Errorf("request = %+v", received)
+	}
+	if received.MaxTokens != 256 || received.Stream {
+		t.Errorf("max_tokens = %d, stream = %v", received.MaxTokens, received.Stream)
+	}
+}
+
+func TestOpenAIParallelToolCalls(t *testing.T) {
+	srv := llmtest.NewServer(llmtest.ToolCalls(
+		llm.ToolCall{ID: "call_1", Name: "get_weather", Arguments: json.RawMessage(`{"city":"Lisbon"}`)},
+		llm.ToolCall{ID: "call_2", Name: "get_weather", Arguments: json.RawMessage(`{"city":"Paris"}`)},
+	))
+	defer srv.Close()
+
+	resp, err := llm.NewOpenAI(srv.URL, "", "gpt-test", 0).Chat(context.Background(), llm.Request{
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: "Lisbon or Paris?"}},
+		Tools:    []llm.Tool{weatherTool},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StopReason != llm.StopToolUse {
+		t.Errorf("stop reason = %q, want %q", resp.StopReason, llm.StopToolUse)
+	}
+	calls := resp.Message.ToolCalls
+	if len(calls) != 2 {
+		t.Fatalf("tool calls = %+v, want 2", calls)
+	}
+	for i, want := range []struct{ id, args string }{{"call_1", `{"city":"Lisbon"}`}, {"call_2", `{"city":"Paris"}`}} {
+		if calls[i].ID != want.id || calls[i].Name != "get_weather" || string(calls[i].Arguments) != want.args {
+			t.Errorf("call %d = %+v, want %s %s", i, calls[i], want.id, want.args)
+		}
+	}
+	if received := srv.Received()[0]; received.MaxTokens != 0 {
+		t.Errorf("max_tokens = %d, want it left out", received.MaxTokens)
+	}
+}
+
+func TestOpenAIStreamedAnswer(t *testing.T) {
+	reply := llmtest.Text("It is sunny in Lisbon.")
+	reply.Usage = llm.Usage{PromptTokens: 42, CompletionTokens: 7}
+	srv := llmtest.NewServer(reply)
+	defer srv.Close()
+
+	var pieces []string
+	resp, err := llm.NewOpenAI(srv.URL, "", "gpt-test", 0).Chat(context.Background(), llm.Request{
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: "Weather in Lisbon?"}},
+		OnText:   func(text string) { pieces = append(pieces, text) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !srv.Received()[0].Stream {
+		t.Error("the request did not ask for a stream")
+	}
+	if want := []string{"It ", "is ", "sunny ", "in ", "Lisbon."}; !slices.Equal(pieces, want) {
+		t.Errorf("OnText received %q, want %q", pieces, want)
+	}
+	if resp.Message.Content != "It is sunny in Lisbon." || resp.StopReason != llm.StopEnd || resp.Usage != reply.Usage {
+		t.Errorf("response = %+v", resp)
+	}
+}
+
+func TestOpenAIStreamedToolCalls(t *testing.T) {
+	srv := llmtest.NewServer(llmtest.ToolCalls(
+		llm.ToolCall{ID: "call_1", Name: "get_weather", Arguments: json.RawMessage(`{"city":"Lisbon"}`)},
+		llm.ToolCall{ID: "call_2", Name: "get_weather", Arguments: json.RawMessage(`{"city":"Zürich"}`)},
+	))
+	defer srv.Close()
+
+	resp, err := llm.NewOpenAI(srv.URL, "", "gpt-test", 0).Chat(context.Background(), llm.Request{
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: "Lisbon or Zürich?"}},
+		Tools:    []llm.Tool{weatherTool},
+		OnText:   func(text string) { t.Errorf("OnText(%q) for an answer without text", text) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StopReason != llm.StopToolUse || resp.Message.Content != "" {
+		t.Errorf("stop reason = %q, content = %q", resp.StopReason, resp.Message.Content)
+	}
+	calls := resp.Message.ToolCalls
+	if len(calls) != 2 {
+		t.Fatalf("tool calls = %+v, want 2", calls)
+	}
+	for i, want := range []struct{ id, args string }{{"call_1", `{"city":"Lisbon"}`}, {"call_2", `{"city":"Zürich"}`}} {
+		if calls[i].ID != want.id || calls[i].Name != "get_weather" || string(calls[i].Arguments) != want.args {
+			t.Errorf("call %d = %+v, want %s %s", i, calls[i], want.id, want.args)
+		}
+	}
+}
+
+func TestOpenAIStreamCutShort(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"choices\": [{\"delta\": {\"content\": \"It is\"}}]}\n\n"))
+	}))
+	defer srv.Close()
+
+	_, err := llm.NewOpenAI(srv.URL, "", "gpt-test", 0).Chat(context.Background(), llm.Request{
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: "Weather in Lisbon?"}},
+		OnText:   func(string) {},
+	})
+	if err == nil {
+		t.Error("no error for a stream without [DONE]")
+	}
+}
+
+func TestOpenAIErrors(t *testing.T) {
+	tests := []struct {
+		status    int
+		kind      error // nil: none of the kinds
+		retryable bool
+	}{
+		{http.StatusBadRequest, nil, false},
+		{http.StatusUnauthorized, llm.ErrUnauthorized, false},
+		{http.StatusForbidden, llm.ErrUnauthorized, false},
+		{http.StatusTooManyRequests, llm.ErrRateLimited, true},
+		{http.StatusInternalServerError, llm.ErrUnavailable, true},
+		{http.StatusServiceUnavailable, llm.ErrUnavailable, true},
+	}
+	kinds := []error{llm.ErrUnauthorized, llm.ErrRateLimited, llm.ErrUnavailable}
+	for _, tt := range tests {
+		srv := llmtest.NewServer(llmtest.Error(tt.status, "something went wrong"))
+		_, err := llm.NewOpenAI(srv.URL, "", "gpt-test", 0).Chat(context.Background(), llm.Request{
+			Messages: []llm.Message{{Role: llm.RoleUser, Content: "Hello"}},
+		})
+		srv.Close()
+
+		var apiErr *llm.APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status || apiErr.Message != "something went wrong" {
+			t.Errorf("%d: err = %#v, want an *APIError with the status and message", tt.status, err)
+			continue
+		}
+		for _, kind := range kinds {
+			if got := errors.Is(err, kind); got != (kind == tt.kind) {
+				t.Errorf("%d: errors.Is(err, %v) = %v", tt.status, kind, got)
+			}
+		}
+		if got := llm.Retryable(err); got != tt.retryable {
+			t.Errorf("%d: Retryable = %v, want %v", tt.status, got, tt.retryable)
+		}
+	}
+}
+
+func TestOpenAIRequestBody(t *testing.T) {
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "Sunny."}, "finish_reason": "stop"}]}`))
+	}))
+	defer srv.Close()
+
+	_, err := llm.NewOpenAI(srv.URL+"/", "", "gpt-test", 128).Chat(context.Background(), llm.Request{
+		Messages: []llm.Message{
+			{Role: llm.RoleSystem, Content: "Use the tools."},
+			{Role: llm.RoleUser, Content: "Weather in Lisbon?"},
+			{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{{ID: "call_1", Name: "get_weather", Arguments: json.RawMessage(`{"city":"Lisbon"}`)}}},
+			{Role: llm.RoleTool, ToolCallID: "call_1", Content: "sunny with 28°C."},
+		},
+		Tools: []llm.Tool{weatherTool},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{
+		"model": "gpt-test",
+		"max_tokens": 128,
+		"messages": [
+			{"role": "system", "content": "Use the tools."},
+			{"role": "user", "content": "Weather in Lisbon?"},
+			{"role": "assistant", "content": null, "tool_calls": [
+				{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Lisbon\"}"}}
+			]},
+			{"role": "tool", "content": "sunny with 28°C.", "tool_call_id": "call_1"}
+		],
+		"tools": [
+			{"type": "function", "function": {
+				"name": "get_weather",
+				"description": "Current weather of a city",
+				"parameters": {"type": "object", "properties": {"city": {"type": "string"}}, "required": ["city"]}
+			}}
+		]
+	}`
+	var wantBody map[string]any
+	if err := json.Unmarshal([]byte(want), &wantBody); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := json.MarshalIndent(body, "", "  ")
+	expected, _ := json.MarshalIndent(wantBody, "", "  ")
+	if string(got) != string(expected) {
+		t.Errorf("request body:\n%s\nwant:\n%s", got, expected)
+	}
+}
+
+func TestOpenAIRejectsInvalidToolArguments(t *testing.T) {
+	srv := llmtest.NewServer(llmtest.ToolCalls(llm.ToolCall{ID: "call_1", Name: "get_weather", Arguments: json.RawMessage(`{"city":`)}))
+	defer srv.Close()
+
+	_, err := llm.NewOpenAI(srv.URL, "", "gpt-test", 0).Chat(context.Background(), llm.Request{
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: "Weather in Lisbon?"}},
+	})
+	if err == nil || llm.Retryable(err) {
+		t.Errorf("err = %v, want a final error for the invalid JSON arguments", err)
+	}
+}
+
+func TestNoToolCallsKeepsTheTools(t *testing.T) {
+	tests := []struct {
+		name       string
+		reply      llmtest.Reply
+		provider   func(url string) llm.LLMProvider
+		tools      int
+		toolChoice string
+	}{
+		{"openai", llmtest.Text("Sunny."), func(url string) llm.LLMProvider { return llm.NewOpenAI(url, "", "gpt-test", 0) }, 1, "none"},
+		{"anthropic", llmtest.Recorded("anthropic/answer.json"), func(url string) llm.LLMProvider { return llm.NewAnthropic(url, "", "claude-test", 0) }, 1, "none"},
+		// Ollama has no tool_choice, so the tools are left out instead.
+		{"ollama", llmtest.Text("Sunny."), func(url string) llm.LLMProvider { return llm.NewOllama(url, "llama-test", 0) }, 0, ""},
+	}
+	for _, tt := range tests {
+		srv := llmtest.NewServer(tt.reply)
+		_, err := tt.provider(srv.URL).Chat(context.Background(), llm.Request{
+			Messages: []llm.Message{
+				{Role: llm.RoleUser, Content: "Weather in Lisbon?"},
+				{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{{ID: "call_1", Name: "get_weather", Arguments: json.RawMessage(`{"city":"Lisbon"}`)}}},
+				{Role: llm.RoleTool, ToolCallID: "call_1", Content: "sunny with 28°C."},
+			},
+			Tools:       []llm.Tool{weatherTool},
+			NoToolCalls: true,
+		})
+		srv.Close()
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		received := srv.Received()[0]
+		if len(received.ToolNames) != tt.tools || received.ToolChoice != tt.toolChoice {
+			t.Errorf("%s: tools = %v, tool_choice = %q, want %d tool(s) and %q", tt.name, received.ToolNames, received.ToolChoice, tt.tools, tt.toolChoice)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package llm
+
+import (
+	"encoding/json"
+	"unicode/utf8"
+)
+
+// Token counts are estimated rather than computed with each model's tokenizer: about four
+// characters of English text per token, plus a few tokens of framing per message. The
+// estimate errs on the high side, which is the safe side for staying within a budget.
+
+const (
+	charsPerToken     = 4
+	tokensPerMessage  = 4 // role and separators
+	tokensPerToolCall = 8 // ID, name and framing
+)
+
+// EstimateTokens estimates the tokens of a piece of text.
+func EstimateTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	return (utf8.RuneCountInString(text) + charsPerToken - 1) / charsPerToken
+}
+
+// EstimateMessageTokens estimates the tokens of one message, tool calls included.
+func EstimateMessageTokens(msg Message) int {
+	n := tokensPerMessage + EstimateTokens(msg.Content)
+	for _, call := range msg.ToolCalls {
+		n += tokensPerToolCall + EstimateTokens(call.Name) + EstimateTokens(string(call.Arguments))
+	}
+	return n
+}
+
+// EstimateRequestTokens estimates the prompt of a request: its messages and the schemas
+// of its tools.
+func EstimateRequestTokens(messages []Message, tools []Tool) int {
+	n := 0
+	for _, msg := range messages {
+		n += EstimateMessageTokens(msg)
+	}
+	for _, tool := range tools {
+		schema, _ := json.Marshal(tool.Parameters)
+		n += tokensPerToolCall + EstimateTokens(tool.Name) + EstimateTokens(tool.Description) + EstimateTokens(string(schema))
+	}
+	return n
+}
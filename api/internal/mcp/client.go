@@ -0,0 +1,342 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+)
+
+// ErrClientClosed is returned for calls on a client whose connection is gone.
+var ErrClientClosed = errors.New("mcp: client closed")
+
+// ClientTransport moves messages between a Client and one MCP server.
+type ClientTransport interface {
+	// Start connects and delivers every message received from the server to handle.
+	Start(ctx context.Context, handle func(*Message)) error
+	// Send delivers one message to the server.
+	Send(ctx context.Context, msg *Message) error
+	// Done is closed when the connection is lost (e.g. the server process exited).
+	Done() <-chan struct{}
+	// Close shuts the connection down.
+	Close() error
+}
+
+// Client is the client side of an MCP session. Create it with NewClient, then Connect.
+type Client struct {
+	// OnNotification, when set before Connect, receives every notification from the server.
+	OnNotification func(msg *Message)
+	// OnMessage, when set before Connect, sees every message exchanged with the server,
+	// e.g. to record a session. outgoing is true for messages we send.
+	OnMessage func(outgoing bool, msg *Message)
+
+	info      Implementation
+	transport ClientTransport
+
+	mu              sync.Mutex
+	nextID          int64
+	pending         map[string]chan *Message
+	serverInfo      Implementation
+	serverCaps      ServerCapabilities
+	protocolVersion string
+	instructions    string
+}
+
+// NewClient creates a client that introduces itself as name/version over the transport.
+func NewClient(name, version string, transport ClientTransport) *Client {
+	return &Client{
+		info:      Implementation{Name: name, Version: version},
+		transport: transport,
+		pending:   make(map[string]chan *Message),
+	}
+}
+
+// Connect starts the transport and performs the initialize handshake.
+func (c *Client) Connect(ctx context.Context) error {
+	if err := c.transport.Start(ctx, c.dispatch); err != nil {
+		return err
+	}
+
+	var result InitializeResult
+	err := c.call(ctx, "initialize", &InitializeParams{
+		ProtocolVersion: ProtocolVersion,
+		ClientInfo:      c.info,
+	}, &result)
+	if err != nil {
+		c.transport.Close()
+		return fmt.Errorf("mcp initialize: %w", err)
+	}
+
+	c.mu.Lock()
+	c.serverInfo = result.ServerInfo
+	c.serverCaps = result.Capabilities
+	c.protocolVersion = result.ProtocolVersion
+	c.instructions = result.Instructions
+	c.mu.Unlock()
+
+	// The HTTP transport has to echo the negotiated version on every request.
+	if t, ok := c.transport.(interface{ setProtocolVersion(string) }); ok {
+		t.setProtocolVersion(result.ProtocolVersion)
+	}
+
+	return c.notify(ctx, "notifications/initialized", nil)
+}
+
+// ServerInfo returns the name and version the server announced.
+func (c *Client) ServerInfo() Implementation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.serverInfo
+}
+
+// ServerCapabilities returns the capabilities the server announced.
+func (c *Client) ServerCapabilities() ServerCapabilities {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.serverCaps
+}
+
+// Done is closed when the connection to the server is lost.
+func (c *Client) Done() <-chan struct{} {
+	return c.transport.Done()
+}
+
+// Close shuts the connection down and fails all calls still waiting for an answer.
+func (c *Client) Close() error {
+	err := c.transport.Close()
+	c.failPending()
+	return err
+}
+
+// Ping checks that the server is still responsive.
+func (c *Client) Ping(ctx context.Context) error {
+	return c.call(ctx, "ping", nil, nil)
+}
+
+// Instructions returns the usage hint the server sent in initialize, if any.
+func (c *Client) Instructions() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.instructions
+}
+
+// ListTools returns every tool of the server, following pagination cursors.
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	return listAll[Tool](ctx, c, "tools/list", "tools")
+}
+
+// ListResources returns every concrete resource of the server.
+func (c *Client) ListResources(ctx context.Context) ([]Resource, error) {
+	return listAll[Resource](ctx, c, "resources/list", "resources")
+}
+
+// ListResourceTemplates returns every resource template of the server.
+func (c *Client) ListResourceTemplates(ctx context.Context) ([]ResourceTemplate, error) {
+	return listAll[ResourceTemplate](ctx, c, "resources/templates/list", "resourceTemplates")
+}
+
+// ReadResource reads the resource at uri.
+func (c *Client) ReadResource(ctx context.Context, uri string) (*ReadResourceResult, error) {
+	var result ReadResourceResult
+	if err := c.call(ctx, "resources/read", map[string]string{"uri": uri}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListPrompts returns every prompt of the server.
+func (c *Client) ListPrompts(ctx context.Context) ([]Prompt, error) {
+	return listAll[Prompt](ctx, c, "prompts/list", "prompts")
+}
+
+// GetPrompt renders a prompt with the given arguments.
+func (c *Client) GetPrompt(ctx context.Context, name string, args map[string]string) (*GetPromptResult, error) {
+	var result GetPromptResult
+	if err := c.call(ctx, "prompts/get", &GetPromptParams{Name: name, Arguments: args}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CallTool invokes a tool. args is marshalled as the arguments object and may be nil.
+func (c *Client) CallTool(ctx context.Context, name string, args any) (*CallToolResult, error) {
+	params := CallToolParams{Name: name}
+	if args != nil {
+		raw, err := json.Marshal(args)
+		if err != nil {
+			return nil, fmt.Errorf("marshal arguments for %s: %w", name, err)
+		}
+		params.Arguments = raw
+	}
+	var result CallToolResult
+	if err := c.call(ctx, "tools/call", &params, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SetLogLevel asks the server to send log records at level or above as
+// notifications/message (delivered to OnNotification).
+func (c *Client) SetLogLevel(ctx context.Context, level LoggingLevel) error {
+	return c.call(ctx, "logging/setLevel", &SetLevelParams{Level: level}, nil)
+}
+
+// Call sends any request and returns its raw result, for methods without a helper
+// above (e.g. resources/subscribe) or to relay messages as they are.
+func (c *Client) Call(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	var result json.RawMessage
+	if err := c.call(ctx, method, params, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// call sends a request and waits for its response, decoding the result into result
+// (which may be nil). If ctx ends first the server is told to cancel the request.
+func (c *Client) call(ctx context.Context, method string, params, result any) error {
+	c.mu.Lock()
+	c.nextID++
+	id := json.RawMessage(strconv.FormatInt(c.nextID, 10))
+	reply := make(chan *Message, 1)
+	c.pending[string(id)] = reply
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, string(id))
+		c.mu.Unlock()
+	}()
+
+	msg, err := newRequest(id, method, params)
+	if err != nil {
+		return err
+	}
+	if err := c.send(ctx, msg); err != nil {
+		return fmt.Errorf("mcp %s: %w", method, err)
+	}
+
+	select {
+	case <-ctx.Done():
+		_ = c.notify(context.Background(), "notifications/cancelled", map[string]any{
+			"requestId": id,
+			"reason":    ctx.Err().Error(),
+		})
+		return ctx.Err()
+	case <-c.transport.Done():
+		return ErrClientClosed
+	case resp, ok := <-reply:
+		if !ok {
+			return ErrClientClosed
+		}
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result != nil && len(resp.Result) > 0 {
+			if err := json.Unmarshal(resp.Result, result); err != nil {
+				return fmt.Errorf("decode %s result: %w", method, err)
+			}
+		}
+		return nil
+	}
+}
+
+func (c *Client) notify(ctx context.Context, method string, params any) error {
+	msg, err := newRequest(nil, method, params)
+	if err != nil {
+		return err
+	}
+	return c.send(ctx, msg)
+}
+
+func (c *Client) send(ctx context.Context, msg *Message) error {
+	if c.OnMessage != nil {
+		c.OnMessage(true, msg)
+	}
+	return c.transport.Send(ctx, msg)
+}
+
+// dispatch routes a message received from the server.
+func (c *Client) dispatch(msg *Message) {
+	if c.OnMessage != nil {
+		c.OnMessage(false, msg)
+	}
+	switch {
+	case msg.IsResponse():
+		c.mu.Lock()
+		if reply, ok := c.pending[string(msg.ID)]; ok {
+			select {
+			case reply <- msg:
+			default: // duplicate response, the first one wins
+			}
+		}
+		c.mu.Unlock()
+	case msg.IsNotification():
+		if c.OnNotification != nil {
+			c.OnNotification(msg)
+		}
+	case msg.IsRequest():
+		c.answerServerRequest(msg)
+	}
+}
+
+// answerServerRequest replies to requests the server sends us. Only ping is supported;
+// everything else is refused so the server does not wait forever.
+func (c *Client) answerServerRequest(msg *Message) {
+	var resp *Message
+	if msg.Method == "ping" {
+		resp = newResponse(msg.ID, struct{}{})
+	} else {
+		resp = newErrorResponse(msg.ID, NewError(CodeMethodNotFound, "client does not support %s", msg.Method))
+	}
+	go func() {
+		if err := c.send(context.Background(), resp); err != nil {
+			log.Printf("MCP client could not answer %s: %v", msg.Method, err)
+		}
+	}()
+}
+
+func (c *Client) failPending() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, reply := range c.pending {
+		close(reply)
+		delete(c.pending, id)
+	}
+}
+
+// listAll calls a paginated list method until the server stops returning a cursor.
+// field is the name of the array in each page, e.g. "tools".
+func listAll[T any](ctx context.Context, c *Client, method, field string) ([]T, error) {
+	var items []T
+	cursor := ""
+	for {
+		var page map[string]json.RawMessage
+		if err := c.call(ctx, method, cursorParams(cursor), &page); err != nil {
+			return nil, err
+		}
+		var batch []T
+		if raw, ok := page[field]; ok {
+			if err := json.Unmarshal(raw, &batch); err != nil {
+				return nil, fmt.Errorf("decode %s result: %w", method, err)
+			}
+		}
+		items = append(items, batch...)
+		cursor = ""
+		if raw, ok := page["nextCursor"]; ok {
+			_ = json.Unmarshal(raw, &cursor)
+		}
+		if cursor == "" {
+			return items, nil
+		}
+	}
+}
+
+func cursorParams(cursor string) any {
+	if cursor == "" {
+		return nil
+	}
+	return map[string]string{"cursor": cursor}
+}
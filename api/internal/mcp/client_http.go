@@ -0,0 +1,240 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// HTTPClientTransport talks to a remote server over the Streamable HTTP transport.
+// Every message is POSTed; answers come back as JSON or as an SSE stream. After the
+// session is established a GET stream is opened, when the server offers one, to receive
+// server-initiated notifications.
+type HTTPClientTransport struct {
+	URL     string            // the server's MCP endpoint
+	Headers map[string]string // extra headers, e.g. Authorization
+	Client  *http.Client      // defaults to http.DefaultClient
+
+	mu              sync.Mutex
+	handle          func(*Message)
+	sessionID       string
+	protocolVersion string
+	ctx             context.Context // lives until Close, for the GET stream and SSE readers
+	cancel          context.CancelFunc
+	done            chan struct{}
+	getStream       bool
+}
+
+// Start only records the handler; the first POST (initialize) opens the session.
+func (t *HTTPClientTransport) Start(ctx context.Context, handle func(*Message)) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.handle != nil {
+		return errors.New("mcp: transport already started")
+	}
+	t.handle = handle
+	t.ctx, t.cancel = context.WithCancel(context.Background())
+	t.done = make(chan struct{})
+	return nil
+}
+
+func (t *HTTPClientTransport) setProtocolVersion(v string) {
+	t.mu.Lock()
+	t.protocolVersion = v
+	t.mu.Unlock()
+}
+
+// Send POSTs one message. Responses and any messages streamed before them are handed to
+// the handler; for streamed answers that happens in the background.
+func (t *HTTPClientTransport) Send(ctx context.Context, msg *Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(t.ctx, http.MethodPost, t.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	t.setHeaders(req)
+
+	resp, err := t.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+
+	if id := resp.Header.Get(HeaderSessionID); id != "" {
+		t.mu.Lock()
+		t.sessionID = id
+		t.mu.Unlock()
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound && t.hasSession():
+		resp.Body.Close()
+		t.Close() // the server forgot our session; the owner has to reconnect
+		return ErrClientClosed
+	case resp.StatusCode == http.StatusAccepted || resp.StatusCode == http.StatusNoContent:
+		resp.Body.Close()
+		if msg.Method == "notifications/initialized" {
+			t.openGetStream()
+		}
+		return nil
+	case resp.StatusCode >= 300:
+		defer resp.Body.Close()
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("mcp: POST %s: %s: %s", t.URL, resp.Status, strings.TrimSpace(string(text)))
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "text/event-stream" {
+		go t.readEvents(resp.Body)
+		return nil
+	}
+
+	defer resp.Body.Close()
+	var reply Message
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return fmt.Errorf("mcp: decode response: %w", err)
+	}
+	t.handle(&reply)
+	return nil
+}
+
+// openGetStream listens for server-initiated messages. Servers may answer 405 when
+// they do not offer the stream, which is fine.
+func (t *HTTPClientTransport) openGetStream() {
+	t.mu.Lock()
+	if t.getStream {
+		t.mu.Unlock()
+		return
+	}
+	t.getStream = true
+	t.mu.Unlock()
+
+	go func() {
+		req, err := http.NewRequestWithContext(t.ctx, http.MethodGet, t.URL, nil)
+		if err != nil {
+			return
+		}
+		req.Header.Set("Accept", "text/event-stream")
+		t.setHeaders(req)
+		resp, err := t.httpClient().Do(req)
+		if err != nil {
+			return
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return
+		}
+		t.readEvents(resp.Body)
+	}()
+}
+
+// readEvents parses an SSE stream, handing each "data:" payload to the handler.
+func (t *HTTPClientTransport) readEvents(body io.ReadCloser) {
+	defer body.Close()
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRequestBytes)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			// A blank line ends the event.
+			if data.Len() > 0 {
+				var msg Message
+				if err := json.Unmarshal([]byte(data.String()), &msg); err != nil {
+					log.Printf("MCP client ignoring malformed event: %v", err)
+				} else {
+					t.handle(&msg)
+				}
+				data.Reset()
+			}
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+		// "event:", "id:" and ": comment" lines carry nothing we need.
+	}
+}
+
+// Done is closed after Close.
+func (t *HTTPClientTransport) Done() <-chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.done
+}
+
+// Close ends the session on the server (DELETE) and stops all streams.
+func (t *HTTPClientTransport) Close() error {
+	t.mu.Lock()
+	if t.done == nil {
+		t.mu.Unlock()
+		return nil
+	}
+	select {
+	case <-t.done:
+		t.mu.Unlock()
+		return nil
+	default:
+	}
+	close(t.done)
+	sessionID := t.sessionID
+	t.mu.Unlock()
+
+	t.cancel()
+	if sessionID == "" {
+		return nil
+	}
+	req, err := http.NewRequest(http.MethodDelete, t.URL, nil)
+	if err != nil {
+		return err
+	}
+	t.setHeaders(req)
+	resp, err := t.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (t *HTTPClientTransport) setHeaders(req *http.Request) {
+	for k, v := range t.Headers {
+		req.Header.Set(k, v)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.sessionID != "" {
+		req.Header.Set(HeaderSessionID, t.sessionID)
+	}
+	if t.protocolVersion != "" {
+		req.Header.Set(HeaderProtocolVersion, t.protocolVersion)
+	}
+}
+
+func (t *HTTPClientTransport) hasSession() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sessionID != ""
+}
+
+func (t *HTTPClientTransport) httpClient() *http.Client {
+	if t.Client != nil {
+		return t.Client
+	}
+	return http.DefaultClient
+}
@@ -0,0 +1,144 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// shutdownGrace is how long Close waits for the server to exit after closing its stdin.
+const shutdownGrace = 2 * time.Second
+
+// CommandTransport launches an MCP server as a subprocess and talks to it over its
+// stdin/stdout. The server's stderr is copied to our log, prefixed with Name.
+type CommandTransport struct {
+	Name    string   // used in log lines
+	Command string   // executable to run
+	Args    []string // arguments
+	Env     []string // extra "KEY=value" entries added to our own environment
+
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	done    chan struct{}
+	started bool
+}
+
+// Start launches the process. The process is not tied to ctx; it lives until Close.
+func (t *CommandTransport) Start(ctx context.Context, handle func(*Message)) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.started {
+		return errors.New("mcp: transport already started")
+	}
+
+	cmd := exec.Command(t.Command, t.Args...)
+	cmd.Env = append(os.Environ(), t.Env...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start %s: %w", t.Command, err)
+	}
+
+	t.cmd = cmd
+	t.stdin = stdin
+	t.done = make(chan struct{})
+	t.started = true
+
+	go t.copyStderr(stderr)
+	go func() {
+		reader := bufio.NewReader(stdout)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(line) > 0 {
+				var msg Message
+				if jsonErr := json.Unmarshal(line, &msg); jsonErr != nil {
+					log.Printf("[%s] ignoring non JSON-RPC output: %s", t.Name, line)
+				} else {
+					handle(&msg)
+				}
+			}
+			if err != nil {
+				break
+			}
+		}
+		// stdout closes when the process exits; reap it and report the connection as lost.
+		if err := cmd.Wait(); err != nil {
+			log.Printf("[%s] MCP server exited: %v", t.Name, err)
+		}
+		close(t.done)
+	}()
+	return nil
+}
+
+func (t *CommandTransport) copyStderr(stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		log.Printf("[%s] %s", t.Name, scanner.Text())
+	}
+}
+
+// Send writes one message as a line on the process's stdin.
+func (t *CommandTransport) Send(ctx context.Context, msg *Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.started {
+		return errors.New("mcp: transport not started")
+	}
+	select {
+	case <-t.done:
+		return ErrClientClosed
+	default:
+	}
+	_, err = t.stdin.Write(append(data, '\n'))
+	return err
+}
+
+// Done is closed once the process has exited.
+func (t *CommandTransport) Done() <-chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.done
+}
+
+// Close closes stdin, which asks a well-behaved server to exit, and kills it otherwise.
+func (t *CommandTransport) Close() error {
+	t.mu.Lock()
+	if !t.started {
+		t.mu.Unlock()
+		return nil
+	}
+	t.stdin.Close()
+	done, proc := t.done, t.cmd.Process
+	t.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-time.After(shutdownGrace):
+		_ = proc.Kill()
+		<-done
+	}
+	return nil
+}
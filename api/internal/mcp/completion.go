@@ -0,0 +1,112 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Reference types accepted by completion/complete: the spec only completes prompt
+// arguments and resource template variables.
+const (
+	RefPrompt   = "ref/prompt"
+	RefResource = "ref/resource"
+)
+
+// maxCompletionValues is the most values the spec allows in one completion result.
+const maxCompletionValues = 100
+
+// CompletionRef identifies what is being completed: a prompt by Name, or a resource
+// template by URI (the template string itself).
+type CompletionRef struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+	URI  string `json:"uri,omitempty"`
+}
+
+// CompleteParams are the params of completion/complete.
+type CompleteParams struct {
+	Ref      CompletionRef `json:"ref"`
+	Argument struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"argument"`
+	Context *struct {
+		Arguments map[string]string `json:"arguments,omitempty"`
+	} `json:"context,omitempty"`
+}
+
+// Completion holds the suggested values.
+type Completion struct {
+	Values  []string `json:"values"`
+	Total   int      `json:"total,omitempty"`
+	HasMore bool     `json:"hasMore,omitempty"`
+}
+
+// CompleteResult is the result of completion/complete.
+type CompleteResult struct {
+	Completion Completion `json:"completion"`
+}
+
+// CompleteRequest is what a Completer receives.
+type CompleteRequest struct {
+	Session *Session
+	Params  CompleteParams
+}
+
+// Completer suggests values for an argument given what has been typed so far, best first.
+type Completer func(ctx context.Context, req *CompleteRequest) ([]string, error)
+
+// AddCompletion registers the completer for one argument of a prompt or resource template.
+func (s *Server) AddCompletion(ref CompletionRef, argument string, completer Completer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.completers[completionKey(ref, argument)] = completer
+}
+
+func completionKey(ref CompletionRef, argument string) string {
+	target := ref.Name
+	if ref.Type == RefResource {
+		target = ref.URI
+	}
+	return ref.Type + " " + target + " " + argument
+}
+
+func (s *Server) hasCompletions() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.completers) > 0
+}
+
+func (s *Server) complete(ctx context.Context, ss *Session, params json.RawMessage) (any, error) {
+	var p CompleteParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+	switch p.Ref.Type {
+	case RefPrompt, RefResource:
+	default:
+		return nil, NewError(CodeInvalidParams, "unsupported completion reference type: %q", p.Ref.Type)
+	}
+
+	s.mu.RLock()
+	completer, ok := s.completers[completionKey(p.Ref, p.Argument.Name)]
+	s.mu.RUnlock()
+	if !ok {
+		// Nothing to suggest is not an error: the client simply shows no completions.
+		return &CompleteResult{Completion: Completion{Values: []string{}}}, nil
+	}
+
+	values, err := completer(ctx, &CompleteRequest{Session: ss, Params: p})
+	if err != nil {
+		return nil, err
+	}
+	completion := Completion{Values: values, Total: len(values)}
+	if completion.Values == nil {
+		completion.Values = []string{}
+	}
+	if len(values) > maxCompletionValues {
+		completion.Values = values[:maxCompletionValues]
+		completion.HasMore = true
+	}
+	return &CompleteResult{Completion: completion}, nil
+}
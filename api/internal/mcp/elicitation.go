@@ -0,0 +1,47 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrElicitationUnsupported is returned by Elicit when the client did not announce the
+// elicitation capability in initialize.
+var ErrElicitationUnsupported = errors.New("mcp: client does not support elicitation")
+
+// Actions a user can take on an elicitation request.
+const (
+	ElicitAccept  = "accept"
+	ElicitDecline = "decline"
+	ElicitCancel  = "cancel"
+)
+
+// ElicitParams are the params of elicitation/create. The requested schema must be a flat
+// object whose properties are strings, numbers, booleans or string enums.
+type ElicitParams struct {
+	Message         string  `json:"message"`
+	RequestedSchema *Schema `json:"requestedSchema"`
+}
+
+// ElicitResult is the client's answer. Content is only set when Action is ElicitAccept.
+type ElicitResult struct {
+	Action  string         `json:"action"`
+	Content map[string]any `json:"content,omitempty"`
+}
+
+// Elicit asks the user, through the client, for the values described by schema and
+// blocks until they answer or ctx ends.
+func (ss *Session) Elicit(ctx context.Context, message string, schema *Schema) (*ElicitResult, error) {
+	ss.mu.Lock()
+	supported := ss.clientCaps.Elicitation != nil
+	ss.mu.Unlock()
+	if !supported {
+		return nil, ErrElicitationUnsupported
+	}
+
+	var result ElicitResult
+	if err := ss.request(ctx, "elicitation/create", &ElicitParams{Message: message, RequestedSchema: schema}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
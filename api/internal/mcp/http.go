@@ -0,0 +1,341 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// HeaderSessionID carries the session assigned in the initialize response.
+	HeaderSessionID = "Mcp-Session-Id"
+	// HeaderProtocolVersion is sent by clients on every request after initialize.
+	HeaderProtocolVersion = "Mcp-Protocol-Version"
+
+	maxRequestBytes   = 4 << 20 // 4 MiB is plenty for a single JSON-RPC message
+	sessionIdleTTL    = 30 * time.Minute
+	streamBuffer      = 64
+	heartbeatInterval = 25 * time.Second
+)
+
+var (
+	errNoStream     = errors.New("mcp: no stream open to the client")
+	errStreamClosed = errors.New("mcp: stream already closed")
+)
+
+// HTTPHandler serves the MCP Streamable HTTP transport on a single endpoint:
+//   - POST carries one JSON-RPC message from the client. Requests are answered with JSON,
+//     or upgraded to an SSE stream when the server has something to send before the result.
+//   - GET opens an SSE stream for messages the server sends outside of any request.
+//   - DELETE ends the session.
+//
+// Sessions are created by initialize and identified by the Mcp-Session-Id header.
+type HTTPHandler struct {
+	// AllowedOrigins restricts browser callers (DNS rebinding protection). Requests without
+	// an Origin header, like those from agents and CLIs, are always accepted.
+	AllowedOrigins []string
+
+	server *Server
+
+	mu       sync.Mutex
+	sessions map[string]*httpSession
+}
+
+// httpSession adds the state of the optional GET stream to a Session.
+type httpSession struct {
+	*Session
+
+	mu       sync.Mutex
+	stream   chan *Message // nil when no GET stream is open
+	lastSeen time.Time
+}
+
+// NewHTTPHandler creates a Streamable HTTP handler for the server.
+func NewHTTPHandler(server *Server) *HTTPHandler {
+	return &HTTPHandler{
+		server:   server,
+		sessions: make(map[string]*httpSession),
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *HTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if origin := r.Header.Get("Origin"); origin != "" && !slices.Contains(h.AllowedOrigins, origin) {
+		http.Error(w, "Origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		h.handlePost(w, r)
+	case http.MethodGet:
+		h.handleGet(w, r)
+	case http.MethodDelete:
+		h.handleDelete(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "Only GET, POST and DELETE methods are allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *HTTPHandler) handlePost(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBytes))
+	if err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	var msg Message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		writeJSON(w, http.StatusBadRequest, newErrorResponse(nil, NewError(CodeParseError, "parse error: %v", err)))
+		return
+	}
+
+	var hs *httpSession
+	if msg.Method == "initialize" {
+		hs = h.newSession()
+		w.Header().Set(HeaderSessionID, hs.ID())
+	} else {
+		var status int
+		if hs, status = h.lookup(r); hs == nil {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		if v := r.Header.Get(HeaderProtocolVersion); v != "" && !slices.Contains(supportedVersions, v) {
+			http.Error(w, "Unsupported MCP protocol version: "+v, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if !msg.IsRequest() {
+		// Notifications and responses have nothing to answer.
+		hs.handle(r.Context(), &msg)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	x := &httpExchange{w: w, canStream: acceptsEventStream(r)}
+	ctx := withSender(r.Context(), func(out *Message) error {
+		err := x.send(out)
+		if errors.Is(err, errNoStream) {
+			return hs.out(out) // fall back to the GET stream, if the client opened one
+		}
+		return err
+	})
+	x.finish(hs.handle(ctx, &msg))
+}
+
+func (h *HTTPHandler) handleGet(w http.ResponseWriter, r *http.Request) {
+	if !acceptsEventStream(r) {
+		http.Error(w, "GET requires Accept: text/event-stream", http.StatusMethodNotAllowed)
+		return
+	}
+	hs, status := h.lookup(r)
+	if hs == nil {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	stream := make(chan *Message, streamBuffer)
+	hs.mu.Lock()
+	if hs.stream != nil {
+		hs.mu.Unlock()
+		http.Error(w, "A stream is already open for this session", http.StatusConflict)
+		return
+	}
+	hs.stream = stream
+	hs.mu.Unlock()
+	defer func() {
+		hs.mu.Lock()
+		hs.stream = nil
+		hs.mu.Unlock()
+	}()
+
+	startEventStream(w)
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case msg := <-stream:
+			if err := writeEvent(w, msg); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			// SSE comments keep proxies from closing an idle connection.
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flush(w)
+		}
+	}
+}
+
+func (h *HTTPHandler) handleDelete(w http.ResponseWriter, r *http.Request) {
+	hs, status := h.lookup(r)
+	if hs == nil {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	h.mu.Lock()
+	delete(h.sessions, hs.ID())
+	h.mu.Unlock()
+	hs.close()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HTTPHandler) newSession() *httpSession {
+	hs := &httpSession{lastSeen: time.Now()}
+	hs.Session = h.server.newSession(hs.sendStandalone)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// Clients are not required to DELETE their session, so drop the ones that went quiet.
+	for id, old := range h.sessions {
+		if old.idleSince() > sessionIdleTTL {
+			delete(h.sessions, id)
+			old.close()
+		}
+	}
+	h.sessions[hs.ID()] = hs
+	return hs
+}
+
+// lookup finds the session named by the request header, returning the HTTP status to
+// reply with when there is none: 400 if the header is missing, 404 if it is unknown.
+func (h *HTTPHandler) lookup(r *http.Request) (*httpSession, int) {
+	id := r.Header.Get(HeaderSessionID)
+	if id == "" {
+		return nil, http.StatusBadRequest
+	}
+	h.mu.Lock()
+	hs, ok := h.sessions[id]
+	h.mu.Unlock()
+	if !ok {
+		return nil, http.StatusNotFound
+	}
+	hs.mu.Lock()
+	hs.lastSeen = time.Now()
+	hs.mu.Unlock()
+	return hs, http.StatusOK
+}
+
+func (hs *httpSession) idleSince() time.Duration {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	if hs.stream != nil {
+		return 0 // a client holding a GET stream is still around
+	}
+	return time.Since(hs.lastSeen)
+}
+
+// sendStandalone queues a message on the GET stream. Messages are dropped when no stream
+// is open or the client does not keep up, which the spec allows for server-initiated traffic.
+func (hs *httpSession) sendStandalone(msg *Message) error {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	if hs.stream == nil {
+		return errNoStream
+	}
+	select {
+	case hs.stream <- msg:
+		return nil
+	default:
+		return fmt.Errorf("mcp: stream for session %s is full, dropping %s", hs.ID(), msg.Method)
+	}
+}
+
+// httpExchange is the response side of a single POSTed request. It starts out as a plain
+// JSON response and switches to SSE the first time the server sends something before
+// the result (progress, logs, requests to the client).
+type httpExchange struct {
+	w         http.ResponseWriter
+	canStream bool
+
+	mu        sync.Mutex
+	streaming bool
+	done      bool
+}
+
+func (x *httpExchange) send(msg *Message) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.done {
+		return errStreamClosed
+	}
+	if !x.streaming {
+		if !x.canStream {
+			return errNoStream
+		}
+		startEventStream(x.w)
+		x.streaming = true
+	}
+	return writeEvent(x.w, msg)
+}
+
+// finish writes the response. resp is nil when the client cancelled the request.
+func (x *httpExchange) finish(resp *Message) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.done = true
+
+	switch {
+	case x.streaming && resp != nil:
+		if err := writeEvent(x.w, resp); err != nil {
+			log.Printf("MCP SSE write failed: %v", err)
+		}
+	case x.streaming:
+		// Nothing left to send; returning closes the stream.
+	case resp != nil:
+		writeJSON(x.w, http.StatusOK, resp)
+	default:
+		x.w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func acceptsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+func startEventStream(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flush(w)
+}
+
+func writeEvent(w http.ResponseWriter, msg *Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: message\ndata: %s\n\n", data); err != nil {
+		return err
+	}
+	flush(w)
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, msg *Message) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(msg); err != nil {
+		log.Printf("Error encoding MCP response: %v", err)
+	}
+}
+
+func flush(w http.ResponseWriter) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,132 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const initializeBody = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"` + ProtocolVersion + `","capabilities":{},"clientInfo":{"name":"test","version":"0"}}}`
+
+// readEvents returns the messages of an SSE response body.
+func readEvents(t *testing.T, resp *http.Response) []*Message {
+	t.Helper()
+	var msgs []*Message
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var msg Message
+		if err := json.Unmarshal([]byte(data), &msg); err != nil {
+			t.Fatalf("event %q: %v", data, err)
+		}
+		msgs = append(msgs, &msg)
+	}
+	return msgs
+}
+
+func TestHTTPSessionLifecycle(t *testing.T) {
+	ts := httptest.NewServer(NewHTTPHandler(NewServer("test", "0.0.0")))
+	defer ts.Close()
+
+	resp := post(t, ts.URL, "", initializeBody)
+	sessionID := resp.Header.Get(HeaderSessionID)
+	if resp.StatusCode != http.StatusOK || sessionID == "" {
+		t.Fatalf("initialize: status %d, session %q", resp.StatusCode, sessionID)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("initialize Content-Type = %q, want plain JSON", ct)
+	}
+	var msg Message
+	if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil || string(msg.ID) != "1" || msg.Error != nil {
+		t.Fatalf("initialize response = %+v (%v)", msg, err)
+	}
+	if other := post(t, ts.URL, "", initializeBody).Header.Get(HeaderSessionID); other == sessionID {
+		t.Error("two initialize calls got the same session")
+	}
+
+	tests := []struct {
+		name      string
+		sessionID string
+		body      string
+		status    int
+	}{
+		{"notification", sessionID, `{"jsonrpc":"2.0","method":"notifications/initialized"}`, http.StatusAccepted},
+		{"request", sessionID, `{"jsonrpc":"2.0","id":2,"method":"ping"}`, http.StatusOK},
+		{"no session", "", `{"jsonrpc":"2.0","id":3,"method":"ping"}`, http.StatusBadRequest},
+		{"unknown session", "not-a-session", `{"jsonrpc":"2.0","id":4,"method":"ping"}`, http.StatusNotFound},
+		{"invalid JSON", sessionID, `{`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if resp := post(t, ts.URL, tt.sessionID, tt.body); resp.StatusCode != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, resp.StatusCode, tt.status)
+		}
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL, nil)
+	req.Header.Set(HeaderSessionID, sessionID)
+	del, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	del.Body.Close()
+	if del.StatusCode != http.StatusNoContent {
+		t.Errorf("DELETE: status = %d, want 204", del.StatusCode)
+	}
+	if resp := post(t, ts.URL, sessionID, `{"jsonrpc":"2.0","id":5,"method":"ping"}`); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("after DELETE: status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestHTTPAnswersWithAnEventStreamWhenTheServerSendsFirst(t *testing.T) {
+	server := NewServer("test", "0.0.0")
+	server.AddTool(Tool{Name: "count"}, func(ctx context.Context, req *CallToolRequest) (*CallToolResult, error) {
+		for i := 1; i <= 2; i++ {
+			if err := req.ReportProgress(ctx, float64(i), 2, ""); err != nil {
+				return nil, err
+			}
+		}
+		return TextResult("done"), nil
+	})
+	ts := httptest.NewServer(NewHTTPHandler(server))
+	defer ts.Close()
+	sessionID := post(t, ts.URL, "", initializeBody).Header.Get(HeaderSessionID)
+
+	resp := post(t, ts.URL, sessionID, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"count","_meta":{"progressToken":"p1"}}}`)
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want an event stream", ct)
+	}
+	msgs := readEvents(t, resp)
+	if len(msgs) != 3 {
+		t.Fatalf("events = %d, want 2 progress notifications and the result", len(msgs))
+	}
+	for _, msg := range msgs[:2] {
+		if msg.Method != "notifications/progress" || !strings.Contains(string(msg.Params), `"progressToken":"p1"`) {
+			t.Errorf("event = %+v, want progress for p1", msg)
+		}
+	}
+	if last := msgs[2]; string(last.ID) != "2" || last.Error != nil {
+		t.Errorf("last event = %+v, want the result", last)
+	}
+}
+
+func TestHTTPRejectsForeignOrigins(t *testing.T) {
+	h := NewHTTPHandler(NewServer("test", "0.0.0"))
+	h.AllowedOrigins = []string{"http://localhost:3000"}
+
+	for origin, status := range map[string]int{"http://localhost:3000": http.StatusOK, "http://evil.example": http.StatusForbidden} {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(initializeBody))
+		req.Header.Set("Origin", origin)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != status {
+			t.Errorf("%s: status = %d, want %d", origin, rec.Code, status)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// LoggingLevel is a syslog severity (RFC 5424) as used by logging/setLevel and
+// notifications/message.
+type LoggingLevel string
+
+// Logging levels from least to most severe.
+const (
+	LevelDebug     LoggingLevel = "debug"
+	LevelInfo      LoggingLevel = "info"
+	LevelNotice    LoggingLevel = "notice"
+	LevelWarning   LoggingLevel = "warning"
+	LevelError     LoggingLevel = "error"
+	LevelCritical  LoggingLevel = "critical"
+	LevelAlert     LoggingLevel = "alert"
+	LevelEmergency LoggingLevel = "emergency"
+)
+
+var levelSeverity = map[LoggingLevel]int{
+	LevelDebug:     0,
+	LevelInfo:      1,
+	LevelNotice:    2,
+	LevelWarning:   3,
+	LevelError:     4,
+	LevelCritical:  5,
+	LevelAlert:     6,
+	LevelEmergency: 7,
+}
+
+// Valid reports whether l is one of the levels defined above.
+func (l LoggingLevel) Valid() bool {
+	_, ok := levelSeverity[l]
+	return ok
+}
+
+// SetLevelParams are the params of logging/setLevel.
+type SetLevelParams struct {
+	Level LoggingLevel `json:"level"`
+}
+
+// LoggingMessageParams are the params of notifications/message.
+type LoggingMessageParams struct {
+	Level  LoggingLevel `json:"level"`
+	Logger string       `json:"logger,omitempty"` // e.g. "assistant" or "tools"
+	Data   any          `json:"data"`
+}
+
+// Log sends a notifications/message to every session that asked for level or anything
+// less severe with logging/setLevel. Sessions that never called it receive nothing.
+func (s *Server) Log(level LoggingLevel, logger string, data any) {
+	s.LogContext(context.Background(), level, logger, data)
+}
+
+// LogContext is Log for a message emitted while handling a request: ctx is the context
+// the handler got. The session that sent the request receives the message like progress
+// notifications, on the stream of that request (the SSE response of a Streamable HTTP
+// POST); other sessions get it on their own channel.
+func (s *Server) LogContext(ctx context.Context, level LoggingLevel, logger string, data any) {
+	origin := sessionFrom(ctx)
+	for _, ss := range s.activeSessions() {
+		if !ss.wantsLog(level) {
+			continue
+		}
+		notifyCtx := context.Background()
+		if ss == origin {
+			notifyCtx = ctx
+		}
+		// Delivery errors are dropped on purpose: logging about failed logging would
+		// only produce more of it.
+		_ = ss.Notify(notifyCtx, "notifications/message", LoggingMessageParams{Level: level, Logger: logger, Data: data})
+	}
+}
+
+// LogLevel returns the minimum level the client asked for, "" if it never did.
+func (ss *Session) LogLevel() LoggingLevel {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	return ss.logLevel
+}
+
+func (ss *Session) wantsLog(level LoggingLevel) bool {
+	min := ss.LogLevel()
+	return min != "" && levelSeverity[level] >= levelSeverity[min]
+}
+
+func (s *Server) setLogLevel(ctx context.Context, ss *Session, params json.RawMessage) (any, error) {
+	var p SetLevelParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+	if !p.Level.Valid() {
+		return nil, NewError(CodeInvalidParams, "unknown logging level: %q", p.Level)
+	}
+	ss.mu.Lock()
+	ss.logLevel = p.Level
+	ss.mu.Unlock()
+	return struct{}{}, nil
+}
@@ -0,0 +1,81 @@
+package mcp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// post sends one JSON-RPC message to an HTTP handler and returns the response.
+func post(t *testing.T, url, sessionID, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if sessionID != "" {
+		req.Header.Set(HeaderSessionID, sessionID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestLogDuringToolCallStreamsOnTheCallsResponse(t *testing.T) {
+	server := NewServer("test", "0.0.0")
+	server.AddTool(Tool{Name: "work"}, func(ctx context.Context, req *CallToolRequest) (*CallToolResult, error) {
+		server.LogContext(ctx, LevelInfo, "work", "halfway there")
+		return TextResult("done"), nil
+	})
+	ts := httptest.NewServer(NewHTTPHandler(server))
+	defer ts.Close()
+
+	resp := post(t, ts.URL, "", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"`+ProtocolVersion+`","capabilities":{},"clientInfo":{"name":"test","version":"0"}}}`)
+	sessionID := resp.Header.Get(HeaderSessionID)
+	if sessionID == "" {
+		t.Fatalf("initialize: no session, status %s", resp.Status)
+	}
+	post(t, ts.URL, sessionID, `{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	post(t, ts.URL, sessionID, `{"jsonrpc":"2.0","id":2,"method":"logging/setLevel","params":{"level":"debug"}}`)
+
+	// No GET stream is open, so the message can only arrive on the POST's own stream.
+	resp = post(t, ts.URL, sessionID, `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"work"}}`)
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		t.Fatalf("tools/call Content-Type = %q, want an event stream", ct)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	logAt := strings.Index(string(body), `"method":"notifications/message"`)
+	resultAt := strings.Index(string(body), `"id":3`)
+	if logAt < 0 || !strings.Contains(string(body), "halfway there") {
+		t.Fatalf("log message missing from the tools/call stream:\n%s", body)
+	}
+	if resultAt < logAt {
+		t.Errorf("log message came after the result:\n%s", body)
+	}
+}
+
+func TestLogWithoutRequestUsesEachSessionsChannel(t *testing.T) {
+	server := NewServer("test", "0.0.0")
+	var got []string
+	for _, level := range []LoggingLevel{LevelDebug, LevelError, ""} {
+		ss := server.newSession(func(msg *Message) error {
+			got = append(got, string(level)+" "+msg.Method)
+			return nil
+		})
+		ss.logLevel = level
+	}
+
+	server.Log(LevelWarning, "test", "careful")
+
+	if len(got) != 1 || got[0] != "debug notifications/message" {
+		t.Errorf("delivered %v, want only the debug session", got)
+	}
+}
@@ -0,0 +1,140 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Prompt describes a reusable prompt template in prompts/list.
+type Prompt struct {
+	Name        string           `json:"name"`
+	Title       string           `json:"title,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+}
+
+// PromptArgument is one value the client has to supply to prompts/get.
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// PromptMessage is one message of a rendered prompt.
+type PromptMessage struct {
+	Role    string  `json:"role"` // "user" or "assistant"
+	Content Content `json:"content"`
+}
+
+// ListPromptsResult is the result of prompts/list.
+type ListPromptsResult struct {
+	Prompts    []Prompt `json:"prompts"`
+	NextCursor string   `json:"nextCursor,omitempty"`
+}
+
+// GetPromptParams are the params of prompts/get.
+type GetPromptParams struct {
+	Name      string            `json:"name"`
+	Arguments map[string]string `json:"arguments,omitempty"`
+}
+
+// GetPromptResult is the result of prompts/get.
+type GetPromptResult struct {
+	Description string          `json:"description,omitempty"`
+	Messages    []PromptMessage `json:"messages"`
+}
+
+// GetPromptRequest is what a PromptHandler receives.
+type GetPromptRequest struct {
+	Session *Session
+	Params  GetPromptParams
+}
+
+// PromptHandler renders a prompt with the client's arguments. Required arguments are
+// checked by the server before the handler runs.
+type PromptHandler func(ctx context.Context, req *GetPromptRequest) (*GetPromptResult, error)
+
+// UserMessage is a convenience for the common single user text message.
+func UserMessage(text string) PromptMessage {
+	return PromptMessage{Role: "user", Content: TextContent(text)}
+}
+
+type serverPrompt struct {
+	prompt  Prompt
+	handler PromptHandler
+}
+
+// AddPrompt registers a prompt, replacing any previous prompt with the same name.
+func (s *Server) AddPrompt(prompt Prompt, handler PromptHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.prompts[prompt.Name]; !exists {
+		s.promptOrder = append(s.promptOrder, prompt.Name)
+	}
+	s.prompts[prompt.Name] = &serverPrompt{prompt: prompt, handler: handler}
+}
+
+// RemovePrompt unregisters a prompt. It reports whether the prompt existed.
+// Call NotifyPromptListChanged once done changing the prompt list.
+func (s *Server) RemovePrompt(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.prompts[name]; !exists {
+		return false
+	}
+	delete(s.prompts, name)
+	s.promptOrder = removeName(s.promptOrder, name)
+	return true
+}
+
+// NotifyPromptListChanged tells every session that prompts/list would now return something different.
+func (s *Server) NotifyPromptListChanged() {
+	for _, ss := range s.activeSessions() {
+		_ = ss.Notify(context.Background(), "notifications/prompts/list_changed", nil)
+	}
+}
+
+func (s *Server) hasPrompts() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.prompts) > 0
+}
+
+func (s *Server) listPrompts(ctx context.Context, ss *Session, params json.RawMessage) (any, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	prompts := make([]Prompt, 0, len(s.promptOrder))
+	for _, name := range s.promptOrder {
+		prompts = append(prompts, s.prompts[name].prompt)
+	}
+	return &ListPromptsResult{Prompts: prompts}, nil
+}
+
+func (s *Server) getPrompt(ctx context.Context, ss *Session, params json.RawMessage) (any, error) {
+	var p GetPromptParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	entry, ok := s.prompts[p.Name]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, NewError(CodeInvalidParams, "unknown prompt: %s", p.Name)
+	}
+	for _, arg := range entry.prompt.Arguments {
+		if arg.Required && p.Arguments[arg.Name] == "" {
+			return nil, NewError(CodeInvalidParams, "prompt %s requires argument %q", p.Name, arg.Name)
+		}
+	}
+
+	result, err := entry.handler(ctx, &GetPromptRequest{Session: ss, Params: p})
+	if err != nil {
+		return nil, err
+	}
+	if result.Messages == nil {
+		result.Messages = []PromptMessage{}
+	}
+	return result, nil
+}
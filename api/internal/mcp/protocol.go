@@ -0,0 +1,247 @@
+// Package mcp implements the pieces of the Model Context Protocol (MCP) that the
+// assistant needs: JSON-RPC 2.0 framing, the MCP message types and a server that
+// dispatches requests to registered tools, resources and prompts.
+//
+// The package is transport agnostic. A Server hands out a Session per connected
+// client and the transports (stdio and Streamable HTTP) only move Messages in and out of it.
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ProtocolVersion is the MCP revision implemented by this package.
+const ProtocolVersion = "2025-06-18"
+
+// supportedVersions are the revisions we accept from clients during initialize.
+// If a client asks for anything else we answer with ProtocolVersion and let it decide.
+var supportedVersions = []string{ProtocolVersion, "2025-03-26", "2024-11-05"}
+
+const jsonrpcVersion = "2.0"
+
+// Standard JSON-RPC 2.0 error codes.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Message is a single JSON-RPC 2.0 message. The same struct is used for requests,
+// notifications and responses so transports can decode without knowing what comes next:
+//   - a request has a Method and an ID
+//   - a notification has a Method but no ID
+//   - a response has an ID and either a Result or an Error
+type Message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// IsRequest reports whether the message expects a response.
+func (m *Message) IsRequest() bool { return m.Method != "" && len(m.ID) > 0 }
+
+// IsNotification reports whether the message is a one-way notification.
+func (m *Message) IsNotification() bool { return m.Method != "" && len(m.ID) == 0 }
+
+// IsResponse reports whether the message answers a previous request.
+func (m *Message) IsResponse() bool { return m.Method == "" && len(m.ID) > 0 }
+
+// Error is a JSON-RPC error object. It implements the error interface so tool
+// handlers can return it directly to control the code sent to the client.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("jsonrpc error %d: %s", e.Code, e.Message)
+}
+
+// NewError creates a JSON-RPC error with a formatted message.
+func NewError(code int, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// newRequest builds a request message, marshalling params when present.
+func newRequest(id json.RawMessage, method string, params any) (*Message, error) {
+	msg := &Message{JSONRPC: jsonrpcVersion, ID: id, Method: method}
+	if params != nil {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return nil, fmt.Errorf("marshal %s params: %w", method, err)
+		}
+		msg.Params = raw
+	}
+	return msg, nil
+}
+
+// newResponse builds a successful response for the request with the given id.
+func newResponse(id json.RawMessage, result any) *Message {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return newErrorResponse(id, NewError(CodeInternalError, "marshal result: %v", err))
+	}
+	return &Message{JSONRPC: jsonrpcVersion, ID: id, Result: raw}
+}
+
+// newErrorResponse builds an error response. A nil id is sent as JSON null, which is
+// what the spec asks for when the request id could not be read.
+func newErrorResponse(id json.RawMessage, err *Error) *Message {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return &Message{JSONRPC: jsonrpcVersion, ID: id, Error: err}
+}
+
+// --- MCP lifecycle types ---
+
+// Implementation identifies a client or server.
+type Implementation struct {
+	Name    string `json:"name"`
+	Title   string `json:"title,omitempty"`
+	Version string `json:"version"`
+}
+
+// ClientCapabilities are the optional features a client announces in initialize.
+type ClientCapabilities struct {
+	Roots       *ListChangedCapability `json:"roots,omitempty"`
+	Sampling    *struct{}              `json:"sampling,omitempty"`
+	Elicitation *struct{}              `json:"elicitation,omitempty"`
+}
+
+// ServerCapabilities are the features this server announces in initialize.
+type ServerCapabilities struct {
+	Tools       *ListChangedCapability `json:"tools,omitempty"`
+	Resources   *ResourcesCapability   `json:"resources,omitempty"`
+	Prompts     *ListChangedCapability `json:"prompts,omitempty"`
+	Completions *struct{}              `json:"completions,omitempty"`
+	Logging     *struct{}              `json:"logging,omitempty"`
+}
+
+// ListChangedCapability is shared by every capability that can send list_changed notifications.
+type ListChangedCapability struct {
+	ListChanged bool `json:"listChanged,omitempty"`
+}
+
+// InitializeParams is sent by the client as the very first request.
+type InitializeParams struct {
+	ProtocolVersion string             `json:"protocolVersion"`
+	Capabilities    ClientCapabilities `json:"capabilities"`
+	ClientInfo      Implementation     `json:"clientInfo"`
+}
+
+// InitializeResult is the server's answer to initialize.
+type InitializeResult struct {
+	ProtocolVersion string             `json:"protocolVersion"`
+	Capabilities    ServerCapabilities `json:"capabilities"`
+	ServerInfo      Implementation     `json:"serverInfo"`
+	Instructions    string             `json:"instructions,omitempty"`
+}
+
+// RequestMeta carries the optional _meta object of a request.
+type RequestMeta struct {
+	ProgressToken any `json:"progressToken,omitempty"`
+}
+
+// --- Tools ---
+
+// Schema is the subset of JSON Schema used to describe tool inputs and outputs.
+type Schema struct {
+	Type        string             `json:"type,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+	MinItems    int                `json:"minItems,omitempty"`
+}
+
+// Tool describes a callable tool in tools/list.
+// InputSchema holds a *Schema for local tools; tools discovered from other servers keep
+// whatever JSON Schema they announced, decoded as a generic map.
+type Tool struct {
+	Name         string           `json:"name"`
+	Title        string           `json:"title,omitempty"`
+	Description  string           `json:"description,omitempty"`
+	InputSchema  any              `json:"inputSchema"`
+	OutputSchema any              `json:"outputSchema,omitempty"` // describes StructuredContent
+	Annotations  *ToolAnnotations `json:"annotations,omitempty"`
+}
+
+// ToolAnnotations are hints about a tool's behaviour. Clients must treat them as
+// untrusted; they help agents decide, for example, which calls can be retried.
+type ToolAnnotations struct {
+	Title           string `json:"title,omitempty"`
+	ReadOnlyHint    *bool  `json:"readOnlyHint,omitempty"`
+	DestructiveHint *bool  `json:"destructiveHint,omitempty"`
+	IdempotentHint  *bool  `json:"idempotentHint,omitempty"`
+	OpenWorldHint   *bool  `json:"openWorldHint,omitempty"`
+}
+
+// Bool returns a pointer to b, for the optional hints in ToolAnnotations.
+func Bool(b bool) *bool { return &b }
+
+// ListToolsResult is the result of tools/list.
+type ListToolsResult struct {
+	Tools      []Tool `json:"tools"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// CallToolParams are the params of tools/call.
+type CallToolParams struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+	Meta      *RequestMeta    `json:"_meta,omitempty"`
+}
+
+// Content is one block of a tool result. Only text content is produced today.
+type Content struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+}
+
+// TextContent wraps a string in a text content block.
+func TextContent(text string) Content {
+	return Content{Type: "text", Text: text}
+}
+
+// CallToolResult is the result of tools/call.
+// Failures of the tool itself (e.g. an unknown city) are reported with IsError so the
+// model can see them; protocol problems are returned as JSON-RPC errors instead.
+// Tools that declare an OutputSchema also return StructuredContent matching it, with the
+// same information as text in Content for clients that only read text.
+type CallToolResult struct {
+	Content           []Content `json:"content"`
+	StructuredContent any       `json:"structuredContent,omitempty"`
+	IsError           bool      `json:"isError,omitempty"`
+}
+
+// TextResult is a convenience for tools that answer with a single sentence.
+func TextResult(text string) *CallToolResult {
+	return &CallToolResult{Content: []Content{TextContent(text)}}
+}
+
+// StructuredResult answers with a sentence for people plus structured data for agents.
+func StructuredResult(text string, structured any) *CallToolResult {
+	return &CallToolResult{Content: []Content{TextContent(text)}, StructuredContent: structured}
+}
+
+// ErrorResult reports a tool-level failure back to the client.
+func ErrorResult(text string) *CallToolResult {
+	return &CallToolResult{Content: []Content{TextContent(text)}, IsError: true}
+}
+
+// ProgressParams are the params of notifications/progress.
+type ProgressParams struct {
+	ProgressToken any     `json:"progressToken"`
+	Progress      float64 `json:"progress"`
+	Total         float64 `json:"total,omitempty"`
+	Message       string  `json:"message,omitempty"`
+}
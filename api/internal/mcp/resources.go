@@ -0,0 +1,310 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// CodeResourceNotFound is the MCP error code for resources/read on an unknown URI.
+const CodeResourceNotFound = -32002
+
+// ResourcesCapability is announced when the server has resources.
+type ResourcesCapability struct {
+	Subscribe   bool `json:"subscribe,omitempty"`
+	ListChanged bool `json:"listChanged,omitempty"`
+}
+
+// Resource is a concrete, readable piece of data.
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// ResourceTemplate describes a family of resources with an RFC 6570 URI template.
+// Only simple {name} expressions are supported, each matching one URI path segment.
+type ResourceTemplate struct {
+	URITemplate string `json:"uriTemplate"`
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// ResourceContents is the body of a resource returned by resources/read.
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"` // base64 for binary data
+}
+
+// ListResourcesResult is the result of resources/list.
+type ListResourcesResult struct {
+	Resources  []Resource `json:"resources"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+}
+
+// ListResourceTemplatesResult is the result of resources/templates/list.
+type ListResourceTemplatesResult struct {
+	ResourceTemplates []ResourceTemplate `json:"resourceTemplates"`
+	NextCursor        string             `json:"nextCursor,omitempty"`
+}
+
+// ReadResourceResult is the result of resources/read.
+type ReadResourceResult struct {
+	Contents []ResourceContents `json:"contents"`
+}
+
+// ReadResourceRequest is what a ResourceHandler receives.
+// Vars holds the (unescaped) template variables when the URI matched a template.
+type ReadResourceRequest struct {
+	Session *Session
+	URI     string
+	Vars    map[string]string
+}
+
+// ResourceHandler reads a resource. Return ResourceNotFound(uri) for unknown data.
+type ResourceHandler func(ctx context.Context, req *ReadResourceRequest) (*ReadResourceResult, error)
+
+// ResourceLister enumerates the concrete resources behind a template for resources/list.
+type ResourceLister func() []Resource
+
+// ResourceNotFound is the error to return when a URI does not name existing data.
+func ResourceNotFound(uri string) *Error {
+	return &Error{Code: CodeResourceNotFound, Message: "Resource not found", Data: map[string]string{"uri": uri}}
+}
+
+// TextResource is a convenience for handlers returning a single text body.
+func TextResource(uri, mimeType, text string) *ReadResourceResult {
+	return &ReadResourceResult{Contents: []ResourceContents{{URI: uri, MimeType: mimeType, Text: text}}}
+}
+
+type serverResource struct {
+	resource Resource
+	handler  ResourceHandler
+}
+
+type serverTemplate struct {
+	template ResourceTemplate
+	pattern  *regexp.Regexp
+	vars     []string
+	handler  ResourceHandler
+	list     ResourceLister
+}
+
+var templateVar = regexp.MustCompile(`\{([A-Za-z0-9_]+)\}`)
+
+// AddResource registers a static resource.
+func (s *Server) AddResource(resource Resource, handler ResourceHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resources = append(s.resources, &serverResource{resource: resource, handler: handler})
+}
+
+// AddResourceTemplate registers a resource template. list may be nil; when set, the
+// resources it returns are included in resources/list so clients can browse them.
+func (s *Server) AddResourceTemplate(template ResourceTemplate, handler ResourceHandler, list ResourceLister) {
+	// Turn "weather://city/{name}" into ^weather://city/([^/]+)$ remembering the names.
+	var vars []string
+	pattern := "^"
+	last := 0
+	for _, loc := range templateVar.FindAllStringSubmatchIndex(template.URITemplate, -1) {
+		pattern += regexp.QuoteMeta(template.URITemplate[last:loc[0]]) + `([^/?#]+)`
+		vars = append(vars, template.URITemplate[loc[2]:loc[3]])
+		last = loc[1]
+	}
+	pattern += regexp.QuoteMeta(template.URITemplate[last:]) + "$"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.templates = append(s.templates, &serverTemplate{
+		template: template,
+		pattern:  regexp.MustCompile(pattern),
+		vars:     vars,
+		handler:  handler,
+		list:     list,
+	})
+}
+
+// NotifyResourceUpdated tells every session subscribed to uri that its contents changed.
+// Each session is sent the URI the way it subscribed to it.
+func (s *Server) NotifyResourceUpdated(uri string) {
+	key := s.canonicalURI(uri)
+	for _, ss := range s.activeSessions() {
+		if subscribed, ok := ss.subscription(key); ok {
+			if err := ss.Notify(context.Background(), "notifications/resources/updated", map[string]string{"uri": subscribed}); err != nil {
+				log.Printf("MCP resource update for %s not delivered to session %s: %v", uri, ss.ID(), err)
+			}
+		}
+	}
+}
+
+// NotifyResourceListChanged tells every session that resources/list would now return
+// something different.
+func (s *Server) NotifyResourceListChanged() {
+	for _, ss := range s.activeSessions() {
+		_ = ss.Notify(context.Background(), "notifications/resources/list_changed", nil)
+	}
+}
+
+// RemoveResource unregisters the static resource with the given URI. It reports whether
+// it existed. Call NotifyResourceListChanged once done changing resources.
+func (s *Server) RemoveResource(uri string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, r := range s.resources {
+		if r.resource.URI == uri {
+			s.resources = append(s.resources[:i:i], s.resources[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveResourceTemplate unregisters the template with the given URI template.
+// It reports whether it existed.
+func (s *Server) RemoveResourceTemplate(uriTemplate string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, t := range s.templates {
+		if t.template.URITemplate == uriTemplate {
+			s.templates = append(s.templates[:i:i], s.templates[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) listResources(ctx context.Context, ss *Session, params json.RawMessage) (any, error) {
+	s.mu.RLock()
+	resources := make([]Resource, 0, len(s.resources))
+	for _, r := range s.resources {
+		resources = append(resources, r.resource)
+	}
+	templates := append([]*serverTemplate{}, s.templates...)
+	s.mu.RUnlock()
+
+	for _, t := range templates {
+		if t.list != nil {
+			resources = append(resources, t.list()...)
+		}
+	}
+	return &ListResourcesResult{Resources: resources}, nil
+}
+
+func (s *Server) listResourceTemplates(ctx context.Context, ss *Session, params json.RawMessage) (any, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	templates := make([]ResourceTemplate, 0, len(s.templates))
+	for _, t := range s.templates {
+		templates = append(templates, t.template)
+	}
+	return &ListResourceTemplatesResult{ResourceTemplates: templates}, nil
+}
+
+func (s *Server) readResource(ctx context.Context, ss *Session, params json.RawMessage) (any, error) {
+	var p struct {
+		URI string `json:"uri"`
+	}
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+	handler, vars, ok := s.resolveResource(p.URI)
+	if !ok {
+		return nil, ResourceNotFound(p.URI)
+	}
+	return handler(ctx, &ReadResourceRequest{Session: ss, URI: p.URI, Vars: vars})
+}
+
+func (s *Server) subscribeResource(ctx context.Context, ss *Session, params json.RawMessage) (any, error) {
+	var p struct {
+		URI string `json:"uri"`
+	}
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+	if _, _, ok := s.resolveResource(p.URI); !ok {
+		return nil, ResourceNotFound(p.URI)
+	}
+	ss.mu.Lock()
+	ss.subscriptions[s.canonicalURI(p.URI)] = p.URI
+	ss.mu.Unlock()
+	return struct{}{}, nil
+}
+
+func (s *Server) unsubscribeResource(ctx context.Context, ss *Session, params json.RawMessage) (any, error) {
+	var p struct {
+		URI string `json:"uri"`
+	}
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+	ss.mu.Lock()
+	delete(ss.subscriptions, s.canonicalURI(p.URI))
+	ss.mu.Unlock()
+	return struct{}{}, nil
+}
+
+// resolveResource finds the handler for a URI: static resources first, then templates
+// in registration order.
+func (s *Server) resolveResource(uri string) (ResourceHandler, map[string]string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, r := range s.resources {
+		if r.resource.URI == uri {
+			return r.handler, nil, true
+		}
+	}
+	for _, t := range s.templates {
+		match := t.pattern.FindStringSubmatch(uri)
+		if match == nil {
+			continue
+		}
+		vars := make(map[string]string, len(t.vars))
+		for i, name := range t.vars {
+			value, err := url.PathUnescape(match[i+1])
+			if err != nil {
+				value = match[i+1]
+			}
+			vars[name] = value
+		}
+		return t.handler, vars, true
+	}
+	return nil, nil, false
+}
+
+func (s *Server) hasResources() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.resources) > 0 || len(s.templates) > 0
+}
+
+func (s *Server) canonicalURI(uri string) string {
+	if s.CanonicalURI != nil {
+		return s.CanonicalURI(uri)
+	}
+	return uri
+}
+
+// subscription returns the URI the session subscribed with, by canonical URI.
+func (ss *Session) subscription(key string) (string, bool) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	uri, ok := ss.subscriptions[key]
+	return uri, ok
+}
+
+// ExpandURI fills a simple {name} URI template, escaping each value as a path segment.
+func ExpandURI(template string, vars map[string]string) string {
+	return templateVar.ReplaceAllStringFunc(template, func(expr string) string {
+		name := strings.Trim(expr, "{}")
+		return url.PathEscape(vars[name])
+	})
+}
@@ -0,0 +1,294 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+)
+
+// ToolHandler executes a tool call. Returning a *Error sends that JSON-RPC error to the
+// client; any other error is reported as an internal error.
+type ToolHandler func(ctx context.Context, req *CallToolRequest) (*CallToolResult, error)
+
+// CallToolRequest is what a ToolHandler receives for each tools/call.
+type CallToolRequest struct {
+	Session *Session
+	Params  CallToolParams
+}
+
+// Bind decodes the call arguments into v and maps decoding failures to InvalidParams.
+func (r *CallToolRequest) Bind(v any) error {
+	if len(r.Params.Arguments) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(r.Params.Arguments, v); err != nil {
+		return NewError(CodeInvalidParams, "invalid arguments for %s: %v", r.Params.Name, err)
+	}
+	return nil
+}
+
+// ReportProgress sends notifications/progress for this call when the client asked for it
+// by setting _meta.progressToken; otherwise it does nothing. ctx must be the handler's
+// context so the notification travels with the call (the SSE stream over HTTP).
+func (r *CallToolRequest) ReportProgress(ctx context.Context, progress, total float64, message string) error {
+	if r.Params.Meta == nil || r.Params.Meta.ProgressToken == nil {
+		return nil
+	}
+	return r.Session.Notify(ctx, "notifications/progress", &ProgressParams{
+		ProgressToken: r.Params.Meta.ProgressToken,
+		Progress:      progress,
+		Total:         total,
+		Message:       message,
+	})
+}
+
+// methodHandler answers a single JSON-RPC method. The returned value is marshalled as the result.
+type methodHandler func(ctx context.Context, ss *Session, params json.RawMessage) (any, error)
+
+type serverTool struct {
+	tool    Tool
+	handler ToolHandler
+}
+
+// Server holds everything a client can discover: tools, resources, prompts and completions.
+// A single Server can be shared by any number of sessions and transports.
+type Server struct {
+	// Instructions is returned to clients in initialize as a hint for the model.
+	Instructions string
+
+	// AuthorizeTool, when set, decides per request which tools the caller may use: tools
+	// it returns an error for are left out of tools/list, and calling them fails with
+	// that error. ctx carries what the transport attached, e.g. the caller's token.
+	AuthorizeTool func(ctx context.Context, tool Tool) error
+
+	// CanonicalURI, when set, maps a resource URI to the form subscriptions are kept
+	// under, for servers whose URIs are case-insensitive or have several spellings.
+	// resources/subscribe, resources/unsubscribe and NotifyResourceUpdated all go through it.
+	CanonicalURI func(uri string) string
+
+	info    Implementation
+	methods map[string]methodHandler
+
+	mu          sync.RWMutex
+	tools       map[string]*serverTool
+	toolOrder   []string
+	resources   []*serverResource
+	templates   []*serverTemplate
+	prompts     map[string]*serverPrompt
+	promptOrder []string
+	completers  map[string]Completer
+	sessions    map[*Session]struct{}
+	onClose     []func()
+}
+
+// NewServer creates a server that identifies itself with the given name and version.
+func NewServer(name, version string) *Server {
+	s := &Server{
+		info:       Implementation{Name: name, Version: version},
+		tools:      make(map[string]*serverTool),
+		prompts:    make(map[string]*serverPrompt),
+		completers: make(map[string]Completer),
+		sessions:   make(map[*Session]struct{}),
+	}
+	s.methods = map[string]methodHandler{
+		"initialize": s.initialize,
+		"ping":       s.ping,
+		"tools/list": s.listTools,
+		"tools/call": s.callTool,
+
+		"resources/list":           s.listResources,
+		"resources/templates/list": s.listResourceTemplates,
+		"resources/read":           s.readResource,
+		"resources/subscribe":      s.subscribeResource,
+		"resources/unsubscribe":    s.unsubscribeResource,
+
+		"prompts/list": s.listPrompts,
+		"prompts/get":  s.getPrompt,
+
+		"completion/complete": s.complete,
+
+		"logging/setLevel": s.setLogLevel,
+	}
+	return s
+}
+
+// AddTool registers a tool, replacing any previous tool with the same name.
+func (s *Server) AddTool(tool Tool, handler ToolHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.tools[tool.Name]; !exists {
+		s.toolOrder = append(s.toolOrder, tool.Name)
+	}
+	s.tools[tool.Name] = &serverTool{tool: tool, handler: handler}
+}
+
+// RemoveTool unregisters a tool. It reports whether the tool existed.
+// Call NotifyToolListChanged once done changing the tool list.
+func (s *Server) RemoveTool(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.tools[name]; !exists {
+		return false
+	}
+	delete(s.tools, name)
+	s.toolOrder = removeName(s.toolOrder, name)
+	return true
+}
+
+// NotifyToolListChanged tells every session that tools/list would now return something different.
+func (s *Server) NotifyToolListChanged() {
+	for _, ss := range s.activeSessions() {
+		_ = ss.Notify(context.Background(), "notifications/tools/list_changed", nil)
+	}
+}
+
+func removeName(names []string, name string) []string {
+	for i, n := range names {
+		if n == name {
+			return append(names[:i:i], names[i+1:]...)
+		}
+	}
+	return names
+}
+
+// Tools returns the registered tools in registration order.
+func (s *Server) Tools() []Tool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	tools := make([]Tool, 0, len(s.toolOrder))
+	for _, name := range s.toolOrder {
+		tools = append(tools, s.tools[name].tool)
+	}
+	return tools
+}
+
+func (s *Server) capabilities() ServerCapabilities {
+	caps := ServerCapabilities{Tools: &ListChangedCapability{ListChanged: true}, Logging: &struct{}{}}
+	if s.hasResources() {
+		caps.Resources = &ResourcesCapability{Subscribe: true, ListChanged: true}
+	}
+	if s.hasPrompts() {
+		caps.Prompts = &ListChangedCapability{ListChanged: true}
+	}
+	if s.hasCompletions() {
+		caps.Completions = &struct{}{}
+	}
+	return caps
+}
+
+// OnClose registers fn to run when the server is closed, e.g. to stop watching the data
+// it publishes.
+func (s *Server) OnClose(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onClose = append(s.onClose, fn)
+}
+
+// Close ends every session, aborting the requests still running, and runs the functions
+// registered with OnClose. Call it once the transports serving the server have stopped.
+func (s *Server) Close() {
+	for _, ss := range s.activeSessions() {
+		ss.close()
+	}
+	s.mu.Lock()
+	onClose := s.onClose
+	s.onClose = nil
+	s.mu.Unlock()
+	for _, fn := range onClose {
+		fn()
+	}
+}
+
+// activeSessions returns a snapshot of the connected sessions for broadcasting.
+func (s *Server) activeSessions() []*Session {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sessions := make([]*Session, 0, len(s.sessions))
+	for ss := range s.sessions {
+		sessions = append(sessions, ss)
+	}
+	return sessions
+}
+
+func (s *Server) initialize(ctx context.Context, ss *Session, params json.RawMessage) (any, error) {
+	var p InitializeParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	version := ProtocolVersion
+	if slices.Contains(supportedVersions, p.ProtocolVersion) {
+		version = p.ProtocolVersion
+	}
+
+	ss.mu.Lock()
+	ss.clientInfo = p.ClientInfo
+	ss.clientCaps = p.Capabilities
+	ss.protocolVersion = version
+	ss.mu.Unlock()
+
+	return &InitializeResult{
+		ProtocolVersion: version,
+		Capabilities:    s.capabilities(),
+		ServerInfo:      s.info,
+		Instructions:    s.Instructions,
+	}, nil
+}
+
+func (s *Server) ping(ctx context.Context, ss *Session, params json.RawMessage) (any, error) {
+	return struct{}{}, nil
+}
+
+func (s *Server) listTools(ctx context.Context, ss *Session, params json.RawMessage) (any, error) {
+	tools := s.Tools()
+	if s.AuthorizeTool != nil {
+		allowed := tools[:0]
+		for _, tool := range tools {
+			if s.AuthorizeTool(ctx, tool) == nil {
+				allowed = append(allowed, tool)
+			}
+		}
+		tools = allowed
+	}
+	return &ListToolsResult{Tools: tools}, nil
+}
+
+func (s *Server) callTool(ctx context.Context, ss *Session, params json.RawMessage) (any, error) {
+	var p CallToolParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	entry, ok := s.tools[p.Name]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, NewError(CodeInvalidParams, "unknown tool: %s", p.Name)
+	}
+	if s.AuthorizeTool != nil {
+		if err := s.AuthorizeTool(ctx, entry.tool); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := entry.handler(ctx, &CallToolRequest{Session: ss, Params: p})
+	if err != nil {
+		return nil, err
+	}
+	if result.Content == nil {
+		result.Content = []Content{} // the spec requires the array even when empty
+	}
+	return result, nil
+}
+
+// unmarshalParams decodes request params, treating a missing object as empty.
+func unmarshalParams(params json.RawMessage, v any) error {
+	if len(params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return NewError(CodeInvalidParams, "invalid params: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+)
+
+// serveLines runs server over stdio pipes. send writes one line; the messages the server
+// writes arrive on the returned channel.
+func serveLines(t *testing.T, server *Server) (send func(line string), recv <-chan *Message) {
+	t.Helper()
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		inW.Close()
+		outR.Close()
+	})
+	go func() {
+		_ = server.ServeStdio(ctx, inR, outW)
+		outW.Close()
+	}()
+
+	out := make(chan *Message, 16)
+	go func() {
+		defer close(out)
+		scanner := bufio.NewScanner(outR)
+		for scanner.Scan() {
+			var msg Message
+			if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+				t.Errorf("output line %q: %v", scanner.Text(), err)
+				return
+			}
+			out <- &msg
+		}
+	}()
+	send = func(line string) {
+		if _, err := io.WriteString(inW, line+"\n"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return send, out
+}
+
+func next(t *testing.T, recv <-chan *Message) *Message {
+	t.Helper()
+	select {
+	case msg, ok := <-recv:
+		if !ok {
+			t.Fatal("server closed its output")
+		}
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a message from the server")
+	}
+	return nil
+}
+
+func TestCancelledNotificationCancelsTheHandler(t *testing.T) {
+	server := NewServer("test", "0.0.0")
+	started := make(chan struct{})
+	stopped := make(chan error, 1)
+	server.AddTool(Tool{Name: "wait"}, func(ctx context.Context, req *CallToolRequest) (*CallToolResult, error) {
+		close(started)
+		<-ctx.Done()
+		stopped <- ctx.Err()
+		return TextResult("too late"), nil
+	})
+	send, recv := serveLines(t, server)
+
+	send(`{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"wait"}}`)
+	<-started
+	// A notification for another request changes nothing.
+	send(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":8}}`)
+	send(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":7,"reason":"user aborted"}}`)
+
+	select {
+	case err := <-stopped:
+		if err != context.Canceled {
+			t.Errorf("handler ctx.Err() = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the handler's context was not cancelled")
+	}
+
+	// No response is sent for the cancelled call: the next message answers the ping.
+	send(`{"jsonrpc":"2.0","id":9,"method":"ping"}`)
+	if msg := next(t, recv); string(msg.ID) != "9" {
+		t.Errorf("got %s %s %s, want only the ping response", msg.ID, msg.Method, msg.Result)
+	}
+}
+
+func TestReportProgressUsesTheProgressToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		meta   string
+		tokens []string // progressToken of each expected notification
+	}{
+		{"string token", `,"_meta":{"progressToken":"job-1"}`, []string{`"job-1"`, `"job-1"`}},
+		{"number token", `,"_meta":{"progressToken":42}`, []string{"42", "42"}},
+		{"no token", ``, nil},
+	}
+
+	for _, tt := range tests {
+		server := NewServer("test", "0.0.0")
+		server.AddTool(Tool{Name: "count"}, func(ctx context.Context, req *CallToolRequest) (*CallToolResult, error) {
+			for i := 1; i <= 2; i++ {
+				if err := req.ReportProgress(ctx, float64(i), 2, "step"); err != nil {
+					return nil, err
+				}
+			}
+			return TextResult("done"), nil
+		})
+		send, recv := serveLines(t, server)
+		send(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"count"` + tt.meta + `}}`)
+
+		for i, token := range tt.tokens {
+			msg := next(t, recv)
+			if msg.Method != "notifications/progress" {
+				t.Errorf("%s: message %d = %s %s, want notifications/progress", tt.name, i, msg.Method, msg.Result)
+				continue
+			}
+			var p struct {
+				ProgressToken json.RawMessage `json:"progressToken"`
+				Progress      float64         `json:"progress"`
+				Total         float64         `json:"total"`
+			}
+			if err := json.Unmarshal(msg.Params, &p); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if string(p.ProgressToken) != token || p.Progress != float64(i+1) || p.Total != 2 {
+				t.Errorf("%s: progress %d = %s", tt.name, i, msg.Params)
+			}
+		}
+		if msg := next(t, recv); string(msg.ID) != "1" || msg.Error != nil {
+			t.Errorf("%s: got %s %s, want the call's result", tt.name, msg.Method, msg.Params)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package mcp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// sender delivers a message to the client on the other end of a session.
+type sender func(msg *Message) error
+
+// senderKey lets a transport route messages produced while handling a request onto
+// that request's own stream (the SSE response of a Streamable HTTP POST).
+type senderKey struct{}
+
+func withSender(ctx context.Context, send sender) context.Context {
+	return context.WithValue(ctx, senderKey{}, send)
+}
+
+// sessionKey marks the context of a request with the session that sent it.
+type sessionKey struct{}
+
+// sessionFrom returns the session whose request ctx belongs to, or nil.
+func sessionFrom(ctx context.Context) *Session {
+	ss, _ := ctx.Value(sessionKey{}).(*Session)
+	return ss
+}
+
+// Session is the server-side state of one connected client.
+// Transports create a session per connection and feed every incoming message to handle.
+type Session struct {
+	id     string
+	server *Server
+	out    sender
+
+	mu              sync.Mutex
+	clientInfo      Implementation
+	clientCaps      ClientCapabilities
+	protocolVersion string
+	inflight        map[string]context.CancelFunc // request id -> cancel, for notifications/cancelled
+	subscriptions   map[string]string             // canonical URI -> URI as sent to resources/subscribe
+	nextID          int64                         // for requests we send to the client
+	pending         map[string]chan *Message      // request id -> waiting caller
+	logLevel        LoggingLevel                  // from logging/setLevel; "" sends no log messages
+}
+
+// newSession creates a session whose outgoing messages are written with send.
+// Transports must call close once the client is gone.
+func (s *Server) newSession(send sender) *Session {
+	ss := &Session{
+		id:            newSessionID(),
+		server:        s,
+		out:           send,
+		inflight:      make(map[string]context.CancelFunc),
+		subscriptions: make(map[string]string),
+		pending:       make(map[string]chan *Message),
+	}
+	s.mu.Lock()
+	s.sessions[ss] = struct{}{}
+	s.mu.Unlock()
+	return ss
+}
+
+// newSessionID returns a random, URL-safe identifier (also used as Mcp-Session-Id).
+func newSessionID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic("mcp: cannot read random bytes: " + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
+
+// ID returns the unique identifier of the session.
+func (ss *Session) ID() string { return ss.id }
+
+// ClientInfo returns the name and version the client sent in initialize.
+func (ss *Session) ClientInfo() Implementation {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	return ss.clientInfo
+}
+
+// handle processes one incoming message and returns the response to send, if any.
+// It is safe to call concurrently; transports run each request in its own goroutine.
+func (ss *Session) handle(ctx context.Context, msg *Message) *Message {
+	if msg.JSONRPC != jsonrpcVersion {
+		if msg.IsRequest() {
+			return newErrorResponse(msg.ID, NewError(CodeInvalidRequest, "jsonrpc must be %q", jsonrpcVersion))
+		}
+		return nil
+	}
+
+	switch {
+	case msg.IsNotification():
+		ss.handleNotification(msg)
+		return nil
+	case msg.IsRequest():
+		return ss.handleRequest(ctx, msg)
+	case msg.IsResponse():
+		ss.deliver(msg)
+		return nil
+	default:
+		return newErrorResponse(msg.ID, NewError(CodeInvalidRequest, "message is neither a request, notification nor response"))
+	}
+}
+
+func (ss *Session) handleRequest(ctx context.Context, msg *Message) *Message {
+	handler, ok := ss.server.methods[msg.Method]
+	if !ok {
+		return newErrorResponse(msg.ID, NewError(CodeMethodNotFound, "method not found: %s", msg.Method))
+	}
+
+	// Every request gets its own cancellable context so the client can abort it.
+	ctx, cancel := context.WithCancel(context.WithValue(ctx, sessionKey{}, ss))
+	key := string(msg.ID)
+	ss.mu.Lock()
+	ss.inflight[key] = cancel
+	ss.mu.Unlock()
+	defer cancel()
+
+	result, err := handler(ctx, ss, msg.Params)
+
+	// notifications/cancelled removes the entry; the spec says no response is sent then.
+	ss.mu.Lock()
+	_, tracked := ss.inflight[key]
+	delete(ss.inflight, key)
+	ss.mu.Unlock()
+	if !tracked {
+		return nil
+	}
+
+	if err != nil {
+		var rpcErr *Error
+		if errors.As(err, &rpcErr) {
+			return newErrorResponse(msg.ID, rpcErr)
+		}
+		log.Printf("MCP %s failed: %v", msg.Method, err)
+		return newErrorResponse(msg.ID, NewError(CodeInternalError, "%v", err))
+	}
+	return newResponse(msg.ID, result)
+}
+
+func (ss *Session) handleNotification(msg *Message) {
+	switch msg.Method {
+	case "notifications/initialized":
+		log.Printf("MCP client initialized: %s %s", ss.ClientInfo().Name, ss.ClientInfo().Version)
+	case "notifications/cancelled":
+		var p struct {
+			RequestID json.RawMessage `json:"requestId"`
+			Reason    string          `json:"reason,omitempty"`
+		}
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return
+		}
+		ss.mu.Lock()
+		cancel, ok := ss.inflight[string(p.RequestID)]
+		delete(ss.inflight, string(p.RequestID))
+		ss.mu.Unlock()
+		if ok {
+			log.Printf("MCP request %s cancelled by client: %s", p.RequestID, p.Reason)
+			cancel()
+		}
+	}
+}
+
+// Notify sends a notification to the client. While handling a request it travels on the
+// same stream as that request's response; otherwise it uses the session's own channel.
+func (ss *Session) Notify(ctx context.Context, method string, params any) error {
+	msg, err := newRequest(nil, method, params)
+	if err != nil {
+		return err
+	}
+	return ss.send(ctx, msg)
+}
+
+// request sends a request to the client and waits for the answer, decoding it into result.
+// Like Notify, inside a handler it travels on the stream of the request being handled.
+func (ss *Session) request(ctx context.Context, method string, params, result any) error {
+	ss.mu.Lock()
+	ss.nextID++
+	id := json.RawMessage(fmt.Sprintf(`"srv-%d"`, ss.nextID))
+	reply := make(chan *Message, 1)
+	ss.pending[string(id)] = reply
+	ss.mu.Unlock()
+
+	defer func() {
+		ss.mu.Lock()
+		delete(ss.pending, string(id))
+		ss.mu.Unlock()
+	}()
+
+	msg, err := newRequest(id, method, params)
+	if err != nil {
+		return err
+	}
+	if err := ss.send(ctx, msg); err != nil {
+		return fmt.Errorf("send %s: %w", method, err)
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case resp := <-reply:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result != nil && len(resp.Result) > 0 {
+			if err := json.Unmarshal(resp.Result, result); err != nil {
+				return fmt.Errorf("decode %s result: %w", method, err)
+			}
+		}
+		return nil
+	}
+}
+
+// deliver hands a response from the client to the request waiting for it.
+func (ss *Session) deliver(msg *Message) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if reply, ok := ss.pending[string(msg.ID)]; ok {
+		select {
+		case reply <- msg:
+		default: // duplicate response, the first one wins
+		}
+	}
+}
+
+func (ss *Session) send(ctx context.Context, msg *Message) error {
+	if send, ok := ctx.Value(senderKey{}).(sender); ok {
+		return send(msg)
+	}
+	return ss.out(msg)
+}
+
+// close aborts every request still running and forgets the session. Transports call it
+// when the client goes away.
+func (ss *Session) close() {
+	ss.server.mu.Lock()
+	delete(ss.server.sessions, ss)
+	ss.server.mu.Unlock()
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	for _, cancel := range ss.inflight {
+		cancel()
+	}
+}
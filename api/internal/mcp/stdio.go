@@ -0,0 +1,97 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"sync"
+)
+
+// ServeStdio runs a single MCP session over newline-delimited JSON, the stdio transport
+// used by desktop clients that launch the server as a subprocess.
+// It returns when r reaches EOF or ctx is cancelled. Anything written to w must be a
+// protocol message, so logs have to go to stderr (the default for the log package).
+func (s *Server) ServeStdio(ctx context.Context, r io.Reader, w io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var writeMu sync.Mutex
+	enc := json.NewEncoder(w) // Encode terminates every message with '\n'
+	send := func(msg *Message) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return enc.Encode(msg)
+	}
+
+	ss := s.newSession(send)
+	defer ss.close()
+
+	// Read lines in their own goroutine so a cancelled ctx does not wait for stdin.
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		reader := bufio.NewReader(r)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(bytes.TrimSpace(line)) > 0 {
+				select {
+				case lines <- line:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-readErr:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		case line := <-lines:
+			var msg Message
+			if err := json.Unmarshal(line, &msg); err != nil {
+				if err := send(newErrorResponse(nil, NewError(CodeParseError, "parse error: %v", err))); err != nil {
+					return err
+				}
+				continue
+			}
+
+			// Notifications and responses are cheap and order sensitive, handle them inline.
+			if !msg.IsRequest() {
+				if resp := ss.handle(ctx, &msg); resp != nil {
+					if err := send(resp); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+
+			// Requests run concurrently so a slow tool does not block pings or cancellations.
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if resp := ss.handle(ctx, &msg); resp != nil {
+					if err := send(resp); err != nil {
+						log.Printf("MCP stdio write failed: %v", err)
+					}
+				}
+			}()
+		}
+	}
+}
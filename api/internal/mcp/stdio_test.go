@@ -0,0 +1,84 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestServeStdioFramesMessagesByLine(t *testing.T) {
+	server := NewServer("test", "0.0.0")
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- server.ServeStdio(context.Background(), inR, outW)
+		outW.Close()
+	}()
+
+	go func() {
+		// Two messages in one write, a blank line, a message split over two writes and
+		// a line that is not JSON.
+		for _, chunk := range []string{
+			`{"jsonrpc":"2.0","id":1,"method":"ping"}` + "\n" + `{"jsonrpc":"2.0","id":2,"method":"ping"}` + "\n\n",
+			`{"jsonrpc":"2.0","id":3,`,
+			`"method":"ping"}` + "\n",
+			"not json\n",
+		} {
+			if _, err := io.WriteString(inW, chunk); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	got := make(map[string]*Message)
+	scanner := bufio.NewScanner(outR)
+	for len(got) < 4 && scanner.Scan() {
+		var msg Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			t.Fatalf("output line %q: %v", scanner.Text(), err)
+		}
+		got[string(msg.ID)] = &msg // requests run concurrently, so answers may come in any order
+	}
+	for _, id := range []string{"1", "2", "3"} {
+		if msg := got[id]; msg == nil || msg.Error != nil || string(msg.Result) != "{}" {
+			t.Errorf("ping %s: response = %+v", id, msg)
+		}
+	}
+	if msg := got["null"]; msg == nil || msg.Error == nil || msg.Error.Code != CodeParseError {
+		t.Errorf("invalid line: response = %+v, want a parse error", msg)
+	}
+
+	inW.Close() // EOF ends the session without an error
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("ServeStdio = %v, want nil at EOF", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ServeStdio did not return at EOF")
+	}
+}
+
+func TestServeStdioStopsWhenCancelled(t *testing.T) {
+	server := NewServer("test", "0.0.0")
+	inR, inW := io.Pipe()
+	defer inW.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- server.ServeStdio(ctx, inR, io.Discard) }()
+
+	cancel() // stdin stays open, as when the parent process hangs
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("ServeStdio = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ServeStdio did not return when cancelled")
+	}
+}
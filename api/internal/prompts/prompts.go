@@ -0,0 +1,348 @@
+// Package prompts keeps the texts the assistant says or sends to the model (the system
+// prompt, the greeting, tool sentences) as versioned text/template files, so they can be
+// changed without recompiling.
+//
+// Each prompt is a directory holding one file per version:
+//
+//	prompts/
+//	├── greeting/
+//	│   ├── v1.tmpl
+//	│   └── v2.tmpl
+//	└── system/
+//	    └── v1.tmpl
+//
+// The built-in versions (the defaults directory, embedded in the binary) are always
+// available; a directory given to Load adds versions, or replaces a built-in one of the
+// same name. The newest version of a prompt is used unless another one is chosen.
+//
+// Templates see a Data value, e.g. {{.Time}} or {{range .Tools}}{{.Name}}{{end}}. Which
+// versions were rendered for a query is recorded in its context (see Start), so every
+// answer can say which prompts produced it.
+package prompts
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+
+	"gonuxt-context-assistant/internal/tools"
+)
+
+// The prompts the assistant uses.
+const (
+	NameSystem   = "system"   // system prompt of the language model
+	NameGreeting = "greeting" // answer to a query no tool matches
+	NameAskCity  = "ask_city" // answer to a weather question without a city
+	NameWeather  = "weather"  // the sentence of the get_weather tool
+	NameContext  = "context"  // passages of the user's documents, sent to the model
+	NameDocument = "document" // answer from a document when there is no model
+)
+
+// fileExt is the extension of template files; other files are ignored.
+const fileExt = ".tmpl"
+
+//go:embed defaults
+var defaults embed.FS
+
+// ErrUnknown is returned for a prompt or version that does not exist.
+var ErrUnknown = errors.New("prompts: unknown prompt")
+
+// Data holds the variables of a template. Not every prompt uses every field.
+type Data struct {
+	Time   string // the current date and time, as tools.GetCurrentDateTime words it; set by Render
+	Tools  []Tool // the tools the assistant can use
+	Query  string // the user's question
+	City   string // the city of a weather answer
+	Report string // the weather of City, e.g. "sunny with 28°C."; empty for an unknown city
+	// Excerpts are the passages of documents found for Query (see internal/rag), best first.
+	Excerpts []Excerpt
+}
+
+// Excerpt is a passage of a document for templates.
+type Excerpt struct {
+	N       int // 1-based, to cite it as [N]
+	Title   string
+	Heading string // the section of the document, if any
+	Text    string
+}
+
+// Tool describes one tool for templates.
+type Tool struct {
+	Name        string
+	Description string
+}
+
+// sampleData is rendered by every template when it is loaded, so a typo in a variable
+// name fails at startup instead of in the middle of a query.
+var sampleData = Data{
+	Time:     "Current time is Monday, January 2, 2006 at 15:04:05 PM (UTC)",
+	Tools:    []Tool{{Name: "get_weather", Description: "Returns the current weather for a single city."}},
+	Query:    "What's the weather in Lisbon?",
+	City:     "Lisbon",
+	Report:   "sunny with 28°C.",
+	Excerpts: []Excerpt{{N: 1, Title: "Travel guide", Heading: "Lisbon", Text: "Lisbon is the capital of Portugal."}},
+}
+
+// Info describes a prompt of a Registry.
+type Info struct {
+	Name     string   `json:"name"`
+	Versions []string `json:"versions"` // oldest first
+	Active   string   `json:"active"`
+}
+
+// Registry holds the loaded prompts. It is safe for concurrent use.
+type Registry struct {
+	// Now words the current time for Data.Time (default tools.GetCurrentDateTime).
+	Now func() string
+
+	dir      string
+	versions map[string]string // chosen versions, by prompt name
+
+	mu      sync.RWMutex
+	prompts map[string]*prompt
+}
+
+// prompt is one prompt with its versions.
+type prompt struct {
+	versions map[string]*template.Template
+	order    []string // oldest first
+	active   string
+}
+
+var (
+	defaultOnce     sync.Once
+	defaultRegistry *Registry
+)
+
+// Default returns a Registry with only the built-in prompts.
+func Default() *Registry {
+	defaultOnce.Do(func() {
+		r, err := Load("", nil)
+		if err != nil {
+			panic(err) // the embedded files are part of the build
+		}
+		defaultRegistry = r
+	})
+	return defaultRegistry
+}
+
+// Load reads the built-in prompts and those of dir, if not "". versions chooses the
+// version of some prompts by name, e.g. {"greeting": "v1"}; the others use their newest.
+func Load(dir string, versions map[string]string) (*Registry, error) {
+	r := &Registry{dir: dir, versions: versions}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the prompt files again. On error the prompts loaded before are kept.
+func (r *Registry) Reload() error {
+	loaded := make(map[string]*prompt)
+	builtin, _ := fs.Sub(defaults, "defaults")
+	if err := readPrompts(builtin, loaded); err != nil {
+		return fmt.Errorf("prompts: built-in: %w", err)
+	}
+	if r.dir != "" {
+		if err := readPrompts(os.DirFS(r.dir), loaded); err != nil {
+			return fmt.Errorf("prompts: %s: %w", r.dir, err)
+		}
+	}
+
+	for _, p := range loaded {
+		sort.Slice(p.order, func(i, j int) bool { return lessVersion(p.order[i], p.order[j]) })
+		p.active = p.order[len(p.order)-1]
+	}
+	for name, version := range r.versions {
+		p, ok := loaded[name]
+		if !ok {
+			return fmt.Errorf("%w %q", ErrUnknown, name)
+		}
+		if p.versions[version] == nil {
+			return fmt.Errorf("%w %q version %q (have %s)", ErrUnknown, name, version, strings.Join(p.order, ", "))
+		}
+		p.active = version
+	}
+
+	r.mu.Lock()
+	r.prompts = loaded
+	r.mu.Unlock()
+	return nil
+}
+
+// readPrompts parses the <name>/<version>.tmpl files of fsys into prompts.
+func readPrompts(fsys fs.FS, prompts map[string]*prompt) error {
+	files, err := fs.Glob(fsys, "*/*"+fileExt)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		name := path.Dir(file)
+		version := strings.TrimSuffix(path.Base(file), fileExt)
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		tmpl, err := template.New(name + "@" + version).Parse(string(data))
+		if err != nil {
+			return err
+		}
+		if err := tmpl.Execute(io.Discard, sampleData); err != nil {
+			return err
+		}
+
+		p := prompts[name]
+		if p == nil {
+			p = &prompt{versions: make(map[string]*template.Template)}
+			prompts[name] = p
+		}
+		if p.versions[version] == nil {
+			p.order = append(p.order, version)
+		}
+		p.versions[version] = tmpl
+	}
+	return nil
+}
+
+// lessVersion orders versions like "v2" before "v10"; versions without a number sort by text.
+func lessVersion(a, b string) bool {
+	na, errA := strconv.Atoi(strings.TrimPrefix(a, "v"))
+	nb, errB := strconv.Atoi(strings.TrimPrefix(b, "v"))
+	if errA == nil && errB == nil && na != nb {
+		return na < nb
+	}
+	return a < b
+}
+
+// Render executes the active version of the named prompt and records the version in ctx
+// (see Start). Leading and trailing white space is trimmed, so a file may end with a newline.
+func (r *Registry) Render(ctx context.Context, name string, data Data) (string, error) {
+	r.mu.RLock()
+	p := r.prompts[name]
+	r.mu.RUnlock()
+	if p == nil {
+		return "", fmt.Errorf("%w %q", ErrUnknown, name)
+	}
+
+	if data.Time == "" {
+		data.Time = r.now()
+	}
+	var text strings.Builder
+	if err := p.versions[p.active].Execute(&text, data); err != nil {
+		return "", err
+	}
+	if used := usedFrom(ctx); used != nil {
+		used.add(name, p.active)
+	}
+	return strings.TrimSpace(text.String()), nil
+}
+
+// Mark records the active version of the named prompt in ctx without rendering it, for
+// prompts rendered where no context reaches, like the sentences of the tools.
+func (r *Registry) Mark(ctx context.Context, name string) {
+	used := usedFrom(ctx)
+	if used == nil {
+		return
+	}
+	if version := r.Active(name); version != "" {
+		used.add(name, version)
+	}
+}
+
+// WeatherSentence renders the weather prompt; it fits tools.SetWeatherSentence.
+func (r *Registry) WeatherSentence(city, report string) (string, error) {
+	return r.Render(context.Background(), NameWeather, Data{City: city, Report: report})
+}
+
+// Active returns the version of the named prompt in use, or "" for an unknown prompt.
+func (r *Registry) Active(name string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if p := r.prompts[name]; p != nil {
+		return p.active
+	}
+	return ""
+}
+
+// List describes the loaded prompts, by name.
+func (r *Registry) List() []Info {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	infos := make([]Info, 0, len(r.prompts))
+	for name, p := range r.prompts {
+		infos = append(infos, Info{Name: name, Versions: append([]string(nil), p.order...), Active: p.active})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+func (r *Registry) now() string {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return tools.GetCurrentDateTime()
+}
+
+// Used collects the prompt versions rendered for one query.
+type Used struct {
+	mu       sync.Mutex
+	versions map[string]string
+}
+
+// Versions returns the versions used so far by prompt name, or nil if none were.
+func (u *Used) Versions() map[string]string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if len(u.versions) == 0 {
+		return nil
+	}
+	versions := make(map[string]string, len(u.versions))
+	for name, version := range u.versions {
+		versions[name] = version
+	}
+	return versions
+}
+
+func (u *Used) add(name, version string) {
+	u.mu.Lock()
+	if u.versions == nil {
+		u.versions = make(map[string]string)
+	}
+	u.versions[name] = version
+	u.mu.Unlock()
+}
+
+type usedKey struct{}
+
+// Start returns a context whose rendered prompts are recorded in the returned Used. If ctx
+// already records them, it is returned as is, with its Used.
+func Start(ctx context.Context) (context.Context, *Used) {
+	if used := usedFrom(ctx); used != nil {
+		return ctx, used
+	}
+	used := &Used{}
+	return context.WithValue(ctx, usedKey{}, used), used
+}
+
+// Versions returns the prompt versions recorded in ctx so far (see Start), or nil.
+func Versions(ctx context.Context) map[string]string {
+	if used := usedFrom(ctx); used != nil {
+		return used.Versions()
+	}
+	return nil
+}
+
+func usedFrom(ctx context.Context) *Used {
+	used, _ := ctx.Value(usedKey{}).(*Used)
+	return used
+}
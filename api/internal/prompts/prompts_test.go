@@ -0,0 +1,201 @@
+package prompts
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBuiltInPrompts(t *testing.T) {
+	r := Default()
+	for _, name := range []string{NameSystem, NameGreeting, NameAskCity, NameWeather, NameContext, NameDocument} {
+		if version := r.Active(name); version != "v1" {
+			t.Errorf("%s: active version %q, want v1", name, version)
+		}
+		if _, err := r.Render(context.Background(), name, sampleData); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if _, err := r.Render(context.Background(), "farewell", Data{}); !errors.Is(err, ErrUnknown) {
+		t.Errorf("unknown prompt: err = %v, want ErrUnknown", err)
+	}
+}
+
+func TestVersionSelection(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions map[string]string
+		prompt   string
+		active   string
+		text     string // what the prompt renders for sampleData
+		err      error
+	}{
+		{"newest by number", nil, NameGreeting, "v10", "Hello from version ten.", nil},
+		{"chosen version of the directory", map[string]string{NameGreeting: "v2"}, NameGreeting, "v2", "Hi, I can use get_weather.", nil},
+		{"chosen built-in version", map[string]string{NameGreeting: "v1"}, NameGreeting, "v1", "Hello! I am a simple assistant.", nil},
+		{"directory replaces a built-in version", nil, NameSystem, "v1", "You answer questions about What's the weather in Lisbon?.", nil},
+		{"prompt only in the directory", nil, "farewell", "v1", "Goodbye from Lisbon!", nil},
+		{"other prompts keep the built-in", map[string]string{NameGreeting: "v2"}, NameAskCity, "v1", "", nil},
+		{"unknown version", map[string]string{NameGreeting: "v3"}, "", "", "", ErrUnknown},
+		{"unknown prompt", map[string]string{"welcome": "v1"}, "", "", "", ErrUnknown},
+	}
+	for _, tt := range tests {
+		r, err := Load("testdata/prompts", tt.versions)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if active := r.Active(tt.prompt); active != tt.active {
+			t.Errorf("%s: active = %q, want %q", tt.name, active, tt.active)
+		}
+		text, err := r.Render(context.Background(), tt.prompt, sampleData)
+		if err != nil || !strings.HasPrefix(text, tt.text) {
+			t.Errorf("%s: Render = %q, %v; want %q", tt.name, text, err, tt.text)
+		}
+	}
+
+	r, err := Load("testdata/prompts", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := slices.IndexFunc(r.List(), func(info Info) bool { return info.Name == NameGreeting })
+	if got := r.List()[i].Versions; !slices.Equal(got, []string{"v1", "v2", "v10"}) {
+		t.Errorf("greeting versions = %q, want v1, v2, v10 (other files ignored)", got)
+	}
+}
+
+func TestLessVersion(t *testing.T) {
+	tests := []struct {
+		a, b string
+		less bool
+	}{
+		{"v1", "v2", true},
+		{"v2", "v10", true},
+		{"v10", "v2", false},
+		{"v1", "v1", false},
+		{"beta", "v2", true}, // against a version without a number, by text
+		{"alpha", "beta", true},
+	}
+	for _, tt := range tests {
+		if got := lessVersion(tt.a, tt.b); got != tt.less {
+			t.Errorf("lessVersion(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.less)
+		}
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	write := func(file, text string) {
+		t.Helper()
+		path := filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	render := func(r *Registry) string {
+		t.Helper()
+		text, err := r.Render(context.Background(), NameGreeting, Data{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return text
+	}
+
+	write("greeting/v2.tmpl", "Hello, version two.")
+	r, err := Load(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := render(r); text != "Hello, version two." {
+		t.Fatalf("greeting = %q", text)
+	}
+
+	// A new version becomes the active one, an edited one is read again.
+	write("greeting/v3.tmpl", "Hello, version three.")
+	if err := r.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if text := render(r); text != "Hello, version three." || r.Active(NameGreeting) != "v3" {
+		t.Errorf("after adding v3: %s %q", r.Active(NameGreeting), text)
+	}
+	write("greeting/v3.tmpl", "Hello again, version three.")
+	if err := r.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if text := render(r); text != "Hello again, version three." {
+		t.Errorf("after editing v3: %q", text)
+	}
+
+	// A broken file is reported and the prompts loaded before stay in use.
+	for _, broken := range []string{"Hello {{.Nope}}", "Hello {{if}}"} {
+		write("greeting/v4.tmpl", broken)
+		if err := r.Reload(); err == nil {
+			t.Errorf("Reload accepted %q", broken)
+		}
+		if text := render(r); text != "Hello again, version three." {
+			t.Errorf("after a failed reload of %q: %q", broken, text)
+		}
+	}
+
+	// A pinned version stays active when newer ones appear.
+	pinned, err := Load(dir, map[string]string{NameGreeting: "v2"})
+	if err == nil {
+		t.Fatal("Load accepted the broken v4")
+	}
+	if err := os.Remove(filepath.Join(dir, "greeting/v4.tmpl")); err != nil {
+		t.Fatal(err)
+	}
+	if pinned, err = Load(dir, map[string]string{NameGreeting: "v2"}); err != nil {
+		t.Fatal(err)
+	}
+	write("greeting/v5.tmpl", "Hello, version five.")
+	if err := pinned.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if text := render(pinned); text != "Hello, version two." {
+		t.Errorf("pinned greeting = %q, want v2", text)
+	}
+}
+
+func TestRenderRecordsVersions(t *testing.T) {
+	r, err := Load("testdata/prompts", map[string]string{NameGreeting: "v2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Now = func() string { return "Current time is noon" }
+
+	ctx, used := Start(context.Background())
+	if again, same := Start(ctx); again != ctx || same != used {
+		t.Error("Start on a recording context started another record")
+	}
+	if Versions(ctx) != nil {
+		t.Errorf("versions before rendering = %v", Versions(ctx))
+	}
+	if _, err := r.Render(ctx, NameGreeting, Data{}); err != nil {
+		t.Fatal(err)
+	}
+	r.Mark(ctx, NameWeather)
+	r.Mark(ctx, "unknown")
+	want := map[string]string{NameGreeting: "v2", NameWeather: "v1"}
+	if got := used.Versions(); len(got) != len(want) || got[NameGreeting] != "v2" || got[NameWeather] != "v1" {
+		t.Errorf("versions = %v, want %v", got, want)
+	}
+
+	// Data.Time defaults to Now.
+	if text, err := r.Render(context.Background(), "farewell", Data{City: "Lisbon"}); err != nil || text != "Goodbye from Lisbon! Current time is noon." {
+		t.Errorf("farewell = %q, %v; want the time of Now", text, err)
+	}
+}
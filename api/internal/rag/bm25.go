@@ -0,0 +1,84 @@
+package rag
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Okapi BM25, the keyword ranking of search engines: a chunk scores higher the more often
+// it has the words of the question, the rarer those words are across all chunks, and the
+// shorter the chunk is.
+const (
+	bm25K1 = 1.2  // how quickly repeating a word stops adding to the score
+	bm25B  = 0.75 // how much long chunks are penalized
+)
+
+// stopWords are too common to tell chunks apart.
+var stopWords = map[string]bool{
+	"a": true, "about": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "can": true, "do": true, "does": true, "for": true, "from": true,
+	"how": true, "i": true, "in": true, "is": true, "it": true, "me": true, "my": true,
+	"of": true, "on": true, "or": true, "our": true, "tell": true, "that": true, "the": true,
+	"this": true, "to": true, "was": true, "we": true, "what": true, "when": true,
+	"where": true, "which": true, "who": true, "why": true, "will": true, "with": true,
+	"you": true, "your": true,
+}
+
+// terms splits text into lower-case words, without stop words and single letters.
+func terms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	out := words[:0]
+	for _, word := range words {
+		if len([]rune(word)) > 1 && !stopWords[word] {
+			out = append(out, word)
+		}
+	}
+	return out
+}
+
+// termCounts counts the words of text.
+func termCounts(text string) (counts map[string]int, length int) {
+	counts = make(map[string]int)
+	for _, term := range terms(text) {
+		counts[term]++
+		length++
+	}
+	return counts, length
+}
+
+// bm25 scores one chunk for the distinct terms of a query and counts how many of them it
+// contains. n is the number of chunks, df the number of chunks containing each term and
+// avgLength their average length.
+func bm25(c *chunk, query []string, n int, df map[string]int, avgLength float64) (score float64, matched int) {
+	for _, term := range query {
+		tf := float64(c.terms[term])
+		if tf == 0 {
+			continue
+		}
+		matched++
+		idf := math.Log(1 + (float64(n)-float64(df[term])+0.5)/(float64(df[term])+0.5))
+		norm := 1 - bm25B + bm25B*float64(c.length)/avgLength
+		score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+	}
+	return score, matched
+}
+
+// cosine is the cosine similarity of two vectors, 0 when they differ in length.
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
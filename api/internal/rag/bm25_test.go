@@ -0,0 +1,77 @@
+package rag
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"What is the weather in Lisbon?", []string{"weather", "lisbon"}},
+		{"Tram 28, São Bento & Porto's river", []string{"tram", "28", "são", "bento", "porto", "river"}},
+		{"a I x of the", []string{}},
+	}
+	for _, tt := range tests {
+		if got := terms(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("terms(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+// chunkOf makes a chunk of text, for scoring.
+func chunkOf(text string) *chunk {
+	c := &chunk{text: text}
+	c.terms, c.length = termCounts(text)
+	return c
+}
+
+func TestBM25(t *testing.T) {
+	// Ten chunks of four words: "tram" is in all of them, "castle" in one.
+	df := map[string]int{"tram": 10, "castle": 1}
+	tests := []struct {
+		name          string
+		better, worse string
+		query         []string
+	}{
+		{"repeated word", "tram tram castle hill", "tram castle hill view", []string{"castle", "tram"}},
+		{"rare word", "castle hill view sea", "tram hill view sea", []string{"castle", "tram"}},
+		{"shorter chunk", "castle hill", "castle hill view sea river bridge cathedral", []string{"castle"}},
+		{"more words of the question", "castle tram hill view", "castle hill view sea", []string{"castle", "tram"}},
+	}
+	for _, tt := range tests {
+		better, matchedBetter := bm25(chunkOf(tt.better), tt.query, 10, df, 4)
+		worse, _ := bm25(chunkOf(tt.worse), tt.query, 10, df, 4)
+		if better <= worse {
+			t.Errorf("%s: %q scores %v, not more than %q with %v", tt.name, tt.better, better, tt.worse, worse)
+		}
+		if matchedBetter == 0 {
+			t.Errorf("%s: %q matches no word of %q", tt.name, tt.better, tt.query)
+		}
+	}
+
+	if score, matched := bm25(chunkOf("castle hill"), []string{"river"}, 10, df, 4); score != 0 || matched != 0 {
+		t.Errorf("no word of the question: score %v, %d matched; want 0", score, matched)
+	}
+}
+
+func TestCosine(t *testing.T) {
+	tests := []struct {
+		a, b []float32
+		want float64
+	}{
+		{[]float32{1, 0}, []float32{2, 0}, 1},
+		{[]float32{1, 0}, []float32{0, 3}, 0},
+		{[]float32{1, 1}, []float32{-1, -1}, -1},
+		{[]float32{1, 0}, []float32{1, 0, 0}, 0},
+		{[]float32{0, 0}, []float32{1, 0}, 0},
+		{nil, nil, 0},
+	}
+	for _, tt := range tests {
+		if got := cosine(tt.a, tt.b); got < tt.want-1e-9 || got > tt.want+1e-9 {
+			t.Errorf("cosine(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
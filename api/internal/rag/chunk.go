@@ -0,0 +1,119 @@
+package rag
+
+import (
+	"sort"
+	"strings"
+
+	"gonuxt-context-assistant/internal/llm"
+)
+
+// DefaultChunkTokens is the size of a chunk when Index.ChunkTokens is not set.
+const DefaultChunkTokens = 200
+
+// chunkText cuts sections into passages of about maxTokens tokens. Paragraphs stay whole
+// when they fit; longer ones are cut between sentences, and sentences between words. A
+// chunk never spans two sections, so it keeps the heading it belongs to.
+func chunkText(sections []section, maxTokens int) []section {
+	var chunks []section
+	for _, sec := range sections {
+		var current []string
+		flush := func() {
+			if len(current) > 0 {
+				chunks = append(chunks, section{Heading: sec.Heading, Text: strings.Join(current, "\n\n")})
+				current = nil
+			}
+		}
+		for _, para := range paragraphs(sec.Text) {
+			for _, piece := range splitToFit(para, maxTokens) {
+				// Measure the joined text: the blank lines between pieces count too.
+				if len(current) > 0 && llm.EstimateTokens(strings.Join(append(current, piece), "\n\n")) > maxTokens {
+					flush()
+				}
+				current = append(current, piece)
+			}
+		}
+		flush()
+	}
+	return chunks
+}
+
+// paragraphs splits text at blank lines and joins the lines of each paragraph.
+func paragraphs(text string) []string {
+	var out []string
+	for _, block := range blankLineRun.Split(text, -1) {
+		if para := strings.Join(strings.Fields(block), " "); para != "" {
+			out = append(out, para)
+		}
+	}
+	return out
+}
+
+// splitToFit cuts a paragraph longer than maxTokens into sentences, sentences still too
+// long into runs of words, and words still too long into runs of letters.
+func splitToFit(para string, maxTokens int) []string {
+	if llm.EstimateTokens(para) <= maxTokens {
+		return []string{para}
+	}
+	var pieces []string
+	for _, sentence := range sentences(para) {
+		if llm.EstimateTokens(sentence) <= maxTokens {
+			pieces = append(pieces, sentence)
+			continue
+		}
+		run := ""
+		for _, word := range strings.Fields(sentence) {
+			if run != "" && llm.EstimateTokens(run+" "+word) <= maxTokens {
+				run += " " + word
+				continue
+			}
+			if run != "" {
+				pieces = append(pieces, run)
+			}
+			parts := splitWord(word, maxTokens)
+			pieces = append(pieces, parts[:len(parts)-1]...)
+			run = parts[len(parts)-1]
+		}
+		if run != "" {
+			pieces = append(pieces, run)
+		}
+	}
+	return pieces
+}
+
+// splitWord cuts a word longer than maxTokens into runs of letters. It looks for the
+// longest run that fits by doubling, then halving, so a huge word (say, base64 pasted into
+// Markdown) takes no quadratic time.
+func splitWord(word string, maxTokens int) []string {
+	var parts []string
+	runes := []rune(word)
+	fits := func(n int) bool { return llm.EstimateTokens(string(runes[:n])) <= maxTokens }
+	for {
+		end := 1
+		for end < len(runes) && fits(end) {
+			end *= 2
+		}
+		if end >= len(runes) && fits(len(runes)) {
+			return append(parts, string(runes))
+		}
+		end = min(end, len(runes))
+		n := max(sort.Search(end, func(n int) bool { return n > 0 && !fits(n) })-1, 1)
+		parts = append(parts, string(runes[:n]))
+		runes = runes[n:]
+	}
+}
+
+// sentences splits text after ".", "!" or "?" followed by a space.
+func sentences(text string) []string {
+	var out []string
+	start := 0
+	for i := 0; i < len(text)-1; i++ {
+		if (text[i] == '.' || text[i] == '!' || text[i] == '?') && text[i+1] == ' ' {
+			out = append(out, text[start:i+1])
+			start = i + 2
+		}
+	}
+	if start < len(text) {
+		out = append(out, text[start:])
+	}
+	return out
+}
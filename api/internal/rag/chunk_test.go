@@ -0,0 +1,63 @@
+package rag
+
+import (
+	"strings"
+	"testing"
+
+	"gonuxt-context-assistant/internal/llm"
+)
+
+func TestSplitToFitKeepsPiecesWithinTheBudget(t *testing.T) {
+	tests := []struct {
+		name      string
+		para      string
+		maxTokens int
+		pieces    int // 0: do not check
+	}{
+		{"short paragraph", "Trams climb the hills of Lisbon.", 20, 1},
+		{"sentences", "Tram 28 leaves from Martim Moniz. It climbs to the castle. It is crowded in summer!", 10, 3},
+		{"long sentence", strings.Repeat("tram ", 50), 8, 0},
+		{"long words after short ones", "a b c " + strings.Repeat("castelo ", 20), 3, 0},
+		{"word longer than a chunk", "The word " + strings.Repeat("x", 30) + " does not fit.", 4, 0},
+		{"one token", "Lisbon has seven hills. Porto has one river.", 1, 0},
+	}
+	for _, tt := range tests {
+		pieces := splitToFit(tt.para, tt.maxTokens)
+		if tt.pieces > 0 && len(pieces) != tt.pieces {
+			t.Errorf("%s: %d pieces, want %d: %q", tt.name, len(pieces), tt.pieces, pieces)
+		}
+		for _, piece := range pieces {
+			if tokens := llm.EstimateTokens(piece); tokens > tt.maxTokens || tokens == 0 {
+				t.Errorf("%s: piece %q has %d tokens, want 1 to %d", tt.name, piece, tokens, tt.maxTokens)
+			}
+		}
+		if got, want := strings.Join(strings.Fields(strings.Join(pieces, "")), ""), strings.Join(strings.Fields(tt.para), ""); got != want {
+			t.Errorf("%s: pieces %q lose text of %q", tt.name, pieces, tt.para)
+		}
+	}
+}
+
+func TestChunkTextKeepsChunksWithinTheBudget(t *testing.T) {
+	sections := []section{
+		{Heading: "Lisbon", Text: "Trams climb the hills.\n\nTram 28 leaves from Martim Moniz. It climbs to the castle.\n\n" + strings.Repeat("Pastéis de nata are sold everywhere. ", 20)},
+		{Heading: "Porto", Text: "Port wine cellars line the river."},
+		{Heading: "Empty", Text: "\n\n"},
+	}
+	for _, maxTokens := range []int{5, 20, 200} {
+		chunks := chunkText(sections, maxTokens)
+		headings := make(map[string]bool)
+		for _, c := range chunks {
+			headings[c.Heading] = true
+			if tokens := llm.EstimateTokens(c.Text); tokens > maxTokens {
+				t.Errorf("maxTokens %d: chunk of %s has %d tokens: %q", maxTokens, c.Heading, tokens, c.Text)
+			}
+		}
+		if !headings["Lisbon"] || !headings["Porto"] || headings["Empty"] {
+			t.Errorf("maxTokens %d: chunks of sections %v, want Lisbon and Porto", maxTokens, headings)
+		}
+	}
+	short := []section{{Heading: "Porto", Text: "Port wine cellars line the river.\n\nThe bridge is by Eiffel's firm."}}
+	if chunks := chunkText(short, 200); len(chunks) != 1 || chunks[0].Text != "Port wine cellars line the river.\n\nThe bridge is by Eiffel's firm." {
+		t.Errorf("paragraphs that fit together: chunks %q, want one", chunks)
+	}
+}
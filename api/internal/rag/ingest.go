@@ -0,0 +1,229 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Indexing a document can take a while with embeddings, so uploads are indexed in the
+// background by an Ingester: Submit returns a Job at once, and its status tells when the
+// document can be found.
+
+// JobStatus is the state of an ingestion job.
+type JobStatus string
+
+// Job states. Queued and running jobs are pending; the others are finished.
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobDone      JobStatus = "done"      // the document is indexed
+	JobDuplicate JobStatus = "duplicate" // the same content was already indexed, or pending
+	JobFailed    JobStatus = "failed"    // see Job.Error
+)
+
+const (
+	// queueSize is the number of jobs that can wait for a worker.
+	queueSize = 100
+	// maxJobs is the number of jobs an Ingester remembers; the oldest finished ones go first.
+	maxJobs = 200
+)
+
+// ErrQueueFull is returned by Submit when too many documents wait to be indexed.
+var ErrQueueFull = errors.New("rag: too many documents waiting to be indexed")
+
+// Job is the ingestion of one document.
+type Job struct {
+	ID     string    `json:"id"`
+	Name   string    `json:"name,omitempty"`
+	Status JobStatus `json:"status"`
+	// DocumentID is the ID the document has once indexed. It follows from the content,
+	// so it is known from the start; for a duplicate it is the document already indexed.
+	DocumentID string    `json:"documentId"`
+	Hash       string    `json:"hash"`
+	Size       int       `json:"size"`
+	Error      string    `json:"error,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// Finished reports whether the job will not change anymore.
+func (j Job) Finished() bool {
+	return j.Status != JobQueued && j.Status != JobRunning
+}
+
+// Ingester indexes documents in the background, a few at a time. It is safe for
+// concurrent use.
+type Ingester struct {
+	Index *Index
+
+	queue  chan ingestTask
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	order   []string          // job IDs, oldest first
+	pending map[string]string // job ID by document ID, for queued and running jobs
+}
+
+type ingestTask struct {
+	jobID string
+	input Input
+}
+
+// NewIngester starts workers goroutines (at least one) indexing into index. Stop them
+// with Close.
+func NewIngester(index *Index, workers int) *Ingester {
+	ctx, cancel := context.WithCancel(context.Background())
+	g := &Ingester{
+		Index:   index,
+		queue:   make(chan ingestTask, queueSize),
+		ctx:     ctx,
+		cancel:  cancel,
+		jobs:    make(map[string]*Job),
+		pending: make(map[string]string),
+	}
+	for range max(workers, 1) {
+		g.wg.Add(1)
+		go g.work()
+	}
+	return g
+}
+
+// Submit queues a document for indexing. Content that is already indexed, or waiting to
+// be, is not indexed twice: its job is finished at once with JobDuplicate. Submit fails
+// with ErrUnsupported, ErrEmpty or ErrQueueFull before creating a job.
+func (g *Ingester) Submit(in Input) (Job, error) {
+	if _, err := in.ResolveFormat(); err != nil {
+		return Job{}, err
+	}
+	if len(bytes.TrimSpace(in.Data)) == 0 {
+		return Job{}, ErrEmpty
+	}
+	docID, hash := contentID(in.Data)
+	now := time.Now()
+	job := &Job{ID: newJobID(), Name: in.Name, DocumentID: docID, Hash: hash, Size: len(in.Data), CreatedAt: now, UpdatedAt: now}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, indexed := g.Index.Get(docID)
+	if _, waiting := g.pending[docID]; indexed || waiting {
+		job.Status = JobDuplicate
+		g.add(job)
+		return *job, nil
+	}
+	select {
+	case g.queue <- ingestTask{jobID: job.ID, input: in}:
+	default:
+		return Job{}, ErrQueueFull
+	}
+	job.Status = JobQueued
+	g.pending[docID] = job.ID
+	g.add(job)
+	return *job, nil
+}
+
+// Job returns a job by ID.
+func (g *Ingester) Job(id string) (Job, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	job, ok := g.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// Jobs lists the jobs remembered, most recent first.
+func (g *Ingester) Jobs() []Job {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	jobs := make([]Job, 0, len(g.jobs))
+	for _, job := range g.jobs {
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.After(jobs[j].CreatedAt) })
+	return jobs
+}
+
+// Close stops the workers. Running jobs are cancelled and queued ones never run.
+func (g *Ingester) Close() {
+	g.cancel()
+	g.wg.Wait()
+}
+
+func (g *Ingester) work() {
+	defer g.wg.Done()
+	for {
+		select {
+		case <-g.ctx.Done():
+			return
+		case task := <-g.queue:
+			g.run(task)
+		}
+	}
+}
+
+func (g *Ingester) run(task ingestTask) {
+	g.update(task.jobID, func(job *Job) { job.Status = JobRunning })
+	doc, err := g.Index.Add(g.ctx, task.input)
+	g.update(task.jobID, func(job *Job) {
+		delete(g.pending, job.DocumentID)
+		switch {
+		case err == nil:
+			job.Status = JobDone
+		case errors.Is(err, ErrDuplicate):
+			job.Status = JobDuplicate
+		default:
+			job.Status = JobFailed
+			job.Error = err.Error()
+			log.Printf("Indexing %s failed: %v", job.Name, err)
+			return
+		}
+		job.DocumentID = doc.ID
+	})
+}
+
+// update changes a job under the lock.
+func (g *Ingester) update(id string, change func(job *Job)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if job, ok := g.jobs[id]; ok {
+		change(job)
+		job.UpdatedAt = time.Now()
+	}
+}
+
+// add remembers a new job, forgetting the oldest finished one when there are too many;
+// g.mu must be held.
+func (g *Ingester) add(job *Job) {
+	g.jobs[job.ID] = job
+	g.order = append(g.order, job.ID)
+	if len(g.order) <= maxJobs {
+		return
+	}
+	for i, id := range g.order {
+		if g.jobs[id].Finished() {
+			delete(g.jobs, id)
+			g.order = append(g.order[:i], g.order[i+1:]...)
+			return
+		}
+	}
+}
+
+// newJobID returns a random job ID.
+func newJobID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic("rag: no randomness: " + err.Error())
+	}
+	return "job-" + hex.EncodeToString(b)
+}
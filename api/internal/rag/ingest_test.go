@@ -0,0 +1,192 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// gate is an Embedder that holds every call until it is opened, or fails with err.
+type gate struct {
+	open chan struct{}
+	err  error
+}
+
+func newGate() *gate { return &gate{open: make(chan struct{})} }
+
+func (g *gate) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	select {
+	case <-g.open:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if g.err != nil {
+		return nil, g.err
+	}
+	return make([][]float32, len(texts)), nil
+}
+
+// waitJob waits until the job has the status.
+func waitJob(t *testing.T, g *Ingester, id string, status JobStatus) Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, ok := g.Job(id)
+		if !ok {
+			t.Fatalf("job %s not found", id)
+		}
+		if job.Status == status {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s is %s, want %s", id, job.Status, status)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestIngesterJobLifecycle(t *testing.T) {
+	index := NewIndex()
+	embed := newGate()
+	index.Embedder = embed
+	g := NewIngester(index, 1)
+	defer g.Close()
+
+	in := Input{Name: "lisbon.md", Data: []byte("# Lisbon\n\nTram 28 climbs to the castle.")}
+	job, err := g.Submit(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != JobQueued || job.Finished() || job.Name != "lisbon.md" || job.Size != len(in.Data) {
+		t.Errorf("submitted: %+v, want a queued job", job)
+	}
+	wantID, wantHash := contentID(in.Data)
+	if job.DocumentID != wantID || job.Hash != wantHash {
+		t.Errorf("document %s with hash %s, want %s and %s", job.DocumentID, job.Hash, wantID, wantHash)
+	}
+
+	waitJob(t, g, job.ID, JobRunning)
+	if _, ok := index.Get(job.DocumentID); ok {
+		t.Error("the document is searchable before its job is done")
+	}
+	close(embed.open)
+	done := waitJob(t, g, job.ID, JobDone)
+	if !done.Finished() || done.Error != "" || !done.UpdatedAt.After(done.CreatedAt) {
+		t.Errorf("done: %+v", done)
+	}
+	if doc, ok := index.Get(job.DocumentID); !ok || doc.Title != "Lisbon" {
+		t.Errorf("indexed document %+v, %v; want Lisbon", doc, ok)
+	}
+
+	embed.err = errors.New("embeddings are down")
+	failed, err := g.Submit(Input{Name: "porto.md", Data: []byte("# Porto\n\nPort wine.")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job := waitJob(t, g, failed.ID, JobFailed); job.Error == "" {
+		t.Errorf("failed job without an error: %+v", job)
+	}
+	if _, ok := index.Get(failed.DocumentID); ok {
+		t.Error("a failed document is indexed")
+	}
+
+	if jobs := g.Jobs(); len(jobs) != 2 || jobs[0].ID != failed.ID || jobs[1].ID != job.ID {
+		t.Errorf("jobs %+v, want the failed one, then the done one", jobs)
+	}
+	if _, ok := g.Job("job-unknown"); ok {
+		t.Error("found an unknown job")
+	}
+}
+
+func TestIngesterDetectsDuplicatesByHash(t *testing.T) {
+	index := NewIndex()
+	embed := newGate()
+	index.Embedder = embed
+	g := NewIngester(index, 2)
+	defer g.Close()
+
+	data := []byte("# Lisbon\n\nTram 28 climbs to the castle.")
+	first, err := g.Submit(Input{Name: "lisbon.md", Data: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		in   Input
+	}{
+		{"same content while pending", Input{Name: "lisbon.md", Data: data}},
+		{"same content under another name", Input{Name: "copy.md", Title: "Copy", Data: data}},
+	}
+	for _, tt := range tests {
+		job, err := g.Submit(tt.in)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if job.Status != JobDuplicate || job.DocumentID != first.DocumentID || job.Hash != first.Hash || job.ID == first.ID {
+			t.Errorf("%s: %+v, want a new duplicate job of document %s", tt.name, job, first.DocumentID)
+		}
+	}
+
+	close(embed.open)
+	waitJob(t, g, first.ID, JobDone)
+	again, err := g.Submit(Input{Name: "again.md", Data: data})
+	if err != nil || again.Status != JobDuplicate || again.DocumentID != first.DocumentID {
+		t.Errorf("same content once indexed: %+v, %v; want a duplicate", again, err)
+	}
+	other, err := g.Submit(Input{Name: "lisbon.md", Data: append(data, " It is yellow."...)})
+	if err != nil || other.Status == JobDuplicate || other.DocumentID == first.DocumentID {
+		t.Errorf("other content under the same name: %+v, %v; want a new document", other, err)
+	}
+	waitJob(t, g, other.ID, JobDone)
+	if n := len(index.Documents()); n != 2 {
+		t.Errorf("%d documents indexed, want 2", n)
+	}
+}
+
+func TestIngesterRejectsBeforeCreatingAJob(t *testing.T) {
+	index := NewIndex()
+	embed := newGate()
+	index.Embedder = embed
+	g := NewIngester(index, 1)
+	defer g.Close()
+
+	// One document keeps the worker busy; queueSize more fill the queue.
+	busy, err := g.Submit(Input{Name: "busy.txt", Data: []byte("busy")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitJob(t, g, busy.ID, JobRunning)
+	for i := range queueSize {
+		if _, err := g.Submit(Input{Name: "queued.txt", Data: fmt.Appendf(nil, "document %d", i)}); err != nil {
+			t.Fatalf("document %d: %v", i, err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		in   Input
+		err  error
+	}{
+		{"no text", Input{Name: "empty.md", Data: []byte(" \n\t\n")}, ErrEmpty},
+		{"no data", Input{Name: "empty.txt"}, ErrEmpty},
+		{"unsupported", Input{Name: "slides.pdf", Data: []byte("%PDF")}, ErrUnsupported},
+		{"queue full", Input{Name: "one-more.txt", Data: []byte("one more")}, ErrQueueFull},
+	}
+	for _, tt := range tests {
+		job, err := g.Submit(tt.in)
+		if !errors.Is(err, tt.err) || job.ID != "" {
+			t.Errorf("%s: %+v, %v; want no job and %v", tt.name, job, err, tt.err)
+		}
+	}
+	if n := len(g.Jobs()); n != 1+queueSize {
+		t.Errorf("%d jobs, want %d", n, 1+queueSize)
+	}
+
+	// A duplicate needs no room in the queue.
+	if job, err := g.Submit(Input{Name: "busy.txt", Data: []byte("busy")}); err != nil || job.Status != JobDuplicate {
+		t.Errorf("duplicate with a full queue: %+v, %v", job, err)
+	}
+}
@@ -0,0 +1,183 @@
+package rag
+
+import (
+	"html"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Format is the kind of text a document is written in.
+type Format string
+
+// The formats that can be ingested.
+const (
+	FormatMarkdown Format = "markdown"
+	FormatHTML     Format = "html"
+	FormatText     Format = "text"
+)
+
+// FormatOf tells the format of a file by its extension: .md, .markdown, .html, .htm or .txt.
+func FormatOf(name string) (Format, bool) {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".md", ".markdown":
+		return FormatMarkdown, true
+	case ".html", ".htm":
+		return FormatHTML, true
+	case ".txt", ".text":
+		return FormatText, true
+	}
+	return "", false
+}
+
+// section is a part of a document under one heading.
+type section struct {
+	Heading string
+	Text    string
+}
+
+// parse turns a document into plain-text sections and finds its title: the first
+// heading, the HTML <title> or a short first line of text, else the file name without
+// extension.
+func parse(name string, format Format, data []byte) (title string, sections []section) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	switch format {
+	case FormatHTML:
+		title, text = htmlToMarkdown(text)
+		sections, heading := markdownSections(text)
+		if title == "" {
+			title = heading
+		}
+		return titleOr(title, name), sections
+	case FormatMarkdown:
+		sections, heading := markdownSections(text)
+		return titleOr(heading, name), sections
+	default:
+		// A short first line followed by more text is taken for the title.
+		first, rest, _ := strings.Cut(strings.TrimSpace(text), "\n")
+		if len(first) <= maxTitleLength && strings.TrimSpace(rest) != "" {
+			return titleOr(first, name), []section{{Text: rest}}
+		}
+		return titleOr("", name), []section{{Text: text}}
+	}
+}
+
+// maxTitleLength is the longest first line of a text file taken for its title.
+const maxTitleLength = 80
+
+func titleOr(title, name string) string {
+	if title = strings.TrimSpace(title); title != "" {
+		return title
+	}
+	if name == "" {
+		return "Untitled"
+	}
+	base := path.Base(strings.ReplaceAll(name, "\\", "/"))
+	return strings.TrimSuffix(base, path.Ext(base))
+}
+
+var (
+	mdHeading = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdImage   = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLink    = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	mdMarks   = strings.NewReplacer("**", "", "__", "", "`", "")
+)
+
+// markdownSections splits Markdown at its headings and strips the markup that does not
+// help a reader: link targets, emphasis, code fences and front matter. It also returns the
+// first top-level heading (or the first heading), the document's title.
+func markdownSections(text string) ([]section, string) {
+	lines := strings.Split(text, "\n")
+	if len(lines) > 0 && strings.TrimSpace(lines[0]) == "---" { // YAML front matter
+		for i := 1; i < len(lines); i++ {
+			if strings.TrimSpace(lines[i]) == "---" {
+				lines = lines[i+1:]
+				break
+			}
+		}
+	}
+
+	var (
+		sections  []section
+		current   section
+		body      strings.Builder
+		h1, first string // the first top-level heading, the first heading
+		inCode    bool
+	)
+	flush := func() {
+		current.Text = body.String()
+		if strings.TrimSpace(current.Text) != "" || current.Heading != "" {
+			sections = append(sections, current)
+		}
+		body.Reset()
+	}
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") || strings.HasPrefix(strings.TrimSpace(line), "~~~") {
+			inCode = !inCode
+			body.WriteString("\n")
+			continue
+		}
+		if !inCode {
+			if m := mdHeading.FindStringSubmatch(line); m != nil {
+				flush()
+				heading := cleanMarkdown(m[2])
+				current = section{Heading: heading}
+				if first == "" {
+					first = heading
+				}
+				if h1 == "" && len(m[1]) == 1 {
+					h1 = heading
+				}
+				continue
+			}
+			line = cleanMarkdown(line)
+		}
+		body.WriteString(line)
+		body.WriteString("\n")
+	}
+	flush()
+	if h1 != "" {
+		return sections, h1
+	}
+	return sections, first
+}
+
+func cleanMarkdown(line string) string {
+	line = mdImage.ReplaceAllString(line, "$1")
+	line = mdLink.ReplaceAllString(line, "$1")
+	return mdMarks.Replace(line)
+}
+
+var (
+	htmlTitle    = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	htmlDrop     = regexp.MustCompile(`(?is)<!--.*?-->|<head[^>]*>.*?</head>|<title[^>]*>.*?</title>|<script[^>]*>.*?</script>|<style[^>]*>.*?</style>|<noscript[^>]*>.*?</noscript>`)
+	htmlHeading  = regexp.MustCompile(`(?is)<h([1-6])[^>]*>(.*?)</h[1-6]>`)
+	htmlBreak    = regexp.MustCompile(`(?i)<br\s*/?>`)
+	htmlItem     = regexp.MustCompile(`(?i)<li[^>]*>`)
+	htmlBlock    = regexp.MustCompile(`(?i)</?(p|div|section|article|main|header|footer|nav|aside|ul|ol|table|tr|blockquote|pre|dl|dt|dd|hr|figure|figcaption)\b[^>]*>`)
+	htmlTag      = regexp.MustCompile(`(?s)<[^>]*>`)
+	spaceRun     = regexp.MustCompile(`[ \t\f\v]+`)
+	blankLineRun = regexp.MustCompile(`\n\s*\n+`)
+)
+
+// htmlToMarkdown reduces HTML to text with Markdown headings, so it can be split like
+// Markdown, and returns the <title>.
+func htmlToMarkdown(doc string) (title, text string) {
+	if m := htmlTitle.FindStringSubmatch(doc); m != nil {
+		title = strings.TrimSpace(html.UnescapeString(htmlTag.ReplaceAllString(m[1], "")))
+	}
+	text = htmlDrop.ReplaceAllString(doc, "")
+	text = htmlHeading.ReplaceAllStringFunc(text, func(h string) string {
+		m := htmlHeading.FindStringSubmatch(h)
+		heading := strings.Join(strings.Fields(htmlTag.ReplaceAllString(m[2], "")), " ")
+		return "\n\n" + strings.Repeat("#", int(m[1][0]-'0')) + " " + heading + "\n\n"
+	})
+	text = htmlBreak.ReplaceAllString(text, "\n")
+	text = htmlItem.ReplaceAllString(text, "\n- ")
+	text = htmlBlock.ReplaceAllString(text, "\n\n")
+	text = htmlTag.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	text = spaceRun.ReplaceAllString(text, " ")
+	text = blankLineRun.ReplaceAllString(text, "\n\n")
+	return title, strings.TrimSpace(text)
+}
@@ -0,0 +1,120 @@
+package rag
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		title    string
+		sections []section
+	}{
+		{
+			"links, images and emphasis",
+			"# Lisbon\n\nTake **tram 28** to the [castle](https://example.com/castle) and see ![the view](view.png) from `Graça`.\n",
+			"Lisbon",
+			[]section{{Heading: "Lisbon", Text: "Take tram 28 to the castle and see the view from Graça."}},
+		},
+		{
+			"sections and heading markup",
+			"Intro text.\n\n## Getting [there](x) ##\n\nBy __train__.\n\n# Porto\n\nBy bus.",
+			"Porto", // the first top-level heading
+			[]section{
+				{Text: "Intro text."},
+				{Heading: "Getting there", Text: "By train."},
+				{Heading: "Porto", Text: "By bus."},
+			},
+		},
+		{
+			"code fences keep their text as is",
+			"## Setup\n\n```sh\n# not a heading\ncurl [x](y)\n```\n",
+			"Setup", // no top-level heading: the first one
+			[]section{{Heading: "Setup", Text: "# not a heading\ncurl [x](y)"}},
+		},
+		{
+			"front matter",
+			"---\ntitle: ignored\n---\nJust text.",
+			"guide", // no heading: the file name
+			[]section{{Text: "Just text."}},
+		},
+	}
+	for _, tt := range tests {
+		title, sections := parse("docs/guide.md", FormatMarkdown, []byte(tt.doc))
+		if title != tt.title {
+			t.Errorf("%s: title %q, want %q", tt.name, title, tt.title)
+		}
+		for i := range sections {
+			sections[i].Text = strings.TrimSpace(sections[i].Text)
+		}
+		if !reflect.DeepEqual(sections, tt.sections) {
+			t.Errorf("%s: sections %q, want %q", tt.name, sections, tt.sections)
+		}
+	}
+}
+
+func TestHTMLToMarkdown(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		title string
+		text  string
+	}{
+		{
+			"page",
+			`<html><head><title>Visit &amp; stay</title><style>p { color: red }</style></head>
+<body><script>alert("hi")</script><!-- menu -->
+<h1 class="top">Lisbon <em>guide</em></h1>
+<p>Trams&nbsp;climb   the <a href="/hills">hills</a>.<br>Tram 28 is <b>famous</b>.</p>
+<ul><li>Baixa</li><li>Alfama</li></ul>
+<noscript>Enable JavaScript</noscript></body></html>`,
+			"Visit & stay",
+			"# Lisbon guide\n\nTrams climb the hills.\nTram 28 is famous.\n\n- Baixa\n- Alfama",
+		},
+		{
+			"fragment without title",
+			"<div><h2>Porto</h2>Port &lt;wine&gt; cellars</div>",
+			"",
+			"## Porto\n\nPort <wine> cellars",
+		},
+	}
+	for _, tt := range tests {
+		title, text := htmlToMarkdown(tt.doc)
+		if title != tt.title {
+			t.Errorf("%s: title %q, want %q", tt.name, title, tt.title)
+		}
+		if text != tt.text {
+			t.Errorf("%s: text %q, want %q", tt.name, text, tt.text)
+		}
+	}
+}
+
+func TestParseTitles(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		doc    string
+		title  string
+		text   string // of all sections
+	}{
+		{"notes.html", FormatHTML, "<title>Page</title><h1>Heading</h1><p>Body</p>", "Page", "Body"},
+		{"notes.html", FormatHTML, "<h1>Heading</h1><p>Body</p>", "Heading", "Body"},
+		{"notes.txt", FormatText, "Opening hours\nNine to six.", "Opening hours", "Nine to six."},
+		{"notes.txt", FormatText, "Nine to six.", "notes", "Nine to six."},
+		{"dir\\notes.txt", FormatText, strings.Repeat("long ", 20) + "\nmore", "notes", strings.Repeat("long ", 20) + "\nmore"},
+		{"", FormatText, "Nine to six.", "Untitled", "Nine to six."},
+	}
+	for _, tt := range tests {
+		title, sections := parse(tt.name, tt.format, []byte(tt.doc))
+		var text []string
+		for _, sec := range sections {
+			text = append(text, strings.TrimSpace(sec.Text))
+		}
+		if title != tt.title || strings.Join(text, "\n") != tt.text {
+			t.Errorf("%s %q: title %q and text %q, want %q and %q", tt.name, tt.doc, title, strings.Join(text, "\n"), tt.title, tt.text)
+		}
+	}
+}
@@ -0,0 +1,439 @@
+// Package rag searches a corpus of local documents, so the assistant can ground its
+// answers in them (retrieval-augmented generation).
+//
+// Markdown, HTML and plain-text files are parsed into text, cut into chunks of a few
+// paragraphs and indexed in memory with BM25, a keyword ranking. With an Embedder the
+// chunks also get embedding vectors, and search ranks by meaning as well as by words.
+// Search returns the best chunks for a question as Hits; the assistant hands them to the
+// model and cites them as Sources of its answer (see Start).
+package rag
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"gonuxt-context-assistant/internal/llm"
+)
+
+// DefaultTopK is the number of hits Search returns when Index.TopK is not set.
+const DefaultTopK = 4
+
+const (
+	// embedBatch is the number of chunks embedded per request.
+	embedBatch = 64
+	// minSimilarity is the cosine similarity from which a chunk counts as relevant even
+	// when it shares no word with the question.
+	minSimilarity = 0.4
+	// embedWeight is the share of the embedding similarity in the score of a hit; the
+	// rest is the BM25 score, scaled to the best hit.
+	embedWeight = 0.5
+)
+
+var (
+	// ErrUnsupported is returned for a file that is not Markdown, HTML or text.
+	ErrUnsupported = errors.New("rag: unsupported document format")
+	// ErrDuplicate is returned when a document with the same content is already indexed.
+	ErrDuplicate = errors.New("rag: document already indexed")
+	// ErrEmpty is returned for a document without any text.
+	ErrEmpty = errors.New("rag: document has no text")
+)
+
+// Input is a document to index.
+type Input struct {
+	Name   string // file name or path, e.g. "guides/lisbon.md"; may be empty
+	Title  string // optional: by default the first heading, or else the file name
+	Format Format // optional: by default told from the extension of Name, else text
+	Data   []byte
+}
+
+// ResolveFormat returns the format of the input, or ErrUnsupported.
+func (in Input) ResolveFormat() (Format, error) {
+	switch in.Format {
+	case FormatMarkdown, FormatHTML, FormatText:
+		return in.Format, nil
+	case "":
+		if format, ok := FormatOf(in.Name); ok {
+			return format, nil
+		}
+		if path.Ext(in.Name) == "" {
+			return FormatText, nil
+		}
+		return "", fmt.Errorf("%w: %s", ErrUnsupported, in.Name)
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnsupported, in.Format)
+}
+
+// contentID returns the hash of a document's content and the document ID derived from it.
+func contentID(data []byte) (id, hash string) {
+	sum := sha256.Sum256(data)
+	hash = hex.EncodeToString(sum[:])
+	return hash[:16], hash
+}
+
+// Document is an indexed document.
+type Document struct {
+	ID      string    `json:"id"` // from the hash of the content
+	Title   string    `json:"title"`
+	Path    string    `json:"path,omitempty"` // the file it was read from, if any
+	Format  Format    `json:"format"`
+	Hash    string    `json:"hash"` // SHA-256 of the content, hex encoded
+	Size    int       `json:"size"` // in bytes
+	Chunks  int       `json:"chunks"`
+	AddedAt time.Time `json:"addedAt"`
+}
+
+// Source says where a passage of an answer came from.
+type Source struct {
+	DocumentID string  `json:"documentId"`
+	Title      string  `json:"title"`
+	Path       string  `json:"path,omitempty"`
+	Heading    string  `json:"heading,omitempty"` // the section of the document
+	Chunk      int     `json:"chunk"`             // position of the passage in the document, from 0
+	Score      float64 `json:"score"`
+}
+
+// Hit is a passage found by Search.
+type Hit struct {
+	Source
+	Text string `json:"text"`
+}
+
+// chunk is one passage of a document, the unit of search.
+type chunk struct {
+	doc     *Document
+	index   int
+	heading string
+	text    string
+	terms   map[string]int // word counts of heading and text
+	length  int            // number of words
+	vector  []float32      // nil without an Embedder
+}
+
+// Index is an in-memory search index of documents. It is safe for concurrent use.
+type Index struct {
+	// Embedder adds embedding vectors to the keyword ranking. Optional.
+	Embedder llm.Embedder
+	// ChunkTokens is the size of the chunks documents are cut into (default 200).
+	ChunkTokens int
+	// TopK is the number of hits of Search (default 4).
+	TopK int
+
+	mu          sync.RWMutex
+	docs        map[string]*Document
+	chunks      []*chunk
+	df          map[string]int // number of chunks containing each word
+	totalLength int
+}
+
+// NewIndex creates an empty Index.
+func NewIndex() *Index {
+	return &Index{docs: make(map[string]*Document), df: make(map[string]int)}
+}
+
+// Add parses, chunks and indexes a document. A document whose content is already indexed
+// is not added again: Add returns the indexed one and ErrDuplicate.
+func (x *Index) Add(ctx context.Context, in Input) (Document, error) {
+	format, err := in.ResolveFormat()
+	if err != nil {
+		return Document{}, err
+	}
+	id, hash := contentID(in.Data)
+	if doc, ok := x.Get(id); ok {
+		return doc, ErrDuplicate
+	}
+	name := in.Name
+
+	title, sections := parse(name, format, in.Data)
+	if in.Title != "" {
+		title = in.Title
+	}
+	parts := chunkText(sections, x.chunkTokens())
+	if len(parts) == 0 {
+		return Document{}, fmt.Errorf("%w: %s", ErrEmpty, name)
+	}
+
+	doc := &Document{ID: id, Title: title, Path: name, Format: format, Hash: hash, Size: len(in.Data), Chunks: len(parts)}
+	chunks := make([]*chunk, len(parts))
+	texts := make([]string, len(parts))
+	for i, part := range parts {
+		c := &chunk{doc: doc, index: i, heading: part.Heading, text: part.Text}
+		c.terms, c.length = termCounts(part.Heading + "\n" + part.Text)
+		chunks[i] = c
+		texts[i] = part.Text
+		if part.Heading != "" {
+			texts[i] = part.Heading + "\n\n" + part.Text
+		}
+	}
+	if x.Embedder != nil {
+		for start := 0; start < len(texts); start += embedBatch {
+			end := min(start+embedBatch, len(texts))
+			vectors, err := x.Embedder.Embed(ctx, texts[start:end])
+			if err != nil {
+				return Document{}, fmt.Errorf("rag: embed %s: %w", name, err)
+			}
+			for i, vector := range vectors {
+				chunks[start+i].vector = vector
+			}
+		}
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if existing, ok := x.docs[id]; ok { // added meanwhile
+		return *existing, ErrDuplicate
+	}
+	doc.AddedAt = time.Now()
+	x.docs[id] = doc
+	for _, c := range chunks {
+		x.chunks = append(x.chunks, c)
+		x.totalLength += c.length
+		for term := range c.terms {
+			x.df[term]++
+		}
+	}
+	return *doc, nil
+}
+
+// AddDir indexes the supported files of dir and its subdirectories, skipping other files
+// and duplicates. Documents are named by their path relative to dir. It returns how many
+// were added, and the errors of the files that could not be.
+func (x *Index) AddDir(ctx context.Context, dir string) (int, error) {
+	added := 0
+	var errs []error
+	err := filepath.WalkDir(dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return ctx.Err()
+		}
+		if _, ok := FormatOf(file); !ok {
+			return nil
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		name, _ := filepath.Rel(dir, file)
+		_, err = x.Add(ctx, Input{Name: filepath.ToSlash(name), Data: data})
+		switch {
+		case err == nil:
+			added++
+		case errors.Is(err, ErrDuplicate), errors.Is(err, ErrEmpty):
+		default:
+			errs = append(errs, err)
+		}
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return added, errors.Join(errs...)
+}
+
+// Remove drops a document from the index. It reports false for an unknown ID.
+func (x *Index) Remove(id string) bool {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if _, ok := x.docs[id]; !ok {
+		return false
+	}
+	delete(x.docs, id)
+	kept := x.chunks[:0]
+	for _, c := range x.chunks {
+		if c.doc.ID != id {
+			kept = append(kept, c)
+			continue
+		}
+		x.totalLength -= c.length
+		for term := range c.terms {
+			if x.df[term]--; x.df[term] == 0 {
+				delete(x.df, term)
+			}
+		}
+	}
+	clear(x.chunks[len(kept):]) // let the removed chunks be collected
+	x.chunks = kept
+	return true
+}
+
+// Get returns an indexed document.
+func (x *Index) Get(id string) (Document, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	doc, ok := x.docs[id]
+	if !ok {
+		return Document{}, false
+	}
+	return *doc, true
+}
+
+// Documents lists the indexed documents, oldest first.
+func (x *Index) Documents() []Document {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	docs := make([]Document, 0, len(x.docs))
+	for _, doc := range x.docs {
+		docs = append(docs, *doc)
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		if !docs[i].AddedAt.Equal(docs[j].AddedAt) {
+			return docs[i].AddedAt.Before(docs[j].AddedAt)
+		}
+		return docs[i].Path < docs[j].Path
+	})
+	return docs
+}
+
+// Search returns the TopK chunks most relevant to query, best first. A chunk is relevant
+// when it contains at least half of the question's words, or, with an Embedder, when its
+// meaning is close enough. If the question cannot be embedded, Search still returns the
+// keyword hits, along with the error.
+func (x *Index) Search(ctx context.Context, query string) ([]Hit, error) {
+	var queryTerms []string
+	seen := make(map[string]bool)
+	for _, term := range terms(query) {
+		if !seen[term] {
+			seen[term] = true
+			queryTerms = append(queryTerms, term)
+		}
+	}
+
+	var (
+		queryVector []float32
+		embedErr    error
+	)
+	if x.Embedder != nil {
+		vectors, err := x.Embedder.Embed(ctx, []string{query})
+		switch {
+		case err != nil:
+			embedErr = fmt.Errorf("rag: embed question: %w", err)
+		case len(vectors) != 1:
+			embedErr = errors.New("rag: embed question: no vector returned")
+		default:
+			queryVector = vectors[0]
+		}
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if len(x.chunks) == 0 {
+		return nil, embedErr
+	}
+	avgLength := float64(x.totalLength) / float64(len(x.chunks))
+	if avgLength == 0 {
+		avgLength = 1
+	}
+
+	type scored struct {
+		c          *chunk
+		bm25       float64
+		similarity float64
+	}
+	var candidates []scored
+	maxBM25 := 0.0
+	for _, c := range x.chunks {
+		score, matched := bm25(c, queryTerms, len(x.chunks), x.df, avgLength)
+		similarity := 0.0
+		if queryVector != nil {
+			similarity = cosine(queryVector, c.vector)
+		}
+		keywordHit := len(queryTerms) > 0 && matched*2 >= len(queryTerms)
+		if !keywordHit && similarity < minSimilarity {
+			continue
+		}
+		candidates = append(candidates, scored{c: c, bm25: score, similarity: similarity})
+		maxBM25 = max(maxBM25, score)
+	}
+
+	hits := make([]Hit, 0, len(candidates))
+	for _, cand := range candidates {
+		score := cand.bm25
+		if queryVector != nil {
+			keyword := 0.0
+			if maxBM25 > 0 {
+				keyword = cand.bm25 / maxBM25
+			}
+			score = (1-embedWeight)*keyword + embedWeight*cand.similarity
+		}
+		hits = append(hits, Hit{
+			Source: Source{
+				DocumentID: cand.c.doc.ID,
+				Title:      cand.c.doc.Title,
+				Path:       cand.c.doc.Path,
+				Heading:    cand.c.heading,
+				Chunk:      cand.c.index,
+				Score:      score,
+			},
+			Text: cand.c.text,
+		})
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if k := x.topK(); len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits, embedErr
+}
+
+func (x *Index) chunkTokens() int {
+	if x.ChunkTokens > 0 {
+		return x.ChunkTokens
+	}
+	return DefaultChunkTokens
+}
+
+func (x *Index) topK() int {
+	if x.TopK > 0 {
+		return x.TopK
+	}
+	return DefaultTopK
+}
+
+// Citations collects the sources of one answer.
+type Citations struct {
+	mu      sync.Mutex
+	sources []Source
+}
+
+// Sources returns the sources cited so far, or nil.
+func (c *Citations) Sources() []Source {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Source(nil), c.sources...)
+}
+
+type citationsKey struct{}
+
+// Start returns a context whose cited sources (see Cite) are collected in the returned
+// Citations. If ctx already collects them, it is returned as is, with its Citations.
+func Start(ctx context.Context) (context.Context, *Citations) {
+	if c, ok := ctx.Value(citationsKey{}).(*Citations); ok {
+		return ctx, c
+	}
+	c := &Citations{}
+	return context.WithValue(ctx, citationsKey{}, c), c
+}
+
+// Cite records the hits an answer was based on, if ctx collects sources.
+func Cite(ctx context.Context, hits ...Hit) {
+	c, ok := ctx.Value(citationsKey{}).(*Citations)
+	if !ok {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, hit := range hits {
+		c.sources = append(c.sources, hit.Source)
+	}
+}
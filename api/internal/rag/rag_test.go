@@ -0,0 +1,174 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var corpus = []Input{
+	{Name: "lisbon.md", Data: []byte("# Lisbon\n\nThe yellow tram 28 climbs from Baixa to the castle of São Jorge.")},
+	{Name: "porto.md", Data: []byte("# Porto\n\nPort wine cellars line the Douro river in Gaia.")},
+	{Name: "castle.md", Data: []byte("# Castle\n\nThe castle opens at nine. The castle closes at six. Tickets for the castle are sold at the gate.")},
+	{Name: "trams.txt", Data: []byte("Trams\n\nTrams run in Lisbon and Porto. The tram is the best way up the hills.")},
+}
+
+// newCorpus indexes the corpus.
+func newCorpus(t *testing.T, embedder *embedder) *Index {
+	t.Helper()
+	index := NewIndex()
+	if embedder != nil {
+		index.Embedder = embedder
+	}
+	for _, in := range corpus {
+		if _, err := index.Add(context.Background(), in); err != nil {
+			t.Fatalf("Add(%s): %v", in.Name, err)
+		}
+	}
+	return index
+}
+
+func titles(hits []Hit) []string {
+	out := []string{}
+	for _, hit := range hits {
+		out = append(out, hit.Title)
+	}
+	return out
+}
+
+func TestSearchRanksByBM25(t *testing.T) {
+	index := newCorpus(t, nil)
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"port wine", []string{"Porto"}},
+		{"When does the castle open?", []string{"Castle", "Lisbon"}},
+		{"tram", []string{"Lisbon", "Trams"}},
+		{"trams in Lisbon", []string{"Trams", "Lisbon"}},
+		// Castle has only one of the three words: less than half is no hit.
+		{"Lisbon castle tram", []string{"Lisbon", "Trams"}},
+		{"streetcar", []string{}},
+		{"What is the", []string{}},
+	}
+	for _, tt := range tests {
+		hits, err := index.Search(context.Background(), tt.query)
+		if err != nil {
+			t.Errorf("%q: %v", tt.query, err)
+		}
+		if got := titles(hits); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: hits %q, want %q", tt.query, got, tt.want)
+		}
+	}
+
+	index.TopK = 1
+	if hits, _ := index.Search(context.Background(), "trams in Lisbon"); len(hits) != 1 || hits[0].Title != "Trams" || hits[0].Path != "trams.txt" {
+		t.Errorf("TopK 1: hits %+v, want the Trams one", hits)
+	}
+}
+
+// embedder embeds a text as one dimension per topic: 1 when it has a word of the topic.
+type embedder struct {
+	err error
+}
+
+var topics = [][]string{
+	{"tram", "trams", "streetcar", "trolley"},
+	{"wine", "port", "vineyard"},
+	{"castle", "fortress"},
+}
+
+func (e *embedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = make([]float32, len(topics))
+		words := terms(text)
+		for d, topic := range topics {
+			for _, word := range topic {
+				for _, w := range words {
+					if w == word {
+						vectors[i][d] = 1
+					}
+				}
+			}
+		}
+	}
+	return vectors, nil
+}
+
+func TestSearchMergesKeywordsAndEmbeddings(t *testing.T) {
+	index := newCorpus(t, &embedder{})
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+		score float64 // of the best hit
+	}{
+		// Shares no word with any document: found by meaning alone. Trams is only about
+		// trams, Lisbon also about a castle.
+		{"meaning only", "streetcar", []string{"Trams", "Lisbon"}, embedWeight},
+		{"meaning only, other topic", "vineyard", []string{"Porto"}, embedWeight},
+		// The best BM25 hit with the same meaning scores 1.
+		{"words and meaning", "port wine", []string{"Porto"}, 1},
+		// Castle has the word most often, Lisbon is closest in meaning, Trams only close.
+		{"words then meaning", "castle trolley", []string{"Castle", "Lisbon", "Trams"}, 0},
+		{"neither", "opening hours", []string{}, 0},
+	}
+	for _, tt := range tests {
+		hits, err := index.Search(context.Background(), tt.query)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if got := titles(hits); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: hits %q, want %q", tt.name, got, tt.want)
+			continue
+		}
+		if tt.score > 0 && (hits[0].Score < tt.score-1e-9 || hits[0].Score > tt.score+1e-9) {
+			t.Errorf("%s: best score %v, want %v", tt.name, hits[0].Score, tt.score)
+		}
+		for _, hit := range hits {
+			if hit.Score < 0 || hit.Score > 1 {
+				t.Errorf("%s: score %v of %s is not between 0 and 1", tt.name, hit.Score, hit.Title)
+			}
+		}
+	}
+}
+
+func TestSearchFallsBackToKeywords(t *testing.T) {
+	e := &embedder{}
+	index := newCorpus(t, e)
+	e.err = errors.New("embeddings are down")
+
+	hits, err := index.Search(context.Background(), "port wine")
+	if err == nil || !strings.Contains(err.Error(), "embeddings are down") {
+		t.Errorf("error %v, want the embedding error", err)
+	}
+	if got := titles(hits); !reflect.DeepEqual(got, []string{"Porto"}) {
+		t.Errorf("hits %q, want the keyword hit", got)
+	}
+	if hits, _ := index.Search(context.Background(), "streetcar"); len(hits) != 0 {
+		t.Errorf("hits %q found by meaning without embeddings", titles(hits))
+	}
+}
+
+func TestAddRejectsDuplicatesAndEmptyDocuments(t *testing.T) {
+	index := newCorpus(t, nil)
+	doc, err := index.Add(context.Background(), Input{Name: "copy.md", Data: corpus[0].Data})
+	if !errors.Is(err, ErrDuplicate) || doc.Title != "Lisbon" || doc.Path != "lisbon.md" {
+		t.Errorf("duplicate: %+v, %v; want the indexed document and ErrDuplicate", doc, err)
+	}
+	if _, err := index.Add(context.Background(), Input{Name: "empty.md", Data: []byte("# \n\n```\n```\n")}); !errors.Is(err, ErrEmpty) {
+		t.Errorf("empty: %v, want ErrEmpty", err)
+	}
+	if _, err := index.Add(context.Background(), Input{Name: "slides.pdf", Data: []byte("%PDF")}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("pdf: %v, want ErrUnsupported", err)
+	}
+	if n := len(index.Documents()); n != len(corpus) {
+		t.Errorf("%d documents, want %d", n, len(corpus))
+	}
+}
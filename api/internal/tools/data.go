@@ -0,0 +1,213 @@
+package tools
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The reference data behind GetWeather and GetCapital. It used to be hard-coded inside
+// those functions; it lives here so it can be browsed (e.g. as MCP resources) and updated
+// at runtime, with watchers told about every change.
+
+// Dataset names reported in a DataChange.
+const (
+	DatasetWeather  = "weather"
+	DatasetCapitals = "capitals"
+)
+
+// DataChange describes a single update to one of the datasets.
+type DataChange struct {
+	Dataset string // DatasetWeather or DatasetCapitals
+	Key     string // the city or country that changed
+	Added   bool   // true when the key did not exist before
+}
+
+// Weather is the simulated weather of one city.
+type Weather struct {
+	City        string  `json:"city"`
+	Condition   string  `json:"condition"`   // e.g. "sunny", "partly cloudy"
+	Temperature float64 `json:"temperature"` // in Unit
+	Unit        string  `json:"unit"`        // always "celsius" for now
+	Report      string  `json:"report"`      // the sentence used by GetWeather, e.g. "sunny with 28°C."
+}
+
+// UnitCelsius is the temperature unit of the simulated data.
+const UnitCelsius = "celsius"
+
+// Capital pairs a country with its capital city.
+type Capital struct {
+	Country string `json:"country"`
+	Capital string `json:"capital"`
+}
+
+var (
+	dataMu sync.RWMutex
+
+	// weatherData is keyed by lower-case city name.
+	weatherData = map[string]Weather{
+		"lisbon":   {City: "Lisbon", Condition: "sunny", Temperature: 28, Unit: UnitCelsius, Report: "sunny with 28°C."},
+		"london":   {City: "London", Condition: "cloudy", Temperature: 18, Unit: UnitCelsius, Report: "cloudy with 18°C."},
+		"new york": {City: "New York", Condition: "partly cloudy", Temperature: 22, Unit: UnitCelsius, Report: "partly cloudy with 22°C."},
+		"paris":    {City: "Paris", Condition: "clear", Temperature: 20, Unit: UnitCelsius, Report: "a delightful 20°C."},
+		"tokyo":    {City: "Tokyo", Condition: "rainy", Temperature: 15, Unit: UnitCelsius, Report: "rainy with 15°C."}, // Added for variety
+	}
+
+	// capitalData is keyed by lower-case country name.
+	capitalData = map[string]Capital{
+		"portugal":       {Country: "Portugal", Capital: "Lisbon"},
+		"united kingdom": {Country: "United Kingdom", Capital: "London"},
+		"united states":  {Country: "United States", Capital: "Washington, D.C."},
+	}
+
+	watchers    = map[int]func(DataChange){} // by the ID Watch handed out
+	nextWatcher int
+)
+
+// knownCities are the cities recognised in free-form queries, in matching order.
+// It used to be copied into every function that looked for cities.
+var knownCities = []string{"Lisbon", "London", "New York", "Paris", "Tokyo", "Porto", "Berlin", "Madrid"}
+
+// KnownCities returns the cities the assistant understands: the fixed list above plus
+// any city that got a weather report at runtime.
+func KnownCities() []string {
+	cities := append([]string{}, knownCities...)
+	for _, city := range WeatherCities() {
+		if !containsFold(cities, city) {
+			cities = append(cities, city)
+		}
+	}
+	return cities
+}
+
+// Countries returns every country with a known capital, sorted.
+func Countries() []string {
+	capitals := Capitals()
+	countries := make([]string, 0, len(capitals))
+	for _, entry := range capitals {
+		countries = append(countries, entry.Country)
+	}
+	return countries
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// WeatherReport returns the raw report (e.g. "sunny with 28°C.") for a city.
+func WeatherReport(city string) (string, bool) {
+	weather, found := WeatherFor(city)
+	return weather.Report, found
+}
+
+// WeatherFor returns the structured weather of a city, ignoring case.
+func WeatherFor(city string) (Weather, bool) {
+	dataMu.RLock()
+	defer dataMu.RUnlock()
+	weather, found := weatherData[strings.ToLower(strings.TrimSpace(city))]
+	return weather, found
+}
+
+// WeatherCities returns the cities with a weather report, sorted and capitalized for display.
+func WeatherCities() []string {
+	dataMu.RLock()
+	defer dataMu.RUnlock()
+	cities := make([]string, 0, len(weatherData))
+	for _, weather := range weatherData {
+		cities = append(cities, weather.City)
+	}
+	sort.Strings(cities)
+	return cities
+}
+
+// SetWeather adds or replaces the weather of weather.City and notifies watchers.
+// Missing fields are filled in: Unit defaults to celsius and Report is built from the
+// condition and temperature.
+func SetWeather(weather Weather) {
+	weather.City = strings.TrimSpace(weather.City)
+	if weather.Unit == "" {
+		weather.Unit = UnitCelsius
+	}
+	if weather.Report == "" {
+		weather.Report = fmt.Sprintf("%s with %g°C.", weather.Condition, weather.Temperature)
+	}
+
+	key := strings.ToLower(weather.City)
+	dataMu.Lock()
+	_, existed := weatherData[key]
+	weatherData[key] = weather
+	dataMu.Unlock()
+
+	notify(DataChange{Dataset: DatasetWeather, Key: weather.City, Added: !existed})
+}
+
+// CapitalOf returns the capital of a country, ignoring case.
+func CapitalOf(country string) (string, bool) {
+	dataMu.RLock()
+	defer dataMu.RUnlock()
+	entry, found := capitalData[strings.ToLower(strings.TrimSpace(country))]
+	return entry.Capital, found
+}
+
+// Capitals returns every known country/capital pair sorted by country.
+func Capitals() []Capital {
+	dataMu.RLock()
+	defer dataMu.RUnlock()
+	capitals := make([]Capital, 0, len(capitalData))
+	for _, entry := range capitalData {
+		capitals = append(capitals, entry)
+	}
+	sort.Slice(capitals, func(i, j int) bool { return capitals[i].Country < capitals[j].Country })
+	return capitals
+}
+
+// SetCapital adds or replaces the capital of a country and notifies watchers.
+func SetCapital(country, capital string) {
+	country = strings.TrimSpace(country)
+	key := strings.ToLower(country)
+	dataMu.Lock()
+	_, existed := capitalData[key]
+	capitalData[key] = Capital{Country: country, Capital: capital}
+	dataMu.Unlock()
+
+	notify(DataChange{Dataset: DatasetCapitals, Key: country, Added: !existed})
+}
+
+// Watch registers fn to be called after every change to the datasets, until the returned
+// function is called. Watchers run synchronously, so they should hand slow work off to a
+// goroutine.
+func Watch(fn func(DataChange)) (unwatch func()) {
+	dataMu.Lock()
+	defer dataMu.Unlock()
+	nextWatcher++
+	id := nextWatcher
+	watchers[id] = fn
+	return func() {
+		dataMu.Lock()
+		defer dataMu.Unlock()
+		delete(watchers, id)
+	}
+}
+
+func notify(change DataChange) {
+	dataMu.RLock()
+	ids := make([]int, 0, len(watchers))
+	for id := range watchers {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids) // in the order they started watching
+	current := make([]func(DataChange), len(ids))
+	for i, id := range ids {
+		current[i] = watchers[id]
+	}
+	dataMu.RUnlock()
+	for _, fn := range current {
+		fn(change)
+	}
+}
@@ -0,0 +1,24 @@
+package tools
+
+import "testing"
+
+func TestUnwatchStopsNotifications(t *testing.T) {
+	original, _ := WeatherFor("Tokyo")
+	t.Cleanup(func() { SetWeather(original) })
+
+	var first, second []DataChange
+	unwatch := Watch(func(change DataChange) { first = append(first, change) })
+	t.Cleanup(Watch(func(change DataChange) { second = append(second, change) }))
+
+	SetWeather(Weather{City: "Tokyo", Condition: "cloudy", Temperature: 14})
+	unwatch()
+	unwatch() // a second call is harmless
+	SetWeather(Weather{City: "Tokyo", Condition: "sunny", Temperature: 18})
+
+	if len(first) != 1 || first[0].Key != "Tokyo" || first[0].Dataset != DatasetWeather {
+		t.Errorf("first watcher got %+v, want only the change before unwatch", first)
+	}
+	if len(second) != 2 {
+		t.Errorf("second watcher got %d changes, want 2", len(second))
+	}
+}
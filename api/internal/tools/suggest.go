@@ -0,0 +1,136 @@
+package tools
+
+import (
+	"sort"
+	"strings"
+)
+
+// Match quality used to rank suggestions, best first.
+const (
+	matchPrefix      = iota // "lis" -> Lisbon
+	matchWordPrefix         // "york" -> New York
+	matchSubstring          // "ond" -> London
+	matchSubsequence        // "nyk" -> New York
+	matchTypo               // "lodn" -> London
+	noMatch
+)
+
+// SuggestCities returns the known cities matching what the user typed so far.
+func SuggestCities(value string) []string {
+	return Suggest(KnownCities(), value)
+}
+
+// SuggestCountries returns the countries with a known capital matching what the user typed so far.
+func SuggestCountries(value string) []string {
+	return Suggest(Countries(), value)
+}
+
+// Suggest ranks candidates against a partial value, ignoring case. Prefix matches come
+// first, then matches on a later word, substrings, letters in order (subsequence) and
+// finally small typos. Candidates that match in none of these ways are left out; an
+// empty value returns every candidate.
+func Suggest(candidates []string, value string) []string {
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	type ranked struct {
+		candidate string
+		rank      int
+	}
+	var matches []ranked
+	for _, candidate := range candidates {
+		if rank := matchRank(strings.ToLower(candidate), value); rank != noMatch {
+			matches = append(matches, ranked{candidate: candidate, rank: rank})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].rank != matches[j].rank {
+			return matches[i].rank < matches[j].rank
+		}
+		return matches[i].candidate < matches[j].candidate
+	})
+
+	suggestions := make([]string, len(matches))
+	for i, m := range matches {
+		suggestions[i] = m.candidate
+	}
+	return suggestions
+}
+
+func matchRank(candidate, value string) int {
+	switch {
+	case strings.HasPrefix(candidate, value):
+		return matchPrefix
+	case hasWordPrefix(candidate, value):
+		return matchWordPrefix
+	case strings.Contains(candidate, value):
+		return matchSubstring
+	case isSubsequence(candidate, value):
+		return matchSubsequence
+	case isTypo(candidate, value):
+		return matchTypo
+	}
+	return noMatch
+}
+
+func hasWordPrefix(candidate, value string) bool {
+	for _, word := range strings.Fields(candidate) {
+		if strings.HasPrefix(word, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSubsequence reports whether all letters of value appear in candidate, in order.
+func isSubsequence(candidate, value string) bool {
+	rest := candidate
+	for _, r := range value {
+		if r == ' ' {
+			continue
+		}
+		i := strings.IndexRune(rest, r)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(string(r)):]
+	}
+	return true
+}
+
+// isTypo compares value with the start of candidate, allowing one edit per three letters.
+func isTypo(candidate, value string) bool {
+	v := []rune(value)
+	if len(v) < 3 {
+		return false // too short to tell a typo from a different word
+	}
+	c := []rune(candidate)
+	if len(c) > len(v)+1 {
+		c = c[:len(v)+1]
+	}
+	return editDistance(c, v) <= len(v)/3 || editDistance(c[:min(len(c), len(v))], v) <= len(v)/3
+}
+
+// editDistance is the Damerau-Levenshtein distance (with adjacent swaps) between a and b.
+func editDistance(a, b []rune) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
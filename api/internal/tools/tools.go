@@ -16,14 +16,19 @@ func GetCurrentDateTime() string {
 	// The layout "2006-01-02 15:04:05" is a magic number in Go's time package,
 	// representing a specific reference date (Jan 2, 2006, 3:04:05 PM).
 	// You use this specific reference to define the desired output format.
-	return time.Now().Format("Current time is Monday, January 2, 2006 at 15:04:05 PM (MST)")
+	return FormatDateTime(time.Now())
 }
 
+// FormatDateTime renders t the way GetCurrentDateTime does, for callers that also need the time.Time.
+func FormatDateTime(t time.Time) string {
+	return t.Format("Current time is Monday, January 2, 2006 at 15:04:05 PM (MST)")
+}
+
+// ExtractCitiesFromQuery returns every known city mentioned in the query.
 func ExtractCitiesFromQuery(query string) []string {
-	knownCities := []string{"Lisbon", "London", "New York", "Paris", "Berlin", "Madrid"}
 	var foundCities []string
 
-	for _, city := range knownCities {
+	for _, city := range KnownCities() {
 		if strings.Contains(strings.ToLower(query), strings.ToLower(city)) {
 			foundCities = append(foundCities, city)
 		}
@@ -43,21 +48,39 @@ func GetWeather(city string) (string, bool) {
 	// 	// Original weather logic
 
 	// }
-	weatherData := map[string]string{
-		"lisbon":   "sunny with 28°C.",
-		"london":   "cloudy with 18°C.",
-		"new york": "partly cloudy with 22°C.",
-		"paris":    "a delightful 20°C.",
-		"tokyo":    "rainy with 15°C.", // Added for variety
-	}
 	log.Printf("GetWeather called for %s, arg: %s", city, strings.ToLower(city)) // Log the city being queried.
-	report, found := weatherData[strings.ToLower(city)]
+	report, found := WeatherReport(city)
+	if sentence := weatherSentenceFunc(); sentence != nil {
+		text, err := sentence(city, report)
+		if err == nil {
+			return text, found
+		}
+		log.Printf("Weather sentence failed, using the built-in one: %v", err)
+	}
 	if found {
 		return fmt.Sprintf("The weather in %s is currently %s", city, report), true
 	}
 	return fmt.Sprintf("No weather information found for %s.", city), false
 
 }
+
+// weatherSentence words the answer of GetWeather when set (see SetWeatherSentence).
+var weatherSentence func(city, report string) (string, error)
+
+// SetWeatherSentence replaces the sentence of GetWeather, e.g. with a prompt template
+// (see internal/prompts). report is "" for an unknown city. nil restores the built-in
+// sentence, which is also used when fn fails.
+func SetWeatherSentence(fn func(city, report string) (string, error)) {
+	dataMu.Lock()
+	weatherSentence = fn
+	dataMu.Unlock()
+}
+
+func weatherSentenceFunc() func(city, report string) (string, error) {
+	dataMu.RLock()
+	defer dataMu.RUnlock()
+	return weatherSentence
+}
 func GetWeatherForCities(ctx context.Context, cities []string) (map[string]string, error) {
 	// This function takes a slice of city names and returns a map with city names as keys
 	// and their corresponding weather reports as values.
@@ -85,16 +108,10 @@ func GetCapital(country string) string {
 func assessCapital(country string) string {
 	// This is a private function, as it starts with a lowercase letter.
 	// It can only be used within this package.
-	switch country {
-	case "Portugal":
-		return "Lisbon"
-	case "United Kingdom":
-		return "London"
-	case "United States":
-		return "Washington, D.C."
-	default:
-		return fmt.Sprintf("I don't know the capital of %s. Please check your input.", country)
+	if capital, found := CapitalOf(country); found {
+		return capital
 	}
+	return fmt.Sprintf("I don't know the capital of %s. Please check your input.", country)
 }
 
 // function with two arguments, go context and a method to call wich can be GetWeather or GetCapital
@@ -157,14 +174,29 @@ func GetData(ctx context.Context, method string, arg interface{}) (interface{},
 func GetData[T any](ctx context.Context, arg T, fn func(T) (string, bool)) (string, error) {
 	// This function takes a context and a function to call with the argument of type T.
 	// It returns the result of the function call or an error if the context is cancelled.
+	// fn runs in its own goroutine so a cancelled context stops the wait immediately,
+	// even while fn is still busy (e.g. a slow weather API).
+
+	if err := ctx.Err(); err != nil {
+		return "", err // Already cancelled, don't even start.
+	}
+
+	type outcome struct {
+		result string
+		found  bool
+	}
+	done := make(chan outcome, 1) // Buffered so the goroutine never blocks if nobody is listening anymore.
+	go func() {
+		result, found := fn(arg)
+		done <- outcome{result: result, found: found}
+	}()
 
 	select {
 	case <-ctx.Done():
 		return "", ctx.Err() // If the context is cancelled, return an error.
-	default: // Simulate a delay for the operation.
-		result, found := fn(arg)
-		if found {
-			return result, nil
+	case out := <-done:
+		if out.found {
+			return out.result, nil
 		}
 		return "", fmt.Errorf("data for %v could not be found", arg)
 	}
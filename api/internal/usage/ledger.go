@@ -0,0 +1,119 @@
+package usage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+const (
+	// AnonymousKey labels the calls of clients that sent no API key.
+	AnonymousKey = "anonymous"
+	// maxSessions bounds the sessions a Ledger keeps; the least recently active go first.
+	maxSessions = 10000
+)
+
+// Ledger adds up model calls since it was created: in total, per API key and per session.
+// It lives in memory, so the totals start over when the API restarts.
+type Ledger struct {
+	mu       sync.Mutex
+	since    time.Time
+	total    Totals
+	keys     map[string]*Totals
+	sessions map[string]*sessionTotals
+}
+
+type sessionTotals struct {
+	Totals
+	lastCall time.Time
+}
+
+// Report is a snapshot of a Ledger.
+type Report struct {
+	Since time.Time `json:"since"`
+	Total Totals    `json:"total"`
+	// Keys are labelled with KeyLabel, so the report shows no API key in the clear.
+	Keys     map[string]Totals `json:"keys"`
+	Sessions map[string]Totals `json:"sessions"`
+}
+
+// NewLedger creates an empty Ledger.
+func NewLedger() *Ledger {
+	return &Ledger{
+		since:    time.Now(),
+		keys:     make(map[string]*Totals),
+		sessions: make(map[string]*sessionTotals),
+	}
+}
+
+// KeyLabel identifies an API key in reports without revealing it: "key-" and the first
+// 8 hex digits of its SHA-256, or AnonymousKey.
+func KeyLabel(key string) string {
+	if key == "" {
+		return AnonymousKey
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "key-" + hex.EncodeToString(sum[:4])
+}
+
+// Record counts a call billed to account.
+func (l *Ledger) Record(account Account, call Call) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.total.Add(call)
+
+	label := KeyLabel(account.Key)
+	if l.keys[label] == nil {
+		l.keys[label] = &Totals{}
+	}
+	l.keys[label].Add(call)
+
+	if account.Session == "" {
+		return
+	}
+	session := l.sessions[account.Session]
+	if session == nil {
+		if len(l.sessions) >= maxSessions {
+			l.dropOldestSession()
+		}
+		session = &sessionTotals{}
+		l.sessions[account.Session] = session
+	}
+	session.Add(call)
+	session.lastCall = time.Now()
+}
+
+// Report returns the totals so far.
+func (l *Ledger) Report() Report {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	report := Report{
+		Since:    l.since,
+		Total:    l.total,
+		Keys:     make(map[string]Totals, len(l.keys)),
+		Sessions: make(map[string]Totals, len(l.sessions)),
+	}
+	for label, totals := range l.keys {
+		report.Keys[label] = *totals
+	}
+	for id, session := range l.sessions {
+		report.Sessions[id] = session.Totals
+	}
+	return report
+}
+
+// dropOldestSession forgets the least recently active session; l.mu must be held.
+func (l *Ledger) dropOldestSession() {
+	var (
+		oldestID string
+		oldest   time.Time
+	)
+	for id, session := range l.sessions {
+		if oldestID == "" || session.lastCall.Before(oldest) {
+			oldestID, oldest = id, session.lastCall
+		}
+	}
+	delete(l.sessions, oldestID)
+}
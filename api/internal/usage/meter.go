@@ -0,0 +1,50 @@
+package usage
+
+import (
+	"context"
+
+	"gonuxt-context-assistant/internal/llm"
+)
+
+// Meter is an llm.LLMProvider that counts the calls it passes on to Provider. Failed
+// calls are not counted; providers do not bill them.
+type Meter struct {
+	Provider llm.LLMProvider
+	Model    string  // the model Provider talks to, for its price
+	Prices   Prices  // optional: without prices only tokens are counted
+	Ledger   *Ledger // optional
+}
+
+// NewMeter wraps provider, recording its calls in ledger.
+func NewMeter(provider llm.LLMProvider, model string, prices Prices, ledger *Ledger) *Meter {
+	return &Meter{Provider: provider, Model: model, Prices: prices, Ledger: ledger}
+}
+
+// Chat implements llm.LLMProvider.
+func (m *Meter) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	resp, err := m.Provider.Chat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	call := Call{Usage: resp.Usage}
+	if call.Usage == (llm.Usage{}) {
+		// Some OpenAI-compatible servers send no usage; an estimate beats counting nothing.
+		call.Usage = llm.Usage{
+			PromptTokens:     llm.EstimateRequestTokens(req.Messages, req.Tools),
+			CompletionTokens: llm.EstimateMessageTokens(resp.Message),
+		}
+		call.Estimated = true
+	}
+	call.CostUSD, call.Priced = m.Prices.Cost(m.Model, call.Usage)
+
+	var account Account
+	if tally := tallyFrom(ctx); tally != nil {
+		tally.add(call)
+		account = tally.Account
+	}
+	if m.Ledger != nil {
+		m.Ledger.Record(account, call)
+	}
+	return resp, nil
+}
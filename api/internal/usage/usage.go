@@ -0,0 +1,102 @@
+// Package usage counts the tokens the language model is billed for and what they cost.
+//
+// Meter wraps the assistant's llm.LLMProvider, so every model call is counted, summaries
+// of long chats included. Each call is added to the Tally of its request (see Start),
+// which the /ask handler returns with the answer, and to a Ledger that adds up the calls
+// per API key and per session for the admin endpoint.
+package usage
+
+import (
+	"context"
+	"sync"
+
+	"gonuxt-context-assistant/internal/config"
+	"gonuxt-context-assistant/internal/llm"
+)
+
+// Account says who a model call is billed to.
+type Account struct {
+	Key     string // the client's API key; "" for anonymous clients
+	Session string // the assistant session, if any
+}
+
+// Call is one counted model call.
+type Call struct {
+	Usage     llm.Usage
+	CostUSD   float64
+	Priced    bool // the model has a price
+	Estimated bool // the provider reported no token counts, so they were estimated
+}
+
+// Totals adds up model calls.
+type Totals struct {
+	Calls            int     `json:"calls"`
+	PromptTokens     int     `json:"promptTokens"`
+	CompletionTokens int     `json:"completionTokens"`
+	TotalTokens      int     `json:"totalTokens"`
+	CostUSD          float64 `json:"costUsd"`
+	UnpricedCalls    int     `json:"unpricedCalls,omitempty"`  // not included in CostUSD
+	EstimatedCalls   int     `json:"estimatedCalls,omitempty"` // token counts are estimates
+}
+
+// Add counts one call.
+func (t *Totals) Add(call Call) {
+	t.Calls++
+	t.PromptTokens += call.Usage.PromptTokens
+	t.CompletionTokens += call.Usage.CompletionTokens
+	t.TotalTokens += call.Usage.PromptTokens + call.Usage.CompletionTokens
+	t.CostUSD += call.CostUSD
+	if !call.Priced {
+		t.UnpricedCalls++
+	}
+	if call.Estimated {
+		t.EstimatedCalls++
+	}
+}
+
+// Prices are the model prices of the config file (llm.prices), by model name.
+type Prices map[string]config.Price
+
+// Cost prices usage of model, in US dollars. It reports false for a model without a price.
+func (p Prices) Cost(model string, usage llm.Usage) (float64, bool) {
+	price, ok := p[model]
+	if !ok {
+		return 0, false
+	}
+	return (float64(usage.PromptTokens)*price.Input + float64(usage.CompletionTokens)*price.Output) / 1e6, true
+}
+
+// Tally collects the model calls made for one request.
+type Tally struct {
+	Account Account
+
+	mu     sync.Mutex
+	totals Totals
+}
+
+// Totals returns the calls counted so far.
+func (t *Tally) Totals() Totals {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.totals
+}
+
+func (t *Tally) add(call Call) {
+	t.mu.Lock()
+	t.totals.Add(call)
+	t.mu.Unlock()
+}
+
+type tallyKey struct{}
+
+// Start returns a context whose model calls are counted in the returned Tally and billed
+// to account.
+func Start(ctx context.Context, account Account) (context.Context, *Tally) {
+	tally := &Tally{Account: account}
+	return context.WithValue(ctx, tallyKey{}, tally), tally
+}
+
+func tallyFrom(ctx context.Context) *Tally {
+	tally, _ := ctx.Value(tallyKey{}).(*Tally)
+	return tally
+}
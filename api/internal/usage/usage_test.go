@@ -0,0 +1,180 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"gonuxt-context-assistant/internal/llm"
+)
+
+var prices = Prices{
+	"gpt-test":    {Input: 2.5, Output: 10},
+	"claude-test": {Input: 3, Output: 15},
+	"free-test":   {},
+}
+
+func closeTo(a, b float64) bool { return math.Abs(a-b) < 1e-12 }
+
+func TestPricesCost(t *testing.T) {
+	tests := []struct {
+		model  string
+		usage  llm.Usage
+		cost   float64
+		priced bool
+	}{
+		{"gpt-test", llm.Usage{PromptTokens: 1000, CompletionTokens: 500}, 0.0075, true},
+		{"gpt-test", llm.Usage{PromptTokens: 1_000_000}, 2.5, true},
+		{"claude-test", llm.Usage{PromptTokens: 1000, CompletionTokens: 500}, 0.0105, true},
+		{"claude-test", llm.Usage{CompletionTokens: 1_000_000}, 15, true},
+		{"free-test", llm.Usage{PromptTokens: 1000, CompletionTokens: 500}, 0, true},
+		{"gpt-test", llm.Usage{}, 0, true},
+		{"unknown", llm.Usage{PromptTokens: 1000, CompletionTokens: 500}, 0, false},
+	}
+	for _, tt := range tests {
+		cost, priced := prices.Cost(tt.model, tt.usage)
+		if !closeTo(cost, tt.cost) || priced != tt.priced {
+			t.Errorf("Cost(%s, %+v) = %v, %v; want %v, %v", tt.model, tt.usage, cost, priced, tt.cost, tt.priced)
+		}
+	}
+	if cost, priced := Prices(nil).Cost("gpt-test", llm.Usage{PromptTokens: 10}); cost != 0 || priced {
+		t.Errorf("nil prices: %v, %v", cost, priced)
+	}
+}
+
+// provider answers with usage, or fails with err.
+type provider struct {
+	usage llm.Usage
+	err   error
+}
+
+func (p provider) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &llm.Response{Message: llm.Message{Role: llm.RoleAssistant, Content: "It is sunny in Lisbon."}, Usage: p.usage}, nil
+}
+
+func TestMeterCountsCallsPerModelAndSession(t *testing.T) {
+	ledger := NewLedger()
+	gpt := NewMeter(provider{usage: llm.Usage{PromptTokens: 1000, CompletionTokens: 500}}, "gpt-test", prices, ledger)
+	claude := NewMeter(provider{usage: llm.Usage{PromptTokens: 2000, CompletionTokens: 400}}, "claude-test", prices, ledger)
+	unpriced := NewMeter(provider{usage: llm.Usage{PromptTokens: 10, CompletionTokens: 10}}, "local-test", prices, ledger)
+	failing := NewMeter(provider{err: errors.New("overloaded")}, "gpt-test", prices, ledger)
+	req := llm.Request{Messages: []llm.Message{{Role: llm.RoleUser, Content: "Weather in Lisbon?"}}}
+
+	// Alice's session calls both models twice, Bob's one unpriced model once.
+	aliceCtx, alice := Start(context.Background(), Account{Key: "sk-alice", Session: "s1"})
+	for range 2 {
+		for _, m := range []*Meter{gpt, claude} {
+			if _, err := m.Chat(aliceCtx, req); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if _, err := failing.Chat(aliceCtx, req); err == nil {
+		t.Fatal("the failing provider did not fail")
+	}
+	bobCtx, bob := Start(context.Background(), Account{Session: "s2"})
+	if _, err := unpriced.Chat(bobCtx, req); err != nil {
+		t.Fatal(err)
+	}
+	// A call outside any request goes to the ledger only.
+	if _, err := gpt.Chat(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	aliceWant := Totals{Calls: 4, PromptTokens: 6000, CompletionTokens: 1800, TotalTokens: 7800, CostUSD: 2 * (0.0075 + 0.012)}
+	bobWant := Totals{Calls: 1, PromptTokens: 10, CompletionTokens: 10, TotalTokens: 20, UnpricedCalls: 1}
+	tests := []struct {
+		name string
+		got  Totals
+		want Totals
+	}{
+		{"alice's tally", alice.Totals(), aliceWant},
+		{"bob's tally", bob.Totals(), bobWant},
+	}
+	report := ledger.Report()
+	tests = append(tests, []struct {
+		name string
+		got  Totals
+		want Totals
+	}{
+		{"session s1", report.Sessions["s1"], aliceWant},
+		{"session s2", report.Sessions["s2"], bobWant},
+		{"alice's key", report.Keys[KeyLabel("sk-alice")], aliceWant},
+		{"anonymous", report.Keys[AnonymousKey], Totals{Calls: 2, PromptTokens: 1010, CompletionTokens: 510, TotalTokens: 1520, CostUSD: 0.0075, UnpricedCalls: 1}},
+		{"total", report.Total, Totals{Calls: 6, PromptTokens: 7010, CompletionTokens: 2310, TotalTokens: 9320, CostUSD: 0.0465, UnpricedCalls: 1}},
+	}...)
+	for _, tt := range tests {
+		got, want := tt.got, tt.want
+		if !closeTo(got.CostUSD, want.CostUSD) {
+			t.Errorf("%s: cost = %v, want %v", tt.name, got.CostUSD, want.CostUSD)
+		}
+		got.CostUSD, want.CostUSD = 0, 0
+		if got != want {
+			t.Errorf("%s = %+v, want %+v", tt.name, got, want)
+		}
+	}
+	if len(report.Sessions) != 2 || len(report.Keys) != 2 {
+		t.Errorf("report has sessions %v and keys %v", report.Sessions, report.Keys)
+	}
+}
+
+func TestMeterEstimatesMissingUsage(t *testing.T) {
+	ledger := NewLedger()
+	meter := NewMeter(provider{}, "gpt-test", prices, ledger)
+	req := llm.Request{Messages: []llm.Message{{Role: llm.RoleUser, Content: "Weather in Lisbon?"}}}
+	if _, err := meter.Chat(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	total := ledger.Report().Total
+	prompt := llm.EstimateRequestTokens(req.Messages, nil)
+	completion := llm.EstimateMessageTokens(llm.Message{Role: llm.RoleAssistant, Content: "It is sunny in Lisbon."})
+	cost, _ := prices.Cost("gpt-test", llm.Usage{PromptTokens: prompt, CompletionTokens: completion})
+	if total.EstimatedCalls != 1 || total.PromptTokens != prompt || total.CompletionTokens != completion || !closeTo(total.CostUSD, cost) {
+		t.Errorf("total = %+v, want estimated %d + %d tokens costing %v", total, prompt, completion, cost)
+	}
+}
+
+func TestKeyLabel(t *testing.T) {
+	if got := KeyLabel(""); got != AnonymousKey {
+		t.Errorf("KeyLabel(\"\") = %q", got)
+	}
+	label := KeyLabel("sk-secret")
+	if len(label) != len("key-")+8 || label[:4] != "key-" || label == KeyLabel("sk-other") || label != KeyLabel("sk-secret") {
+		t.Errorf("KeyLabel(sk-secret) = %q", label)
+	}
+}
+
+func TestLedgerDropsTheLeastRecentSession(t *testing.T) {
+	ledger := NewLedger()
+	call := Call{Usage: llm.Usage{PromptTokens: 1}, Priced: true}
+	ledger.Record(Account{Session: "first"}, call)
+	ledger.Record(Account{Session: "second"}, call)
+	time.Sleep(time.Millisecond) // so the next calls are strictly more recent
+	for i := range maxSessions - 2 {
+		ledger.Record(Account{Session: fmt.Sprint(i)}, call)
+	}
+	ledger.Record(Account{Session: "first"}, call) // active again: second is now the oldest
+	ledger.Record(Account{Session: "new"}, call)
+
+	report := ledger.Report()
+	if len(report.Sessions) != maxSessions {
+		t.Errorf("%d sessions, want %d", len(report.Sessions), maxSessions)
+	}
+	if _, ok := report.Sessions["second"]; ok {
+		t.Error("the least recently active session was kept")
+	}
+	if report.Sessions["first"].Calls != 2 || report.Sessions["new"].Calls != 1 {
+		t.Errorf("sessions first and new: %+v, %+v", report.Sessions["first"], report.Sessions["new"])
+	}
+	// Dropping a session does not change the totals.
+	if want := maxSessions + 2; report.Total.Calls != want {
+		t.Errorf("total calls = %d, want %d", report.Total.Calls, want)
+	}
+}